@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"git-genius/sdk"
+
+	"github.com/spf13/cobra"
+)
+
+func askCmd(dep *SharedDependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ask <question>",
+		Short: "Answer a question about the repository's history",
+		Long: "Answer a question such as \"when did we switch auth to JWT and who did it?\" from the " +
+			"repository's history. The LLM plans git log searches by content (-S, -G), message, path, " +
+			"author and date, git-genius runs them locally and the LLM answers from their output only, " +
+			"citing the commits it relies on.\n\n" +
+			"Every command that ran is shown, on stderr with --output raw.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			output, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
+			answer, err := dep.sdk.AskHistory(ctx, strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case outputJSON:
+				return printJSON(answer)
+			case outputRaw:
+				printQueries(os.Stderr, answer.Queries)
+				fmt.Println(answer.Answer)
+				return nil
+			}
+
+			printQueries(os.Stdout, answer.Queries)
+			fmt.Printf("\n%s\n", answer.Answer)
+			if len(answer.Citations) > 0 {
+				fmt.Printf("\nCited commits: %s\n", strings.Join(answer.Citations, ", "))
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// printQueries shows the git commands that ran and how many commits each found
+func printQueries(out io.Writer, queries []sdk.QueryResult) {
+	for _, query := range queries {
+		if query.Error != "" {
+			fmt.Fprintf(out, "$ %s\n  failed: %s\n", query.Command, query.Error)
+			continue
+		}
+		fmt.Fprintf(out, "$ %s\n  %d commit(s)\n", query.Command, len(query.Commits))
+	}
+}
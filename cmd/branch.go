@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"git-genius/sdk"
+
+	"github.com/spf13/cobra"
+)
+
+// branchResult is the --output json result of branch
+type branchResult struct {
+	*sdk.BranchProposal
+	// Taken is the proposed name when a branch of that name already exists
+	Taken   string `json:"taken,omitempty"`
+	Created bool   `json:"created"`
+}
+
+func branchCmd(dep *SharedDependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "branch",
+		Short: "Propose a branch name for an issue or the staged changes",
+		Long: "Propose a branch name from the title of --issue and the staged changes, following " +
+			"branch.pattern, {type}/{issue}-{slug} by default. Placeholders without a value are left " +
+			"out, the name is made a valid git ref and shortened to branch.max_length.\n\n" +
+			"A name already used by a local or remote branch gets a -2, -3... suffix. When run in a " +
+			"terminal the name can be edited before the branch is created and checked out, --create " +
+			"does so without asking. --output raw prints the name only.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			create, _ := cmd.Flags().GetBool("create")
+			output, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
+			proposal, err := dep.sdk.ProposeBranch(ctx)
+			if err != nil {
+				return err
+			}
+
+			existing, err := existingBranches()
+			if err != nil {
+				return err
+			}
+			result := branchResult{BranchProposal: proposal}
+			name, err := uniqueBranchName(proposal.Name, existing)
+			if err != nil {
+				return err
+			}
+			if name != proposal.Name {
+				result.Taken, result.Name = proposal.Name, name
+			}
+			if err := checkBranchName(result.Name); err != nil {
+				return err
+			}
+
+			if output == outputText {
+				if result.Taken != "" {
+					fmt.Printf("Branch %s already exists.\n", result.Taken)
+				}
+				fmt.Printf("Proposed branch: %s\n", result.Name)
+			}
+
+			if !create && output == outputText && interactive(cmd) {
+				p := newPrompter()
+				edited := strings.TrimSpace(p.ask("Branch name", result.Name))
+				if p.eof {
+					return nil
+				}
+				if edited != result.Name {
+					if err := checkBranchName(edited); err != nil {
+						return err
+					}
+					if unique, err := uniqueBranchName(edited, existing); err != nil {
+						return err
+					} else if unique != edited {
+						return fmt.Errorf("branch %s already exists", edited)
+					}
+					result.Name = edited
+				}
+				create = p.confirm(fmt.Sprintf("Create and switch to %s?", result.Name), true)
+			}
+
+			if create {
+				if err := switchToNewBranch(result.Name); err != nil {
+					return err
+				}
+				result.Created = true
+			}
+
+			switch output {
+			case outputJSON:
+				return printJSON(result)
+			case outputRaw:
+				fmt.Println(result.Name)
+			default:
+				if result.Created {
+					fmt.Printf("Switched to a new branch %s.\n", result.Name)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("issue", "", "Issue ID to name the branch after")
+	cmd.Flags().Bool("create", false, "Create the branch and switch to it without asking")
+
+	return cmd
+}
+
+// existingBranches lists the local branches and the branches of every
+// remote, the latter without the remote name
+func existingBranches() ([]string, error) {
+	out, err := exec.Command("git", "for-each-ref", "--format=%(refname)", "refs/heads", "refs/remotes").Output()
+	if err != nil {
+		return nil, gitError("failed to list branches", err)
+	}
+
+	var branches []string
+	for _, ref := range strings.Fields(string(out)) {
+		if name, ok := strings.CutPrefix(ref, "refs/heads/"); ok {
+			branches = append(branches, name)
+			continue
+		}
+		// refs/remotes/<remote>/<branch>, <remote>/HEAD is not a branch
+		_, name, _ := strings.Cut(strings.TrimPrefix(ref, "refs/remotes/"), "/")
+		if name != "" && name != "HEAD" {
+			branches = append(branches, name)
+		}
+	}
+	return branches, nil
+}
+
+// uniqueBranchName returns name, or name with the first free -N suffix when
+// a branch of that name exists. Branches named like a directory of name, e.g.
+// feat for feat/login, leave no free name.
+func uniqueBranchName(name string, existing []string) (string, error) {
+	for _, branch := range existing {
+		if strings.HasPrefix(name, branch+"/") {
+			return "", fmt.Errorf("branch %s exists, so %s cannot be created", branch, name)
+		}
+	}
+
+	candidate := name
+	for n := 2; branchExists(candidate, existing); n++ {
+		candidate = fmt.Sprintf("%s-%d", name, n)
+	}
+	return candidate, nil
+}
+
+// branchExists reports whether a branch is called name or is inside a
+// directory called name, e.g. feat/login for feat
+func branchExists(name string, existing []string) bool {
+	for _, branch := range existing {
+		if branch == name || strings.HasPrefix(branch, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBranchName fails when git does not accept name as a branch name
+func checkBranchName(name string) error {
+	if err := exec.Command("git", "check-ref-format", "--branch", name).Run(); err != nil {
+		return usageError("%q is not a valid branch name", name)
+	}
+	return nil
+}
+
+// switchToNewBranch creates a branch at HEAD and checks it out, keeping
+// the staged and unstaged changes
+func switchToNewBranch(name string) error {
+	cmd := exec.Command("git", "switch", "--create", name)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return gitError(fmt.Sprintf("failed to create branch %s", name), err)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"git-genius/config"
+	"git-genius/internal/changelog"
+	context_provider "git-genius/internal/context_provider"
+	versioncontrol "git-genius/internal/version_control"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultChangelogFile is where --prepend writes without a path
+const defaultChangelogFile = "CHANGELOG.md"
+
+// changelogResult is the --output json result of changelog
+type changelogResult struct {
+	*changelog.Release
+	Markdown string `json:"markdown"`
+	File     string `json:"file,omitempty"`
+}
+
+func changelogCmd(dep *SharedDependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "changelog <from>..<to>",
+		Short: "Write a Keep a Changelog section for the commits between two revisions",
+		Long: "Group the commits between two revisions, usually tags, into a Keep a Changelog section: " +
+			"breaking changes first, then added, changed, removed, fixed and security changes, by scope. " +
+			"A single revision means the commits after it up to HEAD.\n\n" +
+			"Types and scopes are read from Conventional Commits or gitmoji headers, pull requests from " +
+			"\"(#12)\" suffixes and issues from Closes/Fixes footers and commit.issue_pattern. With a " +
+			"version_control token the pull requests of the other commits and their labels are looked up " +
+			"as well, --offline only reads the commit messages.",
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{skipSDKAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			version, _ := cmd.Flags().GetString("version")
+			all, _ := cmd.Flags().GetBool("all")
+			offline, _ := cmd.Flags().GetBool("offline")
+			prepend, _ := cmd.Flags().GetString("prepend")
+			output, err := outputFormat(cmd, outputText, outputJSON)
+			if err != nil {
+				return err
+			}
+
+			from, to, err := revisionRange(args[0])
+			if err != nil {
+				return err
+			}
+			logRange := to
+			if from != "" {
+				logRange = from + ".." + to
+			}
+			commits, err := context_provider.Log(ctx, "--no-merges", logRange)
+			if err != nil {
+				return err
+			}
+			if len(commits) == 0 {
+				return fmt.Errorf("there are no commits in %s", args[0])
+			}
+
+			opts := changelog.Options{All: all}
+			if pattern := dep.cfg.Commit.IssuePattern; pattern != "" {
+				if opts.IssuePattern, err = regexp.Compile(pattern); err != nil {
+					return fmt.Errorf("invalid commit.issue_pattern: %v", err)
+				}
+			}
+
+			release := &changelog.Release{Version: version, From: from, To: to}
+			if tag := tagAt(to); tag != "" {
+				release.To = tag
+				if release.Version == "" {
+					release.Version = tag
+				}
+			}
+			if release.Version == "" {
+				release.Version = changelog.Unreleased
+			} else {
+				release.Date = commitDate(to)
+			}
+			for _, commit := range commits {
+				release.Entries = append(release.Entries, changelog.NewEntry(commit.Hash, commit.Message, opts))
+			}
+
+			if !offline {
+				if finder := pullRequestFinder(cmd, dep.cfg); finder != nil {
+					for _, entry := range release.Entries {
+						if len(entry.PullRequests) > 0 && entry.Type != "" {
+							continue
+						}
+						prs, err := finder.PullRequestsForCommit(ctx, entry.Hash)
+						if err != nil {
+							fmt.Fprintf(os.Stderr, "Warning: %v, continuing with the commit messages only\n", err)
+							break
+						}
+						for _, pr := range prs {
+							entry.AddPullRequest(pr.Number)
+							entry.ApplyLabels(pr.Labels, all)
+						}
+					}
+				}
+			}
+
+			result := changelogResult{Release: release, Markdown: release.Markdown(changelogLinks(dep.cfg))}
+			if cmd.Flags().Changed("prepend") {
+				result.File = prepend
+				if !filepath.IsAbs(prepend) {
+					result.File = filepath.Join(repoRoot(cmd.Context()), prepend)
+				}
+				if err := changelog.Prepend(result.File, result.Markdown, release.Version); err != nil {
+					return err
+				}
+			}
+
+			if output == outputJSON {
+				return printJSON(result)
+			}
+			if result.File != "" {
+				fmt.Printf("Added %s to %s.\n", release.Version, result.File)
+				return nil
+			}
+			fmt.Print(result.Markdown)
+			return nil
+		},
+	}
+
+	cmd.Flags().String("version", "", "Version heading of the section, the tag at <to> or Unreleased by default")
+	cmd.Flags().Bool("all", false, "Also list docs, tests, chores and other internal changes")
+	cmd.Flags().Bool("offline", false, "Only read commit messages, do not look up pull requests")
+	cmd.Flags().String("prepend", "", "Add the section to the changelog file, "+defaultChangelogFile+" by default")
+	cmd.Flags().Lookup("prepend").NoOptDefVal = defaultChangelogFile
+
+	return cmd
+}
+
+// revisionRange splits "<from>..<to>", a single revision means up to HEAD
+// and an empty <from> the whole history
+func revisionRange(arg string) (string, string, error) {
+	if strings.Contains(arg, "...") {
+		return "", "", usageError("symmetric ranges are not supported, use <from>..<to>")
+	}
+	from, to, found := strings.Cut(arg, "..")
+	if !found {
+		to = "HEAD"
+	}
+	if to == "" {
+		to = "HEAD"
+	}
+
+	for _, rev := range []string{from, to} {
+		if rev == "" {
+			continue
+		}
+		if _, err := revParse(rev); err != nil {
+			return "", "", err
+		}
+	}
+	return from, to, nil
+}
+
+// tagAt returns the tag pointing at rev, or an empty string
+func tagAt(rev string) string {
+	out, err := exec.Command("git", "describe", "--tags", "--exact-match", rev).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commitDate returns the YYYY-MM-DD committer date of rev
+func commitDate(rev string) string {
+	out, err := exec.Command("git", "log", "-1", "--format=%cs", rev).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// pullRequestFinder returns the configured provider when it has a token and
+// can look up pull requests, nil otherwise
+func pullRequestFinder(cmd *cobra.Command, cfg *config.Config) versioncontrol.PullRequestFinder {
+	if cfg.VersionControl.Token == "" {
+		return nil
+	}
+	creator, err := cfg.NewPRCreator(cmd.Context())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v, continuing with the commit messages only\n", err)
+		return nil
+	}
+	finder, _ := creator.(versioncontrol.PullRequestFinder)
+	return finder
+}
+
+// changelogLinks points references at the web UI of the origin remote
+func changelogLinks(cfg *config.Config) *changelog.Links {
+	remote, err := versioncontrol.GetRemote()
+	if err != nil {
+		return nil
+	}
+	provider := cfg.VersionControl.Provider
+	if provider == "" {
+		provider = vcsForHost(remote.Host)
+	}
+	if provider == "" {
+		return nil
+	}
+	return &changelog.Links{
+		Repo:   fmt.Sprintf("https://%s/%s/%s", remote.Host, remote.Owner, remote.Repo),
+		GitLab: provider == "gitlab",
+	}
+}
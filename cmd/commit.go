@@ -1,88 +1,433 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"git-genius/sdk"
 
 	"github.com/spf13/cobra"
 )
 
+// commitResult is the --output json result of smart-commit
+type commitResult struct {
+	Message    string   `json:"message"`
+	Candidates []string `json:"candidates,omitempty"`
+	Committed  bool     `json:"committed"`
+	Commit     string   `json:"commit,omitempty"`
+}
+
 func commitCmd(dep *SharedDependencies) *cobra.Command {
+	var commitOpts gitCommitOptions
+
 	cmd := &cobra.Command{
-		Use:   "smart-commit",
 		Short: "Generate a commit message and commit changes",
-		Run: func(cmd *cobra.Command, args []string) {
+		Use:   "smart-commit [flags] [--] [pathspec...]",
+		Long: "Generate a commit message for the changes git commit would record, review it and commit.\n\n" +
+			"-a, --amend and pathspecs select the changes like they do for git commit, the other " +
+			"git commit flags are passed on. With --yes the generated message is committed without " +
+			"asking. With --output json or raw and without --yes the message is only printed, which " +
+			"lets editors and scripts use it.",
+		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
+			candidates, _ := cmd.Flags().GetInt("candidates")
+			if candidates < 1 {
+				return usageError("--candidates must be at least 1")
+			}
+			commitOpts.pathspecs = args
+			if commitOpts.all && len(args) > 0 {
+				return usageError("paths with -a does not make sense")
+			}
+			yes, _ := cmd.Flags().GetBool("yes")
+			noEdit, _ := cmd.Flags().GetBool("no-edit")
+			output, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
+			// the review menu only runs for people at a terminal
+			review := !yes && output == outputText
+			if review && !interactive(cmd) {
+				return usageError("stdin is not a terminal, use --yes to commit the generated message or --output raw to print it")
+			}
+
+			// generate the message from exactly what will be committed
+			dep.cfg.Pathspecs = commitOpts.pathspecs
+			dep.cfg.Amend = commitOpts.amend
+			restoreIndex, err := commitOpts.previewIndex()
+			if err != nil {
+				return err
+			}
+
 			// Generate commit message
-			commitMessage, err := dep.sdk.GenerateCommitMessage(ctx)
+			session, err := dep.sdk.StartCommitSession(ctx, candidates)
+			restoreIndex()
 			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-				return
+				return err
+			}
+
+			if review {
+				p := newPrompter()
+				if len(session.Candidates) > 1 {
+					session.SetMessage(pickCandidate(p, session.Candidates))
+				}
+				if !reviewCommitMessage(ctx, p, session) {
+					return &ExitError{Code: ExitCanceled, Err: errors.New("commit canceled")}
+				}
+			} else if output == outputText {
+				fmt.Println(session.Message)
+			}
+
+			result := commitResult{Message: session.Message, Candidates: session.Candidates}
+			if review || yes {
+				// keep stdout parseable, git reports on stderr instead
+				gitOutput := io.Writer(os.Stdout)
+				if output != outputText {
+					gitOutput = os.Stderr
+				}
+
+				result.Commit, err = performGitCommit(session.Message, !noEdit, commitOpts, gitOutput)
+				if err != nil {
+					return err
+				}
+				result.Committed = true
 			}
 
-			// Show the generated commit message
-			fmt.Println("\nGenerated Commit Message:")
-			fmt.Println("-------------------------")
-			fmt.Println(commitMessage)
-			fmt.Println("-------------------------")
-
-			// Prompt user for input
-			fmt.Println("What would you like to do?")
-			fmt.Println("[Y] Accept and Commit")
-			fmt.Println("[N] Cancel")
-
-			var choice string
-			fmt.Print("Enter your choice (Y/N): ")
-			fmt.Scanln(&choice)
-
-			switch strings.ToLower(choice) {
-			case "y":
-				// Proceed with Git's editor for commit
-				performGitCommitWithEditor(commitMessage)
-			case "n":
-				// Cancel the operation
-				fmt.Println("Commit canceled.")
+			switch output {
+			case outputJSON:
+				return printJSON(result)
+			case outputRaw:
+				fmt.Println(result.Message)
 			default:
-				fmt.Println("Invalid choice. Commit canceled.")
+				if result.Committed {
+					fmt.Println("Commit completed successfully.")
+				}
 			}
+			return nil
 		},
 	}
 
 	// flags
 	cmd.PersistentFlags().String("issue", "", "Issue ID to associate with the operation")
+	cmd.Flags().Int("candidates", 1, "Number of alternative commit messages to choose from")
+	cmd.Flags().Bool("no-edit", false, "Commit the message without opening git's editor")
+
+	// passed on to git commit
+	cmd.Flags().BoolVarP(&commitOpts.all, "all", "a", false, "Commit all modified and deleted tracked files")
+	cmd.Flags().BoolVar(&commitOpts.amend, "amend", false, "Replace the tip of the current branch")
+	cmd.Flags().StringVarP(&commitOpts.gpgSign, "gpg-sign", "S", "", "GPG-sign the commit, optionally with the given key ID")
+	cmd.Flags().Lookup("gpg-sign").NoOptDefVal = gpgSignDefaultKey
+	cmd.Flags().BoolVarP(&commitOpts.noVerify, "no-verify", "n", false, "Bypass the pre-commit and commit-msg hooks")
+	cmd.Flags().BoolVarP(&commitOpts.signoff, "signoff", "s", false, "Add a Signed-off-by trailer")
+	cmd.Flags().StringVar(&commitOpts.author, "author", "", "Override the commit author, \"Name <email>\"")
 
 	return cmd
 }
 
-func performGitCommitWithEditor(commitMessage string) error {
+// reviewCommitMessage lets the user revise the message until it is accepted,
+// it returns false when the commit is canceled
+func reviewCommitMessage(ctx context.Context, p *prompter, session *sdk.CommitSession) bool {
+	for {
+		// Show the generated commit message
+		fmt.Println("\nGenerated Commit Message:")
+		fmt.Println("-------------------------")
+		fmt.Println(session.Message)
+		fmt.Println("-------------------------")
+
+		// Prompt user for input
+		fmt.Println("What would you like to do?")
+		fmt.Println("[Y] Accept and Commit")
+		fmt.Println("[R] Regenerate")
+		fmt.Println("[F] Give feedback")
+		fmt.Println("[S] Make it shorter")
+		fmt.Println("[E] Edit in $EDITOR")
+		fmt.Println("[N] Cancel")
+
+		var err error
+		switch p.choose("Enter your choice", []string{"Y", "R", "F", "S", "E", "N"}, "") {
+		case "Y":
+			return true
+		case "R":
+			_, err = session.Regenerate(ctx)
+		case "F":
+			feedback := p.ask("Feedback (e.g. \"mention the migration, shorter\")", "")
+			if feedback == "" {
+				continue
+			}
+			_, err = session.Revise(ctx, feedback)
+		case "S":
+			_, err = session.Shorten(ctx)
+		case "E":
+			var edited string
+			edited, err = editMessage(session.Message)
+			if err == nil {
+				session.SetMessage(edited)
+			}
+		default:
+			return false
+		}
+
+		// keep the previous message when a revision fails
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}
+}
+
+// gpgSignDefaultKey is the value of a bare -S, signing with the default key
+const gpgSignDefaultKey = "default"
+
+// gitCommitOptions are the git commit flags smart-commit passes on
+type gitCommitOptions struct {
+	all       bool
+	amend     bool
+	gpgSign   string
+	noVerify  bool
+	signoff   bool
+	author    string
+	pathspecs []string
+}
+
+// args returns the git commit arguments, pathspecs last
+func (o gitCommitOptions) args() []string {
+	var args []string
+	if o.all {
+		args = append(args, "--all")
+	}
+	if o.amend {
+		args = append(args, "--amend")
+	}
+	switch o.gpgSign {
+	case "":
+	case gpgSignDefaultKey:
+		args = append(args, "--gpg-sign")
+	default:
+		args = append(args, "--gpg-sign="+o.gpgSign)
+	}
+	if o.noVerify {
+		args = append(args, "--no-verify")
+	}
+	if o.signoff {
+		args = append(args, "--signoff")
+	}
+	if o.author != "" {
+		args = append(args, "--author="+o.author)
+	}
+	if len(o.pathspecs) > 0 {
+		args = append(append(args, "--"), o.pathspecs...)
+	}
+	return args
+}
+
+// previewIndex stages what git commit will record with -a or pathspecs into a
+// temporary index, so the message is generated from the right diff while the
+// real index stays untouched until git commits. The returned function switches
+// back to the real index.
+func (o gitCommitOptions) previewIndex() (func(), error) {
+	if !o.all && len(o.pathspecs) == 0 {
+		return func() {}, nil
+	}
+
+	indexPath, err := gitPath("index")
+	if err != nil {
+		return nil, err
+	}
+	if previous, ok := os.LookupEnv("GIT_INDEX_FILE"); ok {
+		indexPath = previous
+	}
+
+	tempFile, err := os.CreateTemp("", "git-genius-index-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	tempFile.Close()
+	if content, err := os.ReadFile(indexPath); err == nil {
+		if err := os.WriteFile(tempFile.Name(), content, 0o600); err != nil {
+			os.Remove(tempFile.Name())
+			return nil, fmt.Errorf("failed to copy index: %w", err)
+		}
+	} else {
+		// without an index yet git starts from an empty one
+		os.Remove(tempFile.Name())
+	}
+
+	previous, hadPrevious := os.LookupEnv("GIT_INDEX_FILE")
+	restore := func() {
+		if hadPrevious {
+			os.Setenv("GIT_INDEX_FILE", previous)
+		} else {
+			os.Unsetenv("GIT_INDEX_FILE")
+		}
+		os.Remove(tempFile.Name())
+	}
+	os.Setenv("GIT_INDEX_FILE", tempFile.Name())
+
+	// -a and pathspecs both take the working tree version of tracked files
+	args := []string{"add", "--update"}
+	if len(o.pathspecs) > 0 {
+		args = append(append(args, "--"), o.pathspecs...)
+	}
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		restore()
+		return nil, fmt.Errorf("failed to stage changes: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return restore, nil
+}
+
+// performGitCommit commits with message and the passed on git commit options,
+// opening git's editor first when edit is set, and returns the new commit hash
+func performGitCommit(commitMessage string, edit bool, opts gitCommitOptions, output io.Writer) (string, error) {
 	// Write the message to a temporary file
 	tempFile, err := os.CreateTemp("", "git-commit-msg-*.txt")
 	if err != nil {
-		return fmt.Errorf("failed to create temporary file: %w", err)
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
 	}
 	defer os.Remove(tempFile.Name())
 
 	_, err = tempFile.WriteString(commitMessage)
 	if err != nil {
-		return fmt.Errorf("failed to write commit message to file: %w", err)
+		return "", fmt.Errorf("failed to write commit message to file: %w", err)
 	}
 	tempFile.Close()
 
-	// Open Git's editor for editing and commit
-	cmd := exec.Command("git", "commit", "--edit", "--file", tempFile.Name())
+	args := []string{"commit", "--file", tempFile.Name()}
+	if edit {
+		args = append(args, "--edit")
+	}
+	args = append(args, opts.args()...)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = output
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+
+	started := time.Now()
+	if err := cmd.Run(); err != nil {
+		if edit && editedMessageEmpty(started) {
+			return "", &ExitError{Code: ExitCanceled, Err: errors.New("commit canceled, the commit message was left empty")}
+		}
+		return "", commitFailure(err, stderr.String(), opts)
+	}
+
+	hash, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the new commit: %w", err)
+	}
+	return strings.TrimSpace(string(hash)), nil
+}
+
+// editedMessageEmpty reports whether the user emptied the message in git's
+// editor. git keeps the edited message in COMMIT_EDITMSG, one written before
+// the commit started belongs to an earlier commit.
+func editedMessageEmpty(started time.Time) bool {
+	path, err := gitPath("COMMIT_EDITMSG")
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.ModTime().Before(started) {
+		return false
+	}
+	message, err := readCommitMessage(path)
+	return err == nil && message == ""
+}
+
+// commitFailure tells a commit a hook rejected from one git refused, keeping
+// git's exit code for the latter
+func commitFailure(err error, stderr string, opts gitCommitOptions) error {
+	// hooks report their own errors, git itself prefixes its errors with fatal: or error:
+	gitComplained := strings.Contains(stderr, "fatal:") || strings.Contains(stderr, "error:")
+	if !gitComplained {
+		hooks := []string{"prepare-commit-msg"}
+		if !opts.noVerify {
+			hooks = append(hooks, "pre-commit", "commit-msg")
+		}
+		for _, hook := range hooks {
+			if hookInstalled(hook) {
+				return gitError("a git hook rejected the commit (see its output above, --no-verify skips pre-commit and commit-msg)", err)
+			}
+		}
+	}
+
+	return gitError("git commit failed", err)
+}
+
+// hookInstalled reports whether git would run the hook called name
+func hookInstalled(name string) bool {
+	dir, err := gitPath("hooks")
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, name))
+	return err == nil && info.Mode()&0o111 != 0
+}
+
+// pickCandidate shows the messages as a numbered list and returns the chosen one
+func pickCandidate(p *prompter, candidates []string) string {
+	fmt.Println("\nGenerated Commit Messages:")
+	options := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		options[i] = strconv.Itoa(i + 1)
+		fmt.Println("-------------------------")
+		fmt.Printf("[%d] %s\n", i+1, strings.ReplaceAll(candidate, "\n", "\n    "))
+	}
+	fmt.Println("-------------------------")
+
+	choice := p.choose("Pick a message", options, "1")
+	n, _ := strconv.Atoi(choice)
+	return candidates[n-1]
+}
+
+// editMessage opens the commit message in git's editor ($GIT_EDITOR,
+// core.editor, $VISUAL or $EDITOR) and returns the edited text without comments
+func editMessage(message string) (string, error) {
+	out, err := exec.Command("git", "var", "GIT_EDITOR").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to determine editor: %w", err)
+	}
+	editor := strings.TrimSpace(string(out))
+
+	tempFile, err := os.CreateTemp("", "git-genius-msg-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	content := message + "\n\n# Edit the commit message. Lines starting with '#' are ignored.\n"
+	if _, err := tempFile.WriteString(content); err != nil {
+		tempFile.Close()
+		return "", fmt.Errorf("failed to write commit message to file: %w", err)
+	}
+	tempFile.Close()
+
+	// the editor may carry arguments, e.g. "code --wait"
+	cmd := exec.Command("sh", "-c", editor+` "$@"`, editor, tempFile.Name())
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %w", editor, err)
+	}
 
-	err = cmd.Run()
+	edited, err := os.ReadFile(tempFile.Name())
 	if err != nil {
-		// If Git aborts due to an empty commit message, notify the user
-		fmt.Println("Commit canceled by user.")
-		return nil
+		return "", fmt.Errorf("failed to read edited commit message: %w", err)
 	}
 
-	fmt.Println("Commit completed successfully.")
-	return nil
+	result := stripComments(string(edited), "#")
+	if result == "" {
+		return "", fmt.Errorf("edited commit message is empty, keeping the previous one")
+	}
+	return result, nil
 }
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPickCandidate(t *testing.T) {
+	candidates := []string{"feat: add login", "feat(auth): add login\n\nUses sessions.", "feat: login"}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "default", input: "\n", want: candidates[0]},
+		{name: "picked", input: "2\n", want: candidates[1]},
+		{name: "retried", input: "7\n3\n", want: candidates[2]},
+		{name: "no input", input: "", want: candidates[0]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &prompter{in: bufio.NewReader(strings.NewReader(tt.input)), out: io.Discard}
+			if got := pickCandidate(p, candidates); got != tt.want {
+				t.Errorf("pickCandidate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerformGitCommitEdit(t *testing.T) {
+	tests := []struct {
+		name string
+		// editor is the shell script git runs as its editor
+		editor string
+		// hook is a pre-commit hook, staleMessage leaves an empty
+		// COMMIT_EDITMSG of an earlier commit behind
+		hook         string
+		staleMessage bool
+		wantCode     int
+	}{
+		{name: "message kept", editor: "exit 0"},
+		{name: "message emptied", editor: `printf '\n# comment\n' > "$1"`, wantCode: ExitCanceled},
+		{name: "hook rejected", editor: "exit 0", hook: "exit 1", staleMessage: true, wantCode: ExitFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testRepo(t)
+			if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			git(t, dir, "add", "main.go")
+
+			editor := writeScript(t, filepath.Join(t.TempDir(), "editor"), tt.editor)
+			t.Setenv("GIT_EDITOR", editor)
+			if tt.hook != "" {
+				writeScript(t, filepath.Join(dir, ".git", "hooks", "pre-commit"), tt.hook)
+			}
+			if tt.staleMessage {
+				stale := filepath.Join(dir, ".git", "COMMIT_EDITMSG")
+				if err := os.WriteFile(stale, nil, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				past := time.Now().Add(-time.Hour)
+				if err := os.Chtimes(stale, past, past); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			hash, err := performGitCommit("feat: add main", true, gitCommitOptions{}, io.Discard)
+			if tt.wantCode == 0 {
+				if err != nil || hash == "" {
+					t.Fatalf("performGitCommit() = %q, %v, want a commit", hash, err)
+				}
+				return
+			}
+			var exitErr *ExitError
+			if !errors.As(err, &exitErr) || exitErr.Code != tt.wantCode {
+				t.Errorf("performGitCommit() error = %v, want exit code %d", err, tt.wantCode)
+			}
+		})
+	}
+}
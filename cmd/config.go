@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"git-genius/config"
+
+	"github.com/spf13/cobra"
+)
+
+func configCmd(dep *SharedDependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the effective git-genius configuration",
+		Long: "Inspect the effective git-genius configuration. " +
+			"Unrecognised arguments are passed to git config.",
+		Args:               cobra.ArbitraryArgs,
+		DisableFlagParsing: true,
+		Annotations:        map[string]string{skipConfigAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return cmd.Help()
+			}
+			// Pass anything else to git config.
+			return runGitCommand(append([]string{"config"}, args...))
+		},
+	}
+
+	cmd.AddCommand(configShowCmd(dep))
+	cmd.AddCommand(configValidateCmd())
+	cmd.AddCommand(configSchemaCmd())
+
+	return cmd
+}
+
+func configShowCmd(dep *SharedDependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "show",
+		Short:       "Show the effective configuration",
+		Annotations: map[string]string{skipSDKAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			showOrigin, _ := cmd.Flags().GetBool("origin")
+			output, err := outputFormat(cmd, outputText, outputJSON)
+			if err != nil {
+				return err
+			}
+
+			values, err := dep.cfg.Values()
+			if err != nil {
+				return err
+			}
+
+			keys := make([]string, 0, len(values))
+			for key := range values {
+				keys = append(keys, key)
+				if config.IsSecretKey(key) {
+					values[key] = maskSecret(values[key])
+				}
+			}
+			sort.Strings(keys)
+
+			if output == outputJSON {
+				type entry struct {
+					Key    string `json:"key"`
+					Value  string `json:"value"`
+					Origin string `json:"origin"`
+				}
+				entries := make([]entry, len(keys))
+				for i, key := range keys {
+					entries[i] = entry{Key: key, Value: values[key], Origin: dep.cfg.Origin(key)}
+				}
+				return printJSON(entries)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			for _, key := range keys {
+				value := values[key]
+				if showOrigin {
+					fmt.Fprintf(w, "%s\t%s\t%s\n", key, value, dep.cfg.Origin(key))
+				} else {
+					fmt.Fprintf(w, "%s\t%s\n", key, value)
+				}
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().Bool("origin", false, "Show the file, environment variable or flag each value came from")
+
+	return cmd
+}
+
+func configValidateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:         "validate",
+		Short:       "Check the configuration files for unknown keys and invalid values",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{skipConfigAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configPath, _ := cmd.Flags().GetString("config")
+			overrides, err := parseOverrides(cmd)
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.LoadConfig(cmd.Context(), configPath, overrides)
+			if err != nil {
+				return err
+			}
+
+			if problems := cfg.Validate(); len(problems) > 0 {
+				return problems
+			}
+
+			fmt.Println("Config is valid.")
+			return nil
+		},
+	}
+}
+
+func configSchemaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:         "schema",
+		Short:       "Print the JSON Schema of the configuration file",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{skipConfigAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := os.Stdout.Write(config.JSONSchema)
+			return err
+		},
+	}
+}
+
+// maskSecret hides a credential while still showing that it is set
+func maskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "********"
+}
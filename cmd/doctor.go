@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"git-genius/config"
+	context_provider "git-genius/internal/context_provider"
+	versioncontrol "git-genius/internal/version_control"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// check is a single diagnostic reported by the doctor command
+type check struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Fix       string `json:"fix,omitempty"`
+	LatencyMS int64  `json:"latency_ms,omitempty"`
+}
+
+func doctorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "doctor",
+		Short:       "Diagnose the configuration, git setup and service credentials",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{skipConfigAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := outputFormat(cmd, outputText, outputJSON)
+			if err != nil {
+				return err
+			}
+
+			checks := runDoctor(cmd)
+
+			if output == outputJSON {
+				if err := printJSON(checks); err != nil {
+					return err
+				}
+			} else {
+				printChecks(checks)
+			}
+
+			failed := 0
+			for _, c := range checks {
+				if c.Status == checkFail {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d check(s) failed", failed)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func runDoctor(cmd *cobra.Command) []check {
+	ctx := cmd.Context()
+	var checks []check
+
+	// git
+	if out, err := exec.Command("git", "--version").Output(); err != nil {
+		checks = append(checks, check{Name: "git installed", Status: checkFail, Detail: err.Error(),
+			Fix: "install git and make sure it is on your PATH"})
+	} else {
+		checks = append(checks, check{Name: "git installed", Status: checkOK, Detail: strings.TrimSpace(string(out))})
+	}
+
+	if err := exec.Command("git", "rev-parse", "--is-inside-work-tree").Run(); err != nil {
+		checks = append(checks, check{Name: "git repository", Status: checkFail, Detail: "not inside a git work tree",
+			Fix: "run git-genius from inside a git repository"})
+	} else {
+		checks = append(checks, check{Name: "git repository", Status: checkOK, Detail: repoRoot(cmd.Context())})
+	}
+
+	if remote, err := versioncontrol.GetRemote(); err != nil {
+		checks = append(checks, check{Name: "git remote", Status: checkFail, Detail: err.Error(),
+			Fix: "add a remote with: git remote add origin <url>"})
+	} else if vcsForHost(remote.Host) == "" {
+		checks = append(checks, check{Name: "git remote", Status: checkWarn,
+			Detail: fmt.Sprintf("%s/%s on %s", remote.Owner, remote.Repo, remote.Host),
+			Fix:    "the host is not supported, use one of: " + strings.Join(config.SupportedVersionControls, ", ")})
+	} else {
+		checks = append(checks, check{Name: "git remote", Status: checkOK,
+			Detail: fmt.Sprintf("%s/%s on %s", remote.Owner, remote.Repo, remote.Host)})
+	}
+
+	// config
+	configPath, _ := cmd.Flags().GetString("config")
+	overrides, err := parseOverrides(cmd)
+	var cfg *config.Config
+	if err == nil {
+		cfg, err = config.LoadConfig(ctx, configPath, overrides)
+	}
+	if err != nil {
+		fix := "fix the reported problem in the config file"
+		if strings.Contains(err.Error(), "not found") {
+			fix = "create a config with: git-genius init"
+		}
+		checks = append(checks, check{Name: "config", Status: checkFail, Detail: err.Error(), Fix: fix})
+		return append(checks, check{Name: "services", Status: checkSkip, Detail: "no usable config"})
+	}
+	checks = append(checks, check{Name: "config", Status: checkOK, Detail: strings.Join(cfg.Files, ", ")})
+
+	problems := cfg.Validate()
+	if len(problems) == 0 {
+		checks = append(checks, check{Name: "config schema", Status: checkOK})
+	}
+	for _, problem := range problems {
+		checks = append(checks, check{Name: "config schema", Status: checkFail, Detail: problem.Error(),
+			Fix: "fix the value, see: git-genius config show --origin"})
+	}
+
+	// services
+	checks = append(checks, timedCheck(ctx, "llm "+cfg.LLM.Name,
+		"check llm.api_key or set "+config.EnvVarName("llm.api_key"),
+		func(ctx context.Context) error {
+			llm, err := cfg.NewLLM(ctx)
+			if err != nil {
+				return err
+			}
+			return llm.Ping(ctx)
+		}))
+
+	checks = append(checks, timedCheck(ctx, "version control "+cfg.VersionControl.Provider,
+		"check that version_control.token has access to the repository",
+		func(ctx context.Context) error {
+			prCreator, err := cfg.NewPRCreator(ctx)
+			if err != nil {
+				return err
+			}
+			return prCreator.Ping(ctx)
+		}))
+
+	for _, providerConfig := range cfg.ContextProviders {
+		providerConfig := providerConfig
+		checks = append(checks, timedCheck(ctx, "context provider "+providerConfig.Name,
+			contextProviderFix(providerConfig.Name),
+			func(ctx context.Context) error {
+				provider, err := context_provider.NewContextProvider(providerConfig, cfg.IssueID)
+				if err != nil {
+					return err
+				}
+				if pinger, ok := provider.(context_provider.Pinger); ok {
+					return pinger.Ping(ctx)
+				}
+				_, err = provider.FetchContext(ctx)
+				return err
+			}))
+	}
+
+	return checks
+}
+
+// timedCheck runs fn and records how long it took
+func timedCheck(ctx context.Context, name, fix string, fn func(ctx context.Context) error) check {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := fn(ctx)
+	latency := time.Since(start).Milliseconds()
+
+	if err != nil {
+		return check{Name: name, Status: checkFail, Detail: err.Error(), Fix: fix, LatencyMS: latency}
+	}
+	return check{Name: name, Status: checkOK, LatencyMS: latency}
+}
+
+func contextProviderFix(name string) string {
+	switch name {
+	case string(context_provider.LinearContextProviderType):
+		return "check the linear api_key, create one under Linear settings > API"
+	case string(context_provider.PRTemplateContextProviderType):
+		return "check that the pr_template path exists relative to where git-genius runs"
+	case string(context_provider.GitContextProviderType):
+		return "check that git works in this directory"
+	default:
+		return "use one of: " + strings.Join(config.SupportedContextProviders, ", ")
+	}
+}
+
+func printChecks(checks []check) {
+	symbols := map[string]string{
+		checkOK:   "✓",
+		checkWarn: "!",
+		checkFail: "✗",
+		checkSkip: "-",
+	}
+
+	for _, c := range checks {
+		line := fmt.Sprintf("%s %s", symbols[c.Status], c.Name)
+		if c.Detail != "" {
+			line += ": " + c.Detail
+		}
+		if c.LatencyMS > 0 {
+			line += fmt.Sprintf(" (%dms)", c.LatencyMS)
+		}
+		fmt.Println(line)
+		if c.Fix != "" {
+			fmt.Printf("    fix: %s\n", c.Fix)
+		}
+	}
+}
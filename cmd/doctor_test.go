@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestTimedCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus string
+		wantDetail string
+		wantFix    string
+	}{
+		{name: "ok", wantStatus: checkOK},
+		{name: "failed", err: fmt.Errorf("401 unauthorized"), wantStatus: checkFail, wantDetail: "401 unauthorized", wantFix: "check the key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := timedCheck(context.Background(), "llm", "check the key", func(ctx context.Context) error {
+				if _, ok := ctx.Deadline(); !ok {
+					t.Errorf("timedCheck() ran the check without a deadline")
+				}
+				return tt.err
+			})
+			want := check{Name: "llm", Status: tt.wantStatus, Detail: tt.wantDetail, Fix: tt.wantFix, LatencyMS: got.LatencyMS}
+			if got != want {
+				t.Errorf("timedCheck() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestContextProviderFix(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "git", want: "check that git works in this directory"},
+		{name: "jira", want: "use one of: git, linear, pr_template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := contextProviderFix(tt.name); got != tt.want {
+				t.Errorf("contextProviderFix(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+
+	"git-genius/internal/prompt"
+	"git-genius/sdk"
+
+	"github.com/spf13/cobra"
+)
+
+// explainResult is the --output json result of explain
+type explainResult struct {
+	Target      string        `json:"target"`
+	Commits     []string      `json:"commits"`
+	Issue       *prompt.Issue `json:"issue,omitempty"`
+	Explanation string        `json:"explanation"`
+}
+
+func explainCmd(dep *SharedDependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain <rev>|<range>|<path>",
+		Short: "Explain what changed in a commit, range or path and why",
+		Long: "Summarize what changed and why in a commit, the commits of a <from>..<to> range or the " +
+			"latest commits of a path. The LLM gets the commit messages and diff, the issue they " +
+			"reference when an issue tracker is configured and the earlier history of the touched files.\n\n" +
+			"At a terminal, follow-up questions about the same changes can be asked afterwards, an " +
+			"empty question ends the session. --yes only prints the explanation.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			output, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
+			session, err := dep.sdk.Explain(ctx, args[0])
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case outputJSON:
+				return printJSON(newExplainResult(session))
+			case outputRaw:
+				fmt.Println(session.Explanation)
+				return nil
+			}
+
+			fmt.Println(session.Explanation)
+			if !interactive(cmd) {
+				return nil
+			}
+
+			p := newPrompter()
+			for {
+				fmt.Println()
+				question := p.ask("Follow-up question (empty to quit)", "")
+				if question == "" {
+					return nil
+				}
+				answer, err := session.Ask(ctx, question)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("\n%s\n", answer)
+				if p.eof {
+					return nil
+				}
+			}
+		},
+	}
+
+	return cmd
+}
+
+func newExplainResult(session *sdk.ExplainSession) explainResult {
+	result := explainResult{
+		Target:      session.Target,
+		Commits:     []string{},
+		Issue:       session.Issue,
+		Explanation: session.Explanation,
+	}
+	for _, commit := range session.Commits {
+		result.Commits = append(result.Commits, commit.Hash)
+	}
+	return result
+}
@@ -0,0 +1,544 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"git-genius/config"
+	"git-genius/internal/shell"
+	"git-genius/sdk"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	prepareCommitMsgHook = "prepare-commit-msg"
+	commitMsgHook        = "commit-msg"
+	// hookMarker identifies hooks written by git-genius
+	hookMarker = "# installed by git-genius"
+	// chainedHookSuffix is appended to a hook git-genius replaced, it still runs first
+	chainedHookSuffix = ".pre-git-genius"
+)
+
+// supportedHooks lists the hooks git-genius can install
+var supportedHooks = []string{prepareCommitMsgHook, commitMsgHook}
+
+// hookScript runs any chained hook, then git-genius. A missing git-genius
+// binary never blocks the commit.
+const hookScript = `#!/bin/sh
+%s, remove with: git-genius hook uninstall %s
+hook_dir=$(dirname "$0")
+if [ -x "$hook_dir/%s%s" ]; then
+	"$hook_dir/%s%s" "$@" || exit $?
+fi
+exe=%s
+[ -x "$exe" ] || exe=$(command -v git-genius) || exit 0
+"$exe" hook run %s "$@"%s
+`
+
+func hookCmd(dep *SharedDependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hook",
+		Short: "Manage the git hooks that generate and check commit messages for plain git commit",
+		Long: "Manage the git hooks of the current repository. " + prepareCommitMsgHook + " fills in a " +
+			"generated message for a plain git commit, " + commitMsgHook + " checks the messages people " +
+			"write against the commit rules and the staged diff as set by hook.lint.",
+	}
+
+	cmd.AddCommand(hookInstallCmd())
+	cmd.AddCommand(hookUninstallCmd())
+	cmd.AddCommand(hookRunCmd(dep))
+
+	return cmd
+}
+
+func hookInstallCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install [hook...]",
+		Short: "Install hooks in the current repository, " + prepareCommitMsgHook + " by default",
+		Long: "Install hooks (" + strings.Join(supportedHooks, ", ") + ") in the current repository, " +
+			prepareCommitMsgHook + " by default. An existing hook is kept as <hook>" + chainedHookSuffix +
+			" and still runs before git-genius.",
+		ValidArgs:   supportedHooks,
+		Annotations: map[string]string{skipConfigAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			force, _ := cmd.Flags().GetBool("force")
+
+			hooks, err := hookArgs(args)
+			if err != nil {
+				return err
+			}
+			for _, hook := range hooks {
+				path, err := installHook(hook, force)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Installed %s\n", path)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("force", false, "Replace a hook kept from an earlier install")
+
+	return cmd
+}
+
+func hookUninstallCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:         "uninstall [hook...]",
+		Short:       "Remove git-genius hooks and restore the hooks they replaced, " + prepareCommitMsgHook + " by default",
+		ValidArgs:   supportedHooks,
+		Annotations: map[string]string{skipConfigAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			hooks, err := hookArgs(args)
+			if err != nil {
+				return err
+			}
+			for _, hook := range hooks {
+				path, restored, err := uninstallHook(hook)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Removed %s\n", path)
+				if restored {
+					fmt.Printf("Restored the previous %s hook\n", hook)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// hookArgs checks the hook names given to install and uninstall
+func hookArgs(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return []string{prepareCommitMsgHook}, nil
+	}
+	for _, hook := range args {
+		if !slices.Contains(supportedHooks, hook) {
+			return nil, usageError("unsupported hook %q, expected one of: %s", hook, strings.Join(supportedHooks, ", "))
+		}
+	}
+	return args, nil
+}
+
+func hookRunCmd(dep *SharedDependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run <hook> <message-file> [source] [commit]",
+		Short: "Entry point called by the installed hooks",
+		Long: prepareCommitMsgHook + " fills the commit message file for a plain git commit. Merges, " +
+			"amends, -m/-F/-c/-C messages, templates and rebases are left alone, and git continues " +
+			"with the unchanged message when generation fails or takes longer than hook.timeout.\n\n" +
+			commitMsgHook + " checks the message with the local rules first, then asks the LLM whether " +
+			"it matches the staged diff. Problems are reported, and with hook.lint set to block, " +
+			"errors reject the commit.",
+		Args:        cobra.RangeArgs(2, 4),
+		Hidden:      true,
+		Annotations: map[string]string{skipConfigAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch args[0] {
+			case prepareCommitMsgHook:
+				source := ""
+				if len(args) > 2 {
+					source = args[2]
+				}
+				return runPrepareCommitMsg(cmd, dep, args[1], source)
+			case commitMsgHook:
+				return runCommitMsg(cmd, dep, args[1])
+			default:
+				return usageError("unsupported hook %q", args[0])
+			}
+		},
+	}
+
+	cmd.Flags().Duration("timeout", 0, "Give up on the LLM after this long (default hook.timeout or 10s)")
+
+	return cmd
+}
+
+// hookContext bounds the secret commands, context providers and LLM calls
+// of a hook by hook.timeout or --timeout
+func hookContext(cmd *cobra.Command, cfg *config.Config) (context.Context, context.CancelFunc, time.Duration, error) {
+	timeout, err := cfg.HookTimeout()
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("invalid hook.timeout: %v", err)
+	}
+	if cmd.Flags().Changed("timeout") {
+		timeout, _ = cmd.Flags().GetDuration("timeout")
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+	return ctx, cancel, timeout, nil
+}
+
+func runPrepareCommitMsg(cmd *cobra.Command, dep *SharedDependencies, messageFile, source string) error {
+	if !shouldPrepareCommitMsg(messageFile, source) {
+		return nil
+	}
+
+	if err := loadConfigLayers(cmd, dep); err != nil {
+		return err
+	}
+
+	ctx, cancel, timeout, err := hookContext(cmd, dep.cfg)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	fmt.Fprintln(os.Stderr, "git-genius: generating commit message...")
+	err = resolveDependencies(ctx, dep, true)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("no commit message after %s, continuing without one", timeout)
+	}
+	if err != nil {
+		return err
+	}
+	message, err := dep.sdk.GenerateCommitMessage(ctx)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("no commit message after %s, continuing without one", timeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	return prependMessage(messageFile, message)
+}
+
+// runCommitMsg lints the message of a commit. Only findings can fail the
+// hook, anything going wrong in git-genius itself is reported as a warning.
+func runCommitMsg(cmd *cobra.Command, dep *SharedDependencies, messageFile string) error {
+	// git commit -v passes the diff below the scissors line along
+	message, err := readCommitMessage(messageFile)
+	if err != nil {
+		hookWarning("failed to read commit message: %v", err)
+		return nil
+	}
+	if message == "" || !shouldLintCommitMsg(message) {
+		return nil
+	}
+
+	if err := loadConfigLayers(cmd, dep); err != nil {
+		hookWarning("%v", err)
+		return nil
+	}
+	mode := dep.cfg.HookLint()
+	if mode == config.HookLintOff {
+		return nil
+	}
+
+	ctx, cancel, timeout, err := hookContext(cmd, dep.cfg)
+	if err != nil {
+		hookWarning("%v", err)
+		return nil
+	}
+	defer cancel()
+
+	err = resolveDependencies(ctx, dep, true)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		hookWarning("no config after %s, the commit message was not checked", timeout)
+		return nil
+	}
+	if err != nil {
+		hookWarning("%v", err)
+		return nil
+	}
+
+	// the local rules run first, a blocked commit needs no LLM call
+	findings := dep.sdk.LintCommitMessage(message)
+	score := 0
+	if mode != config.HookLintBlock || !hasErrors(findings) {
+		review, err := dep.sdk.ReviewCommitMessage(ctx, message, amending())
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			hookWarning("no review after %s, only the local rules were checked", timeout)
+		case err != nil:
+			hookWarning("review failed, only the local rules were checked: %v", err)
+		default:
+			findings = append(findings, review.Findings...)
+			score = review.Score
+		}
+	}
+
+	if len(findings) == 0 {
+		return nil
+	}
+
+	if score > 0 {
+		fmt.Fprintf(os.Stderr, "git-genius: commit message review, score %d/10\n", score)
+	} else {
+		fmt.Fprintln(os.Stderr, "git-genius: commit message review")
+	}
+	for _, finding := range findings {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", finding.Severity, finding)
+	}
+
+	if mode == config.HookLintBlock && hasErrors(findings) {
+		return &ExitError{Code: ExitFailure, Err: errors.New("commit message rejected, fix it or skip the check with git commit --no-verify")}
+	}
+	return nil
+}
+
+func hasErrors(findings []sdk.Finding) bool {
+	for _, finding := range findings {
+		if finding.Severity == sdk.SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func hookWarning(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "git-genius: warning: "+format+"\n", args...)
+}
+
+// shouldPrepareCommitMsg reports whether the message of this commit should
+// be generated
+func shouldPrepareCommitMsg(messageFile, source string) bool {
+	// source is empty for a plain git commit, otherwise one of message (-m,
+	// -F), template, merge, squash or commit (--amend, -c, -C)
+	if source != "" || rebaseInProgress() {
+		return false
+	}
+
+	// leave messages written by another hook alone
+	message, err := readCommitMessage(messageFile)
+	return err == nil && message == ""
+}
+
+// shouldLintCommitMsg skips messages git wrote itself or that will be squashed away
+func shouldLintCommitMsg(message string) bool {
+	if rebaseInProgress() {
+		return false
+	}
+	if path, err := gitPath("MERGE_HEAD"); err == nil {
+		if _, err := os.Stat(path); err == nil {
+			return false
+		}
+	}
+	for _, prefix := range []string{"fixup! ", "squash! ", "amend! "} {
+		if strings.HasPrefix(message, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// amending reports whether the hook runs for git commit --amend. git doesn't
+// tell commit-msg hooks, but it keeps the author of the amended commit and
+// hands it to them, so the author ident matches the one of HEAD.
+func amending() bool {
+	ident, err := exec.Command("git", "var", "GIT_AUTHOR_IDENT").Output()
+	if err != nil {
+		return false
+	}
+	head, err := exec.Command("git", "log", "-1", "--format=%an <%ae> %ad", "--date=raw", "HEAD").Output()
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(ident)) == strings.TrimSpace(string(head))
+}
+
+// rebaseInProgress reports whether a rebase is replaying existing commits
+func rebaseInProgress() bool {
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		if path, err := gitPath(dir); err == nil {
+			if _, err := os.Stat(path); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// prependMessage writes message above git's comments in the message file
+func prependMessage(messageFile, message string) error {
+	content, err := os.ReadFile(messageFile)
+	if err != nil {
+		return fmt.Errorf("failed to read commit message file: %w", err)
+	}
+
+	content = append([]byte(strings.TrimSpace(message)+"\n"), content...)
+	if err := os.WriteFile(messageFile, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write commit message file: %w", err)
+	}
+	return nil
+}
+
+// scissorsLine follows the comment character on the line git commit -v
+// writes above the diff, git ignores everything below it
+const scissorsLine = "------------------------ >8 ------------------------"
+
+// autoCommentChars are the characters git picks from with core.commentChar
+// set to auto
+const autoCommentChars = "#;@!$%^&|:"
+
+// readCommitMessage reads a commit message file as git will store it,
+// without comments and without the diff of git commit -v
+func readCommitMessage(messageFile string) (string, error) {
+	content, err := os.ReadFile(messageFile)
+	if err != nil {
+		return "", err
+	}
+	return stripComments(string(content), commentChar(string(content))), nil
+}
+
+// commentChar returns what comment lines start with, core.commentChar or #
+func commentChar(message string) string {
+	out, err := exec.Command("git", "config", "--get", "core.commentChar").Output()
+	char := strings.TrimRight(string(out), "\n")
+	switch {
+	case err != nil || char == "":
+		return "#"
+	case char == "auto":
+		return detectCommentChar(message)
+	default:
+		return char
+	}
+}
+
+// detectCommentChar finds the character git chose for core.commentChar
+// auto: the one starting the scissors line, or git's template comments
+func detectCommentChar(message string) string {
+	lines := strings.Split(message, "\n")
+	for _, line := range lines {
+		if prefix, ok := strings.CutSuffix(line, " "+scissorsLine); ok && prefix != "" {
+			return prefix
+		}
+	}
+	// the template ends with comments like "# Please enter ..." or "#"
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := lines[i]
+		if line == "" {
+			continue
+		}
+		if strings.ContainsRune(autoCommentChars, rune(line[0])) && (len(line) == 1 || line[1] == ' ') {
+			return line[:1]
+		}
+		break
+	}
+	return "#"
+}
+
+// stripComments drops the lines git ignores in a commit message: comment
+// lines and everything below the scissors line
+func stripComments(message, commentChar string) string {
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if line == commentChar+" "+scissorsLine {
+			break
+		}
+		if !strings.HasPrefix(line, commentChar) {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// installHook writes the hook called name, keeping an existing one as
+// <name>.pre-git-genius
+func installHook(name string, force bool) (string, error) {
+	dir, err := gitPath("hooks")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+
+	path := filepath.Join(dir, name)
+	chained := path + chainedHookSuffix
+
+	existing, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return "", fmt.Errorf("failed to read existing hook: %w", err)
+	case strings.Contains(string(existing), hookMarker):
+		// reinstall over our own hook, e.g. after the binary moved
+	default:
+		if _, err := os.Stat(chained); err == nil && !force {
+			return "", fmt.Errorf("%s already exists, use --force to replace it with the current %s hook", chained, name)
+		}
+		if err := os.Rename(path, chained); err != nil {
+			return "", fmt.Errorf("failed to keep the existing hook: %w", err)
+		}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		exe = "git-genius"
+	}
+
+	// a failing prepare-commit-msg must not stop the commit, commit-msg
+	// fails on purpose to block it
+	onFailure := ""
+	if name == prepareCommitMsgHook {
+		onFailure = " || true"
+	}
+
+	script := fmt.Sprintf(hookScript, hookMarker, name, name, chainedHookSuffix, name, chainedHookSuffix, shell.Quote(exe), name, onFailure)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		return "", fmt.Errorf("failed to write hook: %w", err)
+	}
+	return path, nil
+}
+
+// uninstallHook removes the hook called name and restores the one it replaced
+func uninstallHook(name string) (string, bool, error) {
+	dir, err := gitPath("hooks")
+	if err != nil {
+		return "", false, err
+	}
+	path := filepath.Join(dir, name)
+
+	existing, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", false, fmt.Errorf("no %s hook is installed", name)
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read hook: %w", err)
+	}
+	if !strings.Contains(string(existing), hookMarker) {
+		return "", false, fmt.Errorf("%s was not installed by git-genius, leaving it in place", path)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return "", false, fmt.Errorf("failed to remove hook: %w", err)
+	}
+
+	chained := path + chainedHookSuffix
+	if _, err := os.Stat(chained); err != nil {
+		return path, false, nil
+	}
+	if err := os.Rename(chained, path); err != nil {
+		return path, false, fmt.Errorf("failed to restore the previous hook: %w", err)
+	}
+	return path, true, nil
+}
+
+// gitPath resolves a path inside the git directory, honouring core.hooksPath
+// and worktrees
+func gitPath(name string) (string, error) {
+	out, err := exec.Command("git", "rev-parse", "--git-path", name).Output()
+	if err != nil {
+		return "", fmt.Errorf("not a git repository: %w", err)
+	}
+	path := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(path) {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get working directory: %w", err)
+		}
+		path = filepath.Join(wd, path)
+	}
+	return path, nil
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadCommitMessage(t *testing.T) {
+	verbose := "feat: add login\n\nBody line.\n" +
+		"# Please enter the commit message for your changes.\n" +
+		"# ------------------------ >8 ------------------------\n" +
+		"# Do not modify or remove the line above.\n" +
+		"diff --git a/main.go b/main.go\n" +
+		"+# not a comment\n" +
+		"+fmt.Println(\"x\")\n"
+
+	tests := []struct {
+		name        string
+		commentChar string
+		content     string
+		want        string
+	}{
+		{
+			name:    "comments",
+			content: "fix: typo\n\n# Please enter the commit message\n#\n",
+			want:    "fix: typo",
+		},
+		{
+			name:    "scissors line",
+			content: verbose,
+			want:    "feat: add login\n\nBody line.",
+		},
+		{
+			name:        "comment char",
+			commentChar: ";",
+			content:     "fix: typo\n# keep me\n; drop me\n; ------------------------ >8 ------------------------\n+diff\n",
+			want:        "fix: typo\n# keep me",
+		},
+		{
+			name:        "auto from scissors line",
+			commentChar: "auto",
+			content:     "#1 fix: typo\n; comment\n; ------------------------ >8 ------------------------\n+diff\n",
+			want:        "#1 fix: typo",
+		},
+		{
+			name:        "auto from template",
+			commentChar: "auto",
+			content:     "#1 fix: typo\n\n; Please enter the commit message\n;\n",
+			want:        "#1 fix: typo",
+		},
+		{
+			name:        "auto without comments",
+			commentChar: "auto",
+			content:     "fix: typo\n",
+			want:        "fix: typo",
+		},
+		{
+			name:    "only comments",
+			content: "# Please enter the commit message\n# ------------------------ >8 ------------------------\n+diff\n",
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+			t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+			if tt.commentChar != "" {
+				t.Setenv("GIT_CONFIG_COUNT", "1")
+				t.Setenv("GIT_CONFIG_KEY_0", "core.commentChar")
+				t.Setenv("GIT_CONFIG_VALUE_0", tt.commentChar)
+			} else {
+				t.Setenv("GIT_CONFIG_COUNT", "0")
+			}
+
+			file := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+			if err := os.WriteFile(file, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readCommitMessage(file)
+			if err != nil {
+				t.Fatalf("readCommitMessage() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("readCommitMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmending(t *testing.T) {
+	const headDate = "1700000000 +0100"
+
+	tests := []struct {
+		name string
+		// commit creates HEAD before the hook runs
+		commit bool
+		author string
+		date   string
+		want   bool
+	}{
+		{name: "amend", commit: true, author: "Sam", date: headDate, want: true},
+		{name: "new commit", commit: true, author: "Sam", date: "1700000060 +0100"},
+		{name: "other author", commit: true, author: "Alex", date: headDate},
+		{name: "unborn HEAD", author: "Sam", date: headDate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testRepo(t)
+			if tt.commit {
+				t.Setenv("GIT_AUTHOR_DATE", headDate)
+				git(t, dir, "commit", "--allow-empty", "-m", "feat: add login")
+			}
+
+			t.Setenv("GIT_AUTHOR_NAME", tt.author)
+			t.Setenv("GIT_AUTHOR_DATE", tt.date)
+			if got := amending(); got != tt.want {
+				t.Errorf("amending() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
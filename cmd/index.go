@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"git-genius/config"
+	commitindex "git-genius/internal/commit_index"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// indexResult is the --output json result of index
+type indexResult struct {
+	Path      string `json:"path"`
+	Embedding string `json:"embedding"`
+	Added     int    `json:"added"`
+	Commits   int    `json:"commits"`
+}
+
+// indexMatch is a commit found by index --search
+type indexMatch struct {
+	Hash    string  `json:"hash"`
+	Date    string  `json:"date"`
+	Author  string  `json:"author"`
+	Subject string  `json:"subject"`
+	Score   float64 `json:"score"`
+}
+
+func indexCmd(dep *SharedDependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "index",
+		Short: "Index past commits to find similar ones quickly",
+		Long: "Add the commits of HEAD that are not indexed yet to the commit index in .git/git-genius/, " +
+			"up to index.max_commits of the latest ones. commit and pr give the messages of the indexed " +
+			"commits most similar to the change as style examples, instead of the latest subjects.\n\n" +
+			"Commits are matched by shared words (BM25), or with index.embedding ollama by embeddings of " +
+			"index.model served at index.url. Changing the model embeds the indexed commits again. An " +
+			"interrupted run is resumed by the next one.",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{skipSDKAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			rebuild, _ := cmd.Flags().GetBool("rebuild")
+			search, _ := cmd.Flags().GetString("search")
+			limit, _ := cmd.Flags().GetInt("limit")
+			output, err := outputFormat(cmd, outputText, outputJSON)
+			if err != nil {
+				return err
+			}
+			if rebuild && cmd.Flags().Changed("search") {
+				return usageError("--rebuild and --search cannot be combined")
+			}
+			if limit < 1 {
+				return usageError("--limit must be at least 1")
+			}
+
+			path, err := commitindex.DefaultPath()
+			if err != nil {
+				return err
+			}
+			if rebuild {
+				if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+					return fmt.Errorf("failed to remove commit index: %w", err)
+				}
+			}
+			idx, err := commitindex.Open(path)
+			if err != nil {
+				return err
+			}
+			embedder := dep.cfg.NewEmbedder()
+
+			if cmd.Flags().Changed("search") {
+				return searchIndex(cmd, idx, embedder, search, limit, output)
+			}
+
+			var progress func(done, total int)
+			if output == outputText && term.IsTerminal(int(os.Stderr.Fd())) {
+				progress = func(done, total int) {
+					fmt.Fprintf(os.Stderr, "\rIndexing commits %d/%d", done, total)
+				}
+			}
+			added, err := idx.Update(ctx, embedder, dep.cfg.IndexMaxCommits(), progress)
+			if progress != nil && added > 0 {
+				fmt.Fprintln(os.Stderr)
+			}
+			if err != nil {
+				return err
+			}
+
+			result := indexResult{Path: path, Embedding: embeddingName(embedder), Added: added, Commits: len(idx.Documents)}
+			if output == outputJSON {
+				return printJSON(result)
+			}
+			fmt.Printf("Indexed %d new commit(s), %d in total (%s).\n", result.Added, result.Commits, result.Embedding)
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("rebuild", false, "Discard the index and index every commit again")
+	cmd.Flags().String("search", "", "Show the indexed commits most similar to a text instead of updating the index")
+	cmd.Flags().Int("limit", 10, "Number of commits --search shows")
+
+	return cmd
+}
+
+// searchIndex prints the commits most similar to text
+func searchIndex(cmd *cobra.Command, idx *commitindex.Index, embedder commitindex.Embedder, text string, limit int, output string) error {
+	if len(idx.Documents) == 0 {
+		return fmt.Errorf("the commit index is empty, run git-genius index first")
+	}
+	found, err := idx.Search(cmd.Context(), embedder, text, limit)
+	if err != nil {
+		return err
+	}
+
+	matches := []indexMatch{}
+	for _, match := range found {
+		subject, _, _ := strings.Cut(match.Message, "\n")
+		matches = append(matches, indexMatch{
+			Hash:    match.Hash,
+			Date:    match.Date,
+			Author:  match.Author,
+			Subject: subject,
+			Score:   match.Score,
+		})
+	}
+
+	if output == outputJSON {
+		return printJSON(matches)
+	}
+	for _, match := range matches {
+		fmt.Printf("%s %s %.3f %s\n", shortHash(match.Hash), match.Date, match.Score, match.Subject)
+	}
+	return nil
+}
+
+// embeddingName describes how the index matches commits
+func embeddingName(embedder commitindex.Embedder) string {
+	if embedder == nil {
+		return config.IndexEmbeddingBM25
+	}
+	return embedder.Name()
+}
@@ -0,0 +1,395 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"git-genius/config"
+	context_provider "git-genius/internal/context_provider"
+	versioncontrol "git-genius/internal/version_control"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	secretStorePlain   = "plain"
+	secretStoreKeyring = "keyring"
+	secretStoreEnv     = "env"
+
+	// answerNone skips an optional integration
+	answerNone = "none"
+)
+
+// secretEnvVars are the variables referenced when secrets are stored as ${ENV_VAR}
+var secretEnvVars = map[string]string{
+	"gemini": "GEMINI_API_KEY",
+	"github": "GITHUB_TOKEN",
+	"gitlab": "GITLAB_TOKEN",
+	"linear": "LINEAR_API_KEY",
+}
+
+// initOptions holds the answers of the init wizard
+type initOptions struct {
+	local          bool
+	nonInteractive bool
+	force          bool
+	skipValidation bool
+
+	llm              string
+	llmAPIKey        string
+	vcs              string
+	vcsToken         string
+	issueTracker     string
+	issueTrackerKey  string
+	prTemplate       string
+	baseBranch       string
+	secretStore      string
+	configPathOption string
+}
+
+func initCmd() *cobra.Command {
+	opts := &initOptions{}
+
+	cmd := &cobra.Command{
+		Use:         "init",
+		Short:       "Create a git-genius configuration",
+		Long:        "Detect the repository setup, ask for the LLM and issue tracker to use, validate the credentials and write a configuration file.",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{skipConfigAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.configPathOption, _ = cmd.Flags().GetString("config")
+			if yes, _ := cmd.Flags().GetBool("yes"); yes {
+				opts.nonInteractive = true
+			}
+			return runInit(cmd.Context(), opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.local, "local", false, "Write the repository-local "+config.RepoConfigFile+" instead of the user config")
+	cmd.Flags().BoolVar(&opts.nonInteractive, "non-interactive", false, "Do not ask questions, use flags and detected defaults")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "Overwrite an existing config file")
+	cmd.Flags().BoolVar(&opts.skipValidation, "skip-validation", false, "Do not validate credentials with a test call")
+	cmd.Flags().StringVar(&opts.llm, "llm", "", "LLM to use ("+strings.Join(config.SupportedLLMs, ", ")+")")
+	cmd.Flags().StringVar(&opts.llmAPIKey, "llm-api-key", "", "API key for the LLM")
+	cmd.Flags().StringVar(&opts.vcs, "vcs", "", "Version control provider ("+strings.Join(config.SupportedVersionControls, ", ")+")")
+	cmd.Flags().StringVar(&opts.vcsToken, "vcs-token", "", "Token for the version control provider")
+	cmd.Flags().StringVar(&opts.issueTracker, "issue-tracker", "", "Issue tracker to use (none, linear)")
+	cmd.Flags().StringVar(&opts.issueTrackerKey, "issue-tracker-api-key", "", "API key for the issue tracker")
+	cmd.Flags().StringVar(&opts.prTemplate, "pr-template", "", "Path of the PR template, 'none' to skip")
+	cmd.Flags().StringVar(&opts.baseBranch, "base-branch", "", "Base branch for pull requests")
+	cmd.Flags().StringVar(&opts.secretStore, "secret-store", "", "Where to keep credentials: plain (in the file), keyring or env (${ENV_VAR} references)")
+
+	return cmd
+}
+
+func runInit(ctx context.Context, opts *initOptions) error {
+	if opts.local && (opts.llmAPIKey != "" || opts.vcsToken != "" || opts.issueTrackerKey != "" || opts.secretStore != "") {
+		return usageError("credentials cannot be written to %s, run init without --local to keep them in the user config", config.RepoConfigFile)
+	}
+	if opts.local && opts.vcs != "" {
+		return usageError("version_control.provider cannot be written to %s, run init without --local to set it in the user config", config.RepoConfigFile)
+	}
+
+	p := newPrompter()
+
+	// Detect the repository setup
+	root := repoRoot(ctx)
+	var detectedVCS string
+	if remote, err := versioncontrol.GetRemote(); err == nil {
+		detectedVCS = vcsForHost(remote.Host)
+		fmt.Printf("Detected remote: %s/%s on %s\n", remote.Owner, remote.Repo, remote.Host)
+	}
+	var templates []string
+	if root != "" {
+		templates = context_provider.DetectPRTemplates(root)
+		for _, template := range templates {
+			fmt.Printf("Detected PR template: %s\n", template)
+		}
+	}
+	detectedBase := defaultBaseBranch()
+
+	// Decide where to write
+	path := opts.configPathOption
+	if opts.local {
+		if root == "" {
+			return fmt.Errorf("--local requires running inside a git repository")
+		}
+		path = filepath.Join(root, config.RepoConfigFile)
+	} else if path == "" {
+		path = config.DefaultConfigPath()
+	}
+
+	if _, err := os.Stat(path); err == nil && !opts.force {
+		if opts.nonInteractive || !p.confirm(fmt.Sprintf("%s already exists, overwrite it?", path), false) {
+			return fmt.Errorf("config file already exists at path: %s (use --force to overwrite)", path)
+		}
+	}
+
+	// Ask the remaining questions, flags answer them up front
+	answer := func(value, question, def string, options []string) string {
+		if value != "" {
+			return value
+		}
+		if opts.nonInteractive {
+			return def
+		}
+		if options != nil {
+			return p.choose(question, options, def)
+		}
+		return p.ask(question, def)
+	}
+	secret := func(value, question string) string {
+		// credentials are never written to the repository file
+		if value != "" || opts.nonInteractive || opts.local {
+			return value
+		}
+		return p.askSecret(question + " (leave empty to set it later)")
+	}
+
+	if detectedVCS == "" {
+		detectedVCS = config.SupportedVersionControls[0]
+	}
+	defaultTemplate := answerNone
+	if len(templates) > 0 {
+		defaultTemplate = templates[0]
+	}
+
+	opts.llm = answer(opts.llm, "Which LLM should be used?", config.SupportedLLMs[0], config.SupportedLLMs)
+	opts.llmAPIKey = secret(opts.llmAPIKey, fmt.Sprintf("API key for %s", opts.llm))
+	// the provider decides where the token is sent, the user config sets it
+	if !opts.local {
+		opts.vcs = answer(opts.vcs, "Which version control provider hosts the repository?", detectedVCS, config.SupportedVersionControls)
+	}
+	opts.vcsToken = secret(opts.vcsToken, fmt.Sprintf("Token for %s", opts.vcs))
+	opts.issueTracker = answer(opts.issueTracker, "Which issue tracker do you use?", answerNone,
+		[]string{answerNone, string(context_provider.LinearContextProviderType)})
+	if opts.issueTracker != answerNone {
+		opts.issueTrackerKey = secret(opts.issueTrackerKey, fmt.Sprintf("API key for %s", opts.issueTracker))
+	}
+	opts.prTemplate = answer(opts.prTemplate, "PR template path ('none' to skip)", defaultTemplate, nil)
+	opts.baseBranch = answer(opts.baseBranch, "Base branch for pull requests", detectedBase, nil)
+	if !opts.local {
+		opts.secretStore = answer(opts.secretStore, "Where should credentials be kept?", secretStorePlain,
+			[]string{secretStorePlain, secretStoreKeyring, secretStoreEnv})
+	}
+
+	cfg := buildInitConfig(opts)
+
+	// Validate the credentials before anything is written, the repository
+	// file has none
+	if !opts.skipValidation && !opts.local {
+		if failed := validateCredentials(ctx, resolvedInitConfig(cfg, opts)); failed > 0 {
+			if opts.nonInteractive || !p.confirm("Some checks failed, write the config anyway?", false) {
+				return fmt.Errorf("%d credential check(s) failed", failed)
+			}
+		}
+	}
+
+	if !opts.local {
+		if err := storeInitSecrets(&cfg, opts); err != nil {
+			return err
+		}
+	}
+
+	if err := cfg.Save(path); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote config to %s\n", path)
+	if opts.local {
+		fmt.Println("Credentials and the version control provider are read from the user config or GIT_GENIUS_* environment variables, e.g.:")
+		for _, key := range []string{"llm.api_key", "version_control.provider", "version_control.token"} {
+			fmt.Printf("  %s\n", config.EnvVarName(key))
+		}
+	}
+	if opts.secretStore == secretStoreEnv {
+		fmt.Println("Credentials are read from the environment, make sure these are exported:")
+		for _, name := range usedSecretEnvVars(opts) {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+	return nil
+}
+
+// buildInitConfig turns the answers into a config holding plaintext credentials
+func buildInitConfig(opts *initOptions) config.Config {
+	cfg := config.Config{
+		Version: config.CurrentVersion,
+		LLM: config.LLMConfig{
+			Name:   opts.llm,
+			APIKey: config.Secret(opts.llmAPIKey),
+		},
+		VersionControl: config.VersionControlConfig{
+			Provider: opts.vcs,
+			Token:    config.Secret(opts.vcsToken),
+		},
+		ContextProviders: []config.ProviderConfig{
+			{Name: string(context_provider.GitContextProviderType)},
+		},
+		PullRequest: config.PullRequestConfig{
+			BaseBranch: opts.baseBranch,
+		},
+	}
+
+	if opts.prTemplate != "" && opts.prTemplate != answerNone {
+		cfg.ContextProviders = append(cfg.ContextProviders, config.ProviderConfig{
+			Name: string(context_provider.PRTemplateContextProviderType),
+			Path: opts.prTemplate,
+		})
+	}
+
+	if opts.issueTracker != answerNone {
+		cfg.ContextProviders = append(cfg.ContextProviders, config.ProviderConfig{
+			Name:   opts.issueTracker,
+			APIKey: config.Secret(opts.issueTrackerKey),
+		})
+	}
+
+	return cfg
+}
+
+// resolvedInitConfig fills credentials left empty from the environment so
+// that env references can be validated too
+func resolvedInitConfig(cfg config.Config, opts *initOptions) config.Config {
+	fromEnv := func(secret config.Secret, name string) config.Secret {
+		if secret == "" {
+			return config.Secret(os.Getenv(secretEnvVars[name]))
+		}
+		return secret
+	}
+
+	cfg.LLM.APIKey = fromEnv(cfg.LLM.APIKey, cfg.LLM.Name)
+	cfg.VersionControl.Token = fromEnv(cfg.VersionControl.Token, cfg.VersionControl.Provider)
+
+	providers := make([]config.ProviderConfig, len(cfg.ContextProviders))
+	copy(providers, cfg.ContextProviders)
+	for i := range providers {
+		if providers[i].Name == opts.issueTracker {
+			providers[i].APIKey = fromEnv(providers[i].APIKey, providers[i].Name)
+		}
+	}
+	cfg.ContextProviders = providers
+
+	return cfg
+}
+
+// storeInitSecrets replaces plaintext credentials according to the secret store
+func storeInitSecrets(cfg *config.Config, opts *initOptions) error {
+	type credential struct {
+		name    string
+		secret  *config.Secret
+		keyring *string
+	}
+
+	credentials := []credential{
+		{cfg.LLM.Name, &cfg.LLM.APIKey, &cfg.LLM.APIKeyKeyring},
+		{cfg.VersionControl.Provider, &cfg.VersionControl.Token, &cfg.VersionControl.TokenKeyring},
+	}
+	for i := range cfg.ContextProviders {
+		provider := &cfg.ContextProviders[i]
+		if provider.Name == opts.issueTracker {
+			credentials = append(credentials, credential{provider.Name, &provider.APIKey, &provider.APIKeyKeyring})
+		}
+	}
+
+	for _, c := range credentials {
+		switch opts.secretStore {
+		case secretStoreKeyring:
+			if *c.secret == "" {
+				continue
+			}
+			if err := config.StoreSecret(c.name, c.secret.Reveal()); err != nil {
+				return err
+			}
+			*c.secret = ""
+			*c.keyring = c.name
+		case secretStoreEnv:
+			*c.secret = config.Secret("${" + secretEnvVars[c.name] + "}")
+		case secretStorePlain:
+			// keep the value in the file
+		default:
+			return fmt.Errorf("unsupported secret store: %s", opts.secretStore)
+		}
+	}
+
+	return nil
+}
+
+func usedSecretEnvVars(opts *initOptions) []string {
+	names := []string{secretEnvVars[opts.llm], secretEnvVars[opts.vcs]}
+	if opts.issueTracker != answerNone {
+		names = append(names, secretEnvVars[opts.issueTracker])
+	}
+	return names
+}
+
+// validateCredentials makes a test call to every configured service and
+// returns the number of failures
+func validateCredentials(ctx context.Context, cfg config.Config) int {
+	failed := 0
+	report := func(name string, err error) {
+		if err != nil {
+			failed++
+			fmt.Printf("  ✗ %s: %v\n", name, err)
+			return
+		}
+		fmt.Printf("  ✓ %s\n", name)
+	}
+
+	fmt.Println("Validating credentials:")
+
+	llm, err := cfg.NewLLM(ctx)
+	if err == nil {
+		err = llm.Ping(ctx)
+	}
+	report(cfg.LLM.Name, err)
+
+	prCreator, err := cfg.NewPRCreator(ctx)
+	if err == nil {
+		err = prCreator.Ping(ctx)
+	}
+	report(cfg.VersionControl.Provider, err)
+
+	for _, providerConfig := range cfg.ContextProviders {
+		provider, err := context_provider.NewContextProvider(providerConfig, "")
+		if err != nil {
+			report(providerConfig.Name, err)
+			continue
+		}
+		if pinger, ok := provider.(context_provider.Pinger); ok {
+			report(providerConfig.Name, pinger.Ping(ctx))
+		}
+	}
+
+	return failed
+}
+
+// vcsForHost maps a remote host to a version control provider
+func vcsForHost(host string) string {
+	for _, provider := range config.SupportedVersionControls {
+		if strings.Contains(host, provider) {
+			return provider
+		}
+	}
+	return ""
+}
+
+// repoRoot returns the top level directory of the current repository, or
+// an empty string outside of one
+func repoRoot(ctx context.Context) string {
+	root, _ := context_provider.RepoRoot(ctx)
+	return root
+}
+
+// defaultBaseBranch returns the branch origin/HEAD points to
+func defaultBaseBranch() string {
+	out, err := exec.Command("git", "symbolic-ref", "--short", "refs/remotes/origin/HEAD").Output()
+	if err != nil {
+		return "main"
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(out)), "origin/")
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// prompter asks the user questions on the terminal
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	// eof is set once the input is exhausted
+	eof bool
+}
+
+func newPrompter() *prompter {
+	return &prompter{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+}
+
+// ask reads a line, returning def when the answer is empty
+func (p *prompter) ask(question, def string) string {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+
+	answer, err := p.in.ReadString('\n')
+	if err != nil {
+		p.eof = true
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return def
+	}
+	return answer
+}
+
+// choose asks until one of the options is picked, or returns def once the
+// input is exhausted
+func (p *prompter) choose(question string, options []string, def string) string {
+	for {
+		answer := p.ask(fmt.Sprintf("%s (%s)", question, strings.Join(options, "/")), def)
+		for _, option := range options {
+			if strings.EqualFold(answer, option) {
+				return option
+			}
+		}
+		if p.eof {
+			return def
+		}
+		fmt.Fprintf(p.out, "Please choose one of: %s\n", strings.Join(options, ", "))
+	}
+}
+
+// confirm asks a yes/no question
+func (p *prompter) confirm(question string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+
+	answer := strings.ToLower(p.ask(fmt.Sprintf("%s (%s)", question, hint), ""))
+	switch answer {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	default:
+		return def
+	}
+}
+
+// askSecret reads a credential without echoing it when attached to a terminal
+func (p *prompter) askSecret(question string) string {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return p.ask(question, "")
+	}
+
+	fmt.Fprintf(p.out, "%s: ", question)
+	secret, _ := term.ReadPassword(fd)
+	fmt.Fprintln(p.out)
+	return strings.TrimSpace(string(secret))
+}
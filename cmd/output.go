@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// exit codes reported by git-genius, git's own code is passed through for
+// failed git commands
+const (
+	ExitFailure  = 1
+	ExitUsage    = 2
+	ExitCanceled = 3
+)
+
+// output formats accepted by --output
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputRaw  = "raw"
+	// outputSARIF is only supported by review
+	outputSARIF = "sarif"
+)
+
+var outputFormats = []string{outputText, outputJSON, outputRaw}
+
+// ExitError carries the status the process should exit with
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// usageError reports invalid flags or arguments
+func usageError(format string, args ...interface{}) error {
+	return &ExitError{Code: ExitUsage, Err: fmt.Errorf(format, args...)}
+}
+
+// gitError keeps the exit code of a failed git command
+func gitError(message string, err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &ExitError{Code: exitErr.ExitCode(), Err: fmt.Errorf("%s: %v", message, err)}
+	}
+	return fmt.Errorf("%s: %v", message, err)
+}
+
+// outputFormat reads and checks --output against the formats cmd supports
+func outputFormat(cmd *cobra.Command, supported ...string) (string, error) {
+	output, _ := cmd.Flags().GetString("output")
+	if len(supported) == 0 {
+		supported = outputFormats
+	}
+	if !slices.Contains(supported, output) {
+		return "", usageError("unsupported output format %q for %s, expected one of: %s",
+			output, cmd.CommandPath(), strings.Join(supported, ", "))
+	}
+	return output, nil
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("failed to encode output: %w", err)
+	}
+	return nil
+}
+
+// interactive reports whether the user can be asked questions
+func interactive(cmd *cobra.Command) bool {
+	yes, _ := cmd.Flags().GetBool("yes")
+	return !yes && term.IsTerminal(int(os.Stdin.Fd()))
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		supported []string
+		want      string
+		wantErr   bool
+	}{
+		{name: "default formats", output: outputRaw, want: outputRaw},
+		{name: "supported", output: outputJSON, supported: []string{outputText, outputJSON}, want: outputJSON},
+		{name: "unsupported", output: outputRaw, supported: []string{outputText, outputJSON}, wantErr: true},
+		{name: "unknown", output: "yaml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "test"}
+			cmd.Flags().String("output", outputText, "")
+			if err := cmd.Flags().Set("output", tt.output); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := outputFormat(cmd, tt.supported...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("outputFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var exitErr *ExitError
+			if err != nil && (!errors.As(err, &exitErr) || exitErr.Code != ExitUsage) {
+				t.Errorf("outputFormat() error = %v, want exit code %d", err, ExitUsage)
+			}
+			if got != tt.want {
+				t.Errorf("outputFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{name: "git exit code", err: exec.Command("sh", "-c", "exit 128").Run(), wantCode: 128},
+		{name: "not run", err: fmt.Errorf("executable file not found"), wantCode: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := gitError("failed to commit", tt.err)
+			code := 0
+			var exitErr *ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.Code
+			}
+			if code != tt.wantCode {
+				t.Errorf("gitError() exit code = %d, want %d", code, tt.wantCode)
+			}
+			if want := "failed to commit: " + tt.err.Error(); err.Error() != want {
+				t.Errorf("gitError() = %q, want %q", err, want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -10,18 +11,36 @@ func prCmd(dep *SharedDependencies) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "pr",
 		Short: "Generate a pull request",
-		Run: func(cmd *cobra.Command, args []string) {
+		Long: "Generate a pull request title and description. --output raw prints the title, " +
+			"a blank line and the body, --output json the structured content.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
+			output, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
 			// Generate pull request
 			prContent, err := dep.sdk.GeneratePullRequestContent(ctx)
 			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-				return
+				return err
 			}
 
-			// print the title and body of the PR
-			fmt.Printf("Generated Pull Request:\nTitle: %s\nBody: %s\n",
-				prContent.Title, prContent.Body)
+			switch output {
+			case outputJSON:
+				return printJSON(prContent)
+			case outputRaw:
+				fmt.Printf("%s\n\n%s\n", prContent.Title, prContent.Body)
+			default:
+				// print the title and body of the PR
+				fmt.Printf("Generated Pull Request:\nTitle: %s\nBody: %s\n",
+					prContent.Title, prContent.Body)
+				if len(prContent.Tags) > 0 {
+					fmt.Printf("Labels: %s\n", strings.Join(prContent.Tags, ", "))
+				}
+			}
+			return nil
 		},
 	}
 
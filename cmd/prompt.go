@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"git-genius/internal/prompt"
+	"git-genius/sdk"
+
+	"github.com/spf13/cobra"
+)
+
+func promptCmd(dep *SharedDependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prompt",
+		Short: "Inspect the prompts sent to the LLM",
+		Long: "Inspect the prompts sent to the LLM. Prompts are text/template files that can be " +
+			"replaced per user or per repository with prompts.dir or prompts.templates in the config.",
+	}
+
+	cmd.AddCommand(promptRenderCmd(dep))
+	cmd.AddCommand(promptListCmd(dep))
+	cmd.AddCommand(promptDefaultCmd())
+
+	return cmd
+}
+
+func promptRenderCmd(dep *SharedDependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:         "render <commit|pull_request>",
+		Short:       "Show the final prompt for the current repository without calling the LLM",
+		Args:        cobra.ExactArgs(1),
+		ValidArgs:   []string{prompt.Commit, prompt.PullRequest},
+		Annotations: map[string]string{skipSDKAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+
+			rendered, err := sdk.RenderPrompt(cmd.Context(), dep.cfg, args[0])
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case outputJSON:
+				return printJSON(rendered)
+			case outputRaw:
+				fmt.Println(rendered.Prompt)
+				return nil
+			}
+
+			fmt.Printf("# prompt %s (%s)\n", rendered.Name, rendered.Source)
+			if rendered.System != "" {
+				fmt.Printf("\n## system\n\n%s\n", rendered.System)
+			}
+			fmt.Printf("\n## user\n\n%s\n", rendered.Prompt)
+			return nil
+		},
+	}
+
+	cmd.Flags().String("issue", "", "Issue ID to include in the prompt")
+
+	return cmd
+}
+
+func promptListCmd(dep *SharedDependencies) *cobra.Command {
+	return &cobra.Command{
+		Use:         "list",
+		Short:       "List the prompts and the template each one is read from",
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{skipSDKAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			loader := dep.cfg.NewPromptLoader()
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			for _, name := range prompt.Names {
+				source, err := loader.Source(name)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(w, "%s\t%s\n", name, source)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func promptDefaultCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:         "default <name>",
+		Short:       "Print the built-in template of a prompt, as a starting point for an override",
+		Args:        cobra.ExactArgs(1),
+		ValidArgs:   prompt.Names,
+		Annotations: map[string]string{skipConfigAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			content, err := prompt.Default(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Print(content)
+			return nil
+		},
+	}
+}
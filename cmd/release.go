@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	versioncontrol "git-genius/internal/version_control"
+	"git-genius/sdk"
+
+	"github.com/spf13/cobra"
+)
+
+// releaseResult is the --output json result of release
+type releaseResult struct {
+	*sdk.ReleaseNotes
+	Tag        string `json:"tag"`
+	From       string `json:"from,omitempty"`
+	Markdown   string `json:"markdown"`
+	TagCreated bool   `json:"tag_created"`
+	URL        string `json:"url,omitempty"`
+}
+
+func releaseCmd(dep *SharedDependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "release <tag>",
+		Short: "Write release notes for a tag and publish them as a release",
+		Long: "Write user-facing release notes for the commits since the previous tag: a summary with " +
+			"the highlights, upgrade notes from the BREAKING CHANGE footers and the contributors.\n\n" +
+			"The notes are published as a release of <tag> on the version_control provider. When the tag " +
+			"doesn't exist yet, an annotated tag is created at --target first. Tags missing on origin are " +
+			"pushed. --dry-run only prints the notes.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			tag := args[0]
+			from, _ := cmd.Flags().GetString("from")
+			target, _ := cmd.Flags().GetString("target")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			draft, _ := cmd.Flags().GetBool("draft")
+			prerelease, _ := cmd.Flags().GetBool("prerelease")
+			yes, _ := cmd.Flags().GetBool("yes")
+			output, err := outputFormat(cmd, outputText, outputJSON)
+			if err != nil {
+				return err
+			}
+
+			confirm := !dryRun && !yes
+			if confirm && (!interactive(cmd) || output != outputText) {
+				return usageError("publishing needs confirmation, use --yes to publish or --dry-run to only show the notes")
+			}
+
+			if err := exec.Command("git", "check-ref-format", "refs/tags/"+tag).Run(); err != nil {
+				return usageError("%q is not a valid tag name", tag)
+			}
+			_, tagErr := revParse("refs/tags/" + tag)
+			exists := tagErr == nil
+			if exists && cmd.Flags().Changed("target") {
+				return usageError("tag %s already exists, --target only applies to new tags", tag)
+			}
+
+			to := target
+			if exists {
+				to = "refs/tags/" + tag
+			} else if _, err := revParse(target); err != nil {
+				return err
+			}
+			if from == "" {
+				from = previousTag(to, exists)
+			} else if _, err := revParse(from); err != nil {
+				return err
+			}
+
+			notes, err := dep.sdk.GenerateReleaseNotes(ctx, from, to, tag)
+			if err != nil {
+				return err
+			}
+			result := releaseResult{ReleaseNotes: notes, Tag: tag, From: from, Markdown: notes.Markdown()}
+
+			if output == outputText {
+				fmt.Print(result.Markdown)
+			}
+
+			if !dryRun {
+				prCreator, err := dep.cfg.NewPRCreator(ctx)
+				if err != nil {
+					return err
+				}
+				releaser, ok := prCreator.(versioncontrol.ReleaseCreator)
+				if !ok {
+					return fmt.Errorf("%s does not support releases", dep.cfg.VersionControl.Provider)
+				}
+				release := versioncontrol.Release{
+					Tag:        tag,
+					Name:       tag,
+					Notes:      result.Markdown,
+					Draft:      draft,
+					Prerelease: prerelease,
+				}
+				if err := releaser.CheckRelease(release); err != nil {
+					return usageError("%v", err)
+				}
+
+				if confirm && !newPrompter().confirm(fmt.Sprintf("\nPublish release %s on %s?", tag, dep.cfg.VersionControl.Provider), false) {
+					return &ExitError{Code: ExitCanceled, Err: errors.New("release canceled")}
+				}
+
+				if !exists {
+					if err := createReleaseTag(tag, target, notes); err != nil {
+						return err
+					}
+					result.TagCreated = true
+				}
+				if err := pushTag(tag); err != nil {
+					return err
+				}
+
+				result.URL, err = releaser.CreateRelease(ctx, release)
+				if err != nil {
+					return err
+				}
+			}
+
+			if output == outputJSON {
+				return printJSON(result)
+			}
+			if result.URL != "" {
+				fmt.Printf("\nPublished release %s: %s\n", tag, result.URL)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("from", "", "Previous release, the latest tag before <tag> by default")
+	cmd.Flags().String("target", "HEAD", "Commit a new tag points at")
+	cmd.Flags().Bool("dry-run", false, "Only show the release notes")
+	cmd.Flags().Bool("draft", false, "Publish the release as a draft (GitHub only)")
+	cmd.Flags().Bool("prerelease", false, "Mark the release as a pre-release (GitHub only)")
+
+	return cmd
+}
+
+// previousTag returns the latest tag reachable from rev, not counting rev
+// itself when it is the release's existing tag
+func previousTag(rev string, exclusive bool) string {
+	if exclusive {
+		rev += "^"
+	}
+	out, err := exec.Command("git", "describe", "--tags", "--abbrev=0", rev).Output()
+	if err != nil {
+		// no earlier tag, the release covers the whole history
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// createReleaseTag creates an annotated tag at target carrying the summary
+func createReleaseTag(tag, target string, notes *sdk.ReleaseNotes) error {
+	message := "Release " + tag
+	if notes.Summary != "" {
+		message += "\n\n" + notes.Summary
+	}
+	if out, err := exec.Command("git", "tag", "--annotate", "--message", message, tag, target).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create tag %s: %v: %s", tag, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// pushTag pushes the tag to origin unless it is already there
+func pushTag(tag string) error {
+	out, err := exec.Command("git", "ls-remote", "--tags", "origin", "refs/tags/"+tag).Output()
+	if err != nil {
+		return gitError("failed to list the tags of origin", err)
+	}
+	if strings.TrimSpace(string(out)) != "" {
+		return nil
+	}
+
+	push := exec.Command("git", "push", "origin", "refs/tags/"+tag)
+	push.Stdout = os.Stderr
+	push.Stderr = os.Stderr
+	if err := push.Run(); err != nil {
+		return gitError(fmt.Sprintf("failed to push tag %s", tag), err)
+	}
+	return nil
+}
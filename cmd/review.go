@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"git-genius/config"
+	"git-genius/internal/sarif"
+	versioncontrol "git-genius/internal/version_control"
+	"git-genius/sdk"
+
+	"github.com/spf13/cobra"
+)
+
+// sarifInformationURI is where SARIF consumers link the tool to
+const sarifInformationURI = "https://github.com/alicansalor/git-genius"
+
+// reviewResult is the --output json result of review
+type reviewResult struct {
+	*sdk.CodeReview
+	PullRequest int                          `json:"pull_request,omitempty"`
+	Posted      *versioncontrol.ReviewResult `json:"posted,omitempty"`
+}
+
+// reviewSeverities orders the severities of findings, most severe first
+var reviewSeverities = []string{sdk.SeverityError, sdk.SeverityWarning, sdk.SeverityInfo}
+
+func reviewCmd(dep *SharedDependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "review [--staged | <base>..<head> | --post]",
+		Short: "Review changes with the LLM before pushing them",
+		Long: "Send the staged changes, or what <head> changes since it diverged from <base>, to the LLM " +
+			"for a code review. A single revision reviews the changes of HEAD since <base>. Every finding " +
+			"has a file, line, severity (error, warning or info), category and suggestion.\n\n" +
+			"The repository's review rules in review.rules are added to the prompt. The command exits " +
+			"with status 1 when a finding is at least as severe as --fail-on, review.fail_on or error by " +
+			"default. --output sarif writes a SARIF 2.1.0 log for code scanning tools.\n\n" +
+			"--post reviews the pull request of the current branch, or --pr, and posts the findings as " +
+			"inline comments on it. Findings git-genius posted on the pull request before are skipped, " +
+			"so re-running the review only adds new ones.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			staged, _ := cmd.Flags().GetBool("staged")
+			failOn, _ := cmd.Flags().GetString("fail-on")
+			post, _ := cmd.Flags().GetBool("post")
+			number, _ := cmd.Flags().GetInt("pr")
+			output, err := outputFormat(cmd, outputText, outputJSON, outputSARIF)
+			if err != nil {
+				return err
+			}
+
+			if failOn == "" {
+				failOn = dep.cfg.ReviewFailOn()
+			} else if !slices.Contains(config.SupportedReviewFailOn, failOn) {
+				return usageError("unknown --fail-on severity %q, expected one of: %s",
+					failOn, strings.Join(config.SupportedReviewFailOn, ", "))
+			}
+
+			var base, head string
+			var reviewer versioncontrol.Reviewer
+			var pr *versioncontrol.PullRequest
+			switch {
+			case cmd.Flags().Changed("pr") && !post:
+				return usageError("--pr only applies to --post")
+			case post && (staged || len(args) > 0):
+				return usageError("--post reviews the pull request's changes and takes no revisions")
+			case staged && len(args) > 0:
+				return usageError("--staged reviews the staged changes and takes no revisions")
+			case post:
+				if reviewer, pr, err = reviewPullRequest(cmd, dep.cfg, number); err != nil {
+					return err
+				}
+				base, head = pullRequestBase(pr.Base), "HEAD"
+			case len(args) > 0:
+				if base, head, err = revisionRange(args[0]); err != nil {
+					return err
+				}
+				if base == "" {
+					return usageError("missing <base> in %q", args[0])
+				}
+			}
+
+			review, err := dep.sdk.ReviewChanges(ctx, base, head)
+			if err != nil {
+				return err
+			}
+
+			result := reviewResult{CodeReview: review}
+			if post {
+				result.PullRequest = pr.Number
+				result.Posted, err = reviewer.CreateReview(ctx, pr.Number, pullRequestReview(review))
+				if err != nil {
+					return err
+				}
+			}
+
+			switch output {
+			case outputJSON:
+				err = printJSON(result)
+			case outputSARIF:
+				err = printJSON(reviewSARIF(review))
+			default:
+				printReview(review)
+			}
+			if err != nil {
+				return err
+			}
+			if result.Posted != nil {
+				printPosted(output, pr.Number, result.Posted)
+			}
+
+			if failing := failingFindings(review.Findings, failOn); failing > 0 {
+				return &ExitError{Code: ExitFailure, Err: fmt.Errorf("review found %d problem(s) of severity %s or higher", failing, failOn)}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("staged", false, "Review the staged changes, the default without revisions")
+	cmd.Flags().Bool("post", false, "Review the pull request of the current branch and post the findings on it")
+	cmd.Flags().Int("pr", 0, "Number of the pull request --post reviews")
+	cmd.Flags().String("fail-on", "", "Lowest severity that fails the review: error, warning, info or never (default review.fail_on or error)")
+
+	return cmd
+}
+
+// reviewPullRequest finds the pull request --post reviews and checks that
+// HEAD is its head, so that the lines of the findings match its diff
+func reviewPullRequest(cmd *cobra.Command, cfg *config.Config, number int) (versioncontrol.Reviewer, *versioncontrol.PullRequest, error) {
+	ctx := cmd.Context()
+	prCreator, err := cfg.NewPRCreator(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	reviewer, ok := prCreator.(versioncontrol.Reviewer)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s does not support pull request reviews", cfg.VersionControl.Provider)
+	}
+
+	var pr *versioncontrol.PullRequest
+	if number > 0 {
+		pr, err = reviewer.GetPullRequest(ctx, number)
+	} else {
+		var branch string
+		if branch, err = currentBranch(); err != nil {
+			return nil, nil, err
+		}
+		if pr, err = reviewer.PullRequestForBranch(ctx, branch); err != nil {
+			return nil, nil, fmt.Errorf("%v, pass the pull request number with --pr if the branch is pushed elsewhere", err)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	head, err := revParse("HEAD")
+	if err != nil {
+		return nil, nil, err
+	}
+	if head != pr.HeadSHA {
+		return nil, nil, fmt.Errorf("HEAD is not the head of pull request #%d (%s), push your changes or check out its branch first",
+			pr.Number, shortHash(pr.HeadSHA))
+	}
+	return reviewer, pr, nil
+}
+
+// pullRequestBase prefers the remote-tracking branch of the base, which is
+// what the pull request is compared with
+func pullRequestBase(branch string) string {
+	if _, err := revParse("origin/" + branch); err == nil {
+		return "origin/" + branch
+	}
+	return branch
+}
+
+// pullRequestReview turns the findings into inline comments keyed by file,
+// category and the commented code, which stay the same when later commits
+// shift the lines or the wording of a finding changes between runs
+func pullRequestReview(review *sdk.CodeReview) versioncontrol.Review {
+	body := "**git-genius review**"
+	if review.Summary != "" {
+		body += "\n\n" + review.Summary
+	}
+
+	pr := versioncontrol.Review{Body: body}
+	for _, finding := range review.Findings {
+		text := fmt.Sprintf("**%s** (%s): %s", finding.Severity, finding.Category, finding.Message)
+		if finding.Suggestion != "" {
+			text += "\n\nSuggestion: " + finding.Suggestion
+		}
+		key := sha256.Sum256([]byte(findingKey(review, finding)))
+		pr.Comments = append(pr.Comments, versioncontrol.ReviewComment{
+			Path: finding.File,
+			Line: finding.Line,
+			Body: text,
+			Key:  "finding-" + hex.EncodeToString(key[:6]),
+		})
+	}
+	return pr
+}
+
+// findingKey identifies a finding by its file, category and the text of its
+// line, or its normalized message for findings about the whole file
+func findingKey(review *sdk.CodeReview, finding sdk.CodeFinding) string {
+	anchor := strings.ToLower(strings.Join(strings.Fields(finding.Message), " "))
+	for _, file := range review.Files {
+		if file.Path() != finding.File || finding.Line == 0 {
+			continue
+		}
+		if location, ok := file.Locate(finding.Line); ok {
+			anchor = strings.Join(strings.Fields(location.Text), " ")
+		}
+		break
+	}
+	return finding.File + "\x00" + finding.Category + "\x00" + anchor
+}
+
+// printPosted reports what was posted, on stderr unless the output is text
+func printPosted(output string, number int, posted *versioncontrol.ReviewResult) {
+	out := os.Stdout
+	if output != outputText {
+		out = os.Stderr
+	}
+	if posted.Posted == 0 {
+		fmt.Fprintf(out, "\nNothing new to post on #%d, %d finding(s) were already there.\n", number, posted.Duplicates)
+		return
+	}
+	fmt.Fprintf(out, "\nPosted %d comment(s) on #%d: %s\n", posted.Posted, number, posted.URL)
+	if posted.Duplicates > 0 {
+		fmt.Fprintf(out, "Skipped %d finding(s) posted before.\n", posted.Duplicates)
+	}
+}
+
+// printReview writes the findings for the terminal, grouped by file
+func printReview(review *sdk.CodeReview) {
+	if review.Summary != "" {
+		fmt.Println(review.Summary)
+	}
+	if len(review.Findings) == 0 {
+		fmt.Println("\nNo findings.")
+		return
+	}
+
+	file := ""
+	counts := map[string]int{}
+	for _, finding := range review.Findings {
+		if finding.File != file {
+			file = finding.File
+			fmt.Printf("\n%s\n", file)
+		}
+		counts[finding.Severity]++
+
+		line := "-"
+		if finding.Line > 0 {
+			line = fmt.Sprint(finding.Line)
+		}
+		fmt.Printf("  %5s  %-8s %s [%s]\n", line, finding.Severity, finding.Message, finding.Category)
+		if finding.Suggestion != "" {
+			fmt.Printf("  %5s  %-8s suggestion: %s\n", "", "", finding.Suggestion)
+		}
+	}
+
+	var totals []string
+	for _, severity := range reviewSeverities {
+		if counts[severity] > 0 {
+			totals = append(totals, fmt.Sprintf("%d %s", counts[severity], severity))
+		}
+	}
+	fmt.Printf("\n%d finding(s): %s\n", len(review.Findings), strings.Join(totals, ", "))
+}
+
+// failingFindings counts the findings at least as severe as failOn
+func failingFindings(findings []sdk.CodeFinding, failOn string) int {
+	threshold := slices.Index(reviewSeverities, failOn)
+	if threshold < 0 {
+		return 0
+	}
+	failing := 0
+	for _, finding := range findings {
+		if i := slices.Index(reviewSeverities, finding.Severity); i >= 0 && i <= threshold {
+			failing++
+		}
+	}
+	return failing
+}
+
+// reviewSARIF converts the review to a SARIF log with one rule per category
+func reviewSARIF(review *sdk.CodeReview) *sarif.Log {
+	driver := sarif.Driver{Name: "git-genius", InformationURI: sarifInformationURI}
+	var results []sarif.Result
+	for _, finding := range review.Findings {
+		ruleID := finding.Category
+		if ruleID == "" {
+			ruleID = "review"
+		}
+		if !slices.ContainsFunc(driver.Rules, func(rule sarif.Rule) bool { return rule.ID == ruleID }) {
+			driver.Rules = append(driver.Rules, sarif.Rule{ID: ruleID, ShortDescription: &sarif.Message{Text: "Code review: " + ruleID}})
+		}
+
+		text := finding.Message
+		if finding.Suggestion != "" {
+			text += "\n\nSuggestion: " + finding.Suggestion
+		}
+		results = append(results, sarif.Result{
+			RuleID:    ruleID,
+			Level:     sarifLevel(finding.Severity),
+			Message:   sarif.Message{Text: text},
+			Locations: []sarif.Location{sarif.FileLocation(finding.File, finding.Line)},
+		})
+	}
+	return sarif.NewLog(driver, results)
+}
+
+func sarifLevel(severity string) string {
+	switch severity {
+	case sdk.SeverityError:
+		return sarif.LevelError
+	case sdk.SeverityWarning:
+		return sarif.LevelWarning
+	default:
+		return sarif.LevelNote
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"git-genius/internal/patch"
+	"git-genius/sdk"
+)
+
+func TestReviewSARIF(t *testing.T) {
+	tests := []struct {
+		name     string
+		findings []sdk.CodeFinding
+		want     string
+	}{
+		{
+			name: "no findings",
+			want: `{"$schema":"https://json.schemastore.org/sarif-2.1.0.json","version":"2.1.0","runs":[` +
+				`{"tool":{"driver":{"name":"git-genius","informationUri":"https://github.com/alicansalor/git-genius"}},"results":[]}]}`,
+		},
+		{
+			name: "rule per category",
+			findings: []sdk.CodeFinding{
+				{File: "main.go", Line: 3, Severity: sdk.SeverityError, Category: "bug", Message: "nil map", Suggestion: "make the map"},
+				{File: "main.go", Line: 9, Severity: sdk.SeverityWarning, Category: "bug", Message: "shadowed err"},
+				{File: "README.md", Severity: sdk.SeverityInfo, Message: "typo"},
+			},
+			want: `{"$schema":"https://json.schemastore.org/sarif-2.1.0.json","version":"2.1.0","runs":[` +
+				`{"tool":{"driver":{"name":"git-genius","informationUri":"https://github.com/alicansalor/git-genius","rules":[` +
+				`{"id":"bug","shortDescription":{"text":"Code review: bug"}},{"id":"review","shortDescription":{"text":"Code review: review"}}]}},` +
+				`"results":[` +
+				`{"ruleId":"bug","level":"error","message":{"text":"nil map\n\nSuggestion: make the map"},` +
+				`"locations":[{"physicalLocation":{"artifactLocation":{"uri":"main.go"},"region":{"startLine":3}}}]},` +
+				`{"ruleId":"bug","level":"warning","message":{"text":"shadowed err"},` +
+				`"locations":[{"physicalLocation":{"artifactLocation":{"uri":"main.go"},"region":{"startLine":9}}}]},` +
+				`{"ruleId":"review","level":"note","message":{"text":"typo"},` +
+				`"locations":[{"physicalLocation":{"artifactLocation":{"uri":"README.md"}}}]}]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(reviewSARIF(&sdk.CodeReview{Findings: tt.findings}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("reviewSARIF() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailingFindings(t *testing.T) {
+	findings := []sdk.CodeFinding{
+		{Severity: sdk.SeverityError},
+		{Severity: sdk.SeverityWarning},
+		{Severity: sdk.SeverityWarning},
+		{Severity: sdk.SeverityInfo},
+	}
+
+	tests := []struct {
+		failOn string
+		want   int
+	}{
+		{failOn: "error", want: 1},
+		{failOn: "warning", want: 3},
+		{failOn: "info", want: 4},
+		{failOn: "never", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.failOn, func(t *testing.T) {
+			if got := failingFindings(findings, tt.failOn); got != tt.want {
+				t.Errorf("failingFindings(%q) = %d, want %d", tt.failOn, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPullRequestReviewKeys(t *testing.T) {
+	// the same added line, once at line 2 and once shifted to line 4 by a
+	// later commit
+	before := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n" +
+		"@@ -1,2 +1,3 @@\n package main\n+var debug = true\n \n"
+	after := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n" +
+		"@@ -1,2 +1,5 @@\n package main\n+\n+// debug logs\n+var  debug = true\n \n"
+
+	finding := sdk.CodeFinding{File: "main.go", Line: 2, Severity: sdk.SeverityWarning, Category: "bug", Message: "debug left on"}
+	shifted := finding
+	shifted.Line = 4
+	shifted.Message = "Debugging is still enabled"
+	otherCategory := finding
+	otherCategory.Category = "style"
+	wholeFile := sdk.CodeFinding{File: "main.go", Severity: sdk.SeverityInfo, Category: "docs", Message: "Add a  package comment"}
+	wholeFileCase := wholeFile
+	wholeFileCase.Message = "add a package comment"
+	wholeFileReworded := wholeFile
+	wholeFileReworded.Message = "Document the package"
+
+	tests := []struct {
+		name     string
+		a, b     sdk.CodeFinding
+		diffB    string
+		wantSame bool
+	}{
+		{name: "shifted and reworded", a: finding, b: shifted, diffB: after, wantSame: true},
+		{name: "other category", a: finding, b: otherCategory, diffB: before},
+		{name: "whole file, spacing and case", a: wholeFile, b: wholeFileCase, diffB: before, wantSame: true},
+		{name: "whole file, reworded", a: wholeFile, b: wholeFileReworded, diffB: before},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := reviewKey(t, before, tt.a)
+			b := reviewKey(t, tt.diffB, tt.b)
+			if (a == b) != tt.wantSame {
+				t.Errorf("keys %s and %s, want them the same: %v", a, b, tt.wantSame)
+			}
+		})
+	}
+}
+
+// reviewKey returns the key of the comment posted for finding on diff
+func reviewKey(t *testing.T, diff string, finding sdk.CodeFinding) string {
+	t.Helper()
+	files, err := patch.Parse(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	review := pullRequestReview(&sdk.CodeReview{Findings: []sdk.CodeFinding{finding}, Files: files})
+	if len(review.Comments) != 1 || review.Comments[0].Key == "" {
+		t.Fatalf("pullRequestReview() comments = %+v, want one keyed comment", review.Comments)
+	}
+	return review.Comments[0].Key
+}
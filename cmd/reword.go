@@ -0,0 +1,412 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	context_provider "git-genius/internal/context_provider"
+	"git-genius/internal/shell"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// backupRefPrefix is where history rewriting commands keep the original tip
+const backupRefPrefix = "refs/git-genius/backup/"
+
+// rewordResult is the outcome for one commit of the range
+type rewordResult struct {
+	Commit     string `json:"commit"`
+	OldMessage string `json:"old_message"`
+	NewMessage string `json:"new_message,omitempty"`
+	Rewritten  bool   `json:"rewritten"`
+	Error      string `json:"error,omitempty"`
+}
+
+// rewordReport is the --output json result of reword
+type rewordReport struct {
+	Commits   []*rewordResult `json:"commits"`
+	BackupRef string          `json:"backup_ref,omitempty"`
+}
+
+func rewordCmd(dep *SharedDependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reword <rev-range>",
+		Short: "Regenerate the messages of existing commits on the current branch",
+		Long: "Regenerate the messages of the commits in <rev-range> from each commit's own diff, " +
+			"review them side by side and rewrite the branch. A single revision such as HEAD~3 means " +
+			"the commits after it, and the range must end at HEAD.\n\n" +
+			"The original tip is kept under " + backupRefPrefix + ". Commits that are already on a " +
+			"protected remote branch (rewrite.protected_branches) are refused unless --force is given.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			force, _ := cmd.Flags().GetBool("force")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			yes, _ := cmd.Flags().GetBool("yes")
+			output, err := outputFormat(cmd, outputText, outputJSON)
+			if err != nil {
+				return err
+			}
+
+			if !yes && !dryRun && output != outputText {
+				return usageError("rewriting history needs review, use --yes to rewrite without review or --dry-run to only show the new messages")
+			}
+			review := !yes && !dryRun
+			if review && !interactive(cmd) {
+				return usageError("stdin is not a terminal, use --yes to rewrite without review or --dry-run to only show the new messages")
+			}
+
+			commits, err := rewordCommits(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if !dryRun {
+				if err := checkRewritable(commits, dep.cfg.ProtectedBranches(), force); err != nil {
+					return err
+				}
+			}
+
+			var results []*rewordResult
+			for i, commit := range commits {
+				fmt.Fprintf(os.Stderr, "Generating message for %s (%d/%d)...\n", commit.ShortHash(), i+1, len(commits))
+				result := &rewordResult{Commit: commit.Hash, OldMessage: commit.Message}
+				message, err := dep.sdk.GenerateCommitMessageFor(ctx, commit)
+				if err != nil {
+					result.Error = err.Error()
+				} else if message != commit.Message {
+					result.NewMessage = message
+				}
+				results = append(results, result)
+			}
+
+			if output == outputText {
+				printSideBySide(results)
+			}
+
+			if review {
+				if !selectRewords(newPrompter(), results) {
+					return &ExitError{Code: ExitCanceled, Err: errors.New("reword canceled")}
+				}
+			}
+
+			report := rewordReport{Commits: results}
+			if !dryRun && hasRewords(results) {
+				report.BackupRef, err = rewordHistory(commits, results)
+				if err != nil {
+					return err
+				}
+			}
+
+			if output == outputJSON {
+				return printJSON(report)
+			}
+			if report.BackupRef != "" {
+				rewritten := 0
+				for _, result := range results {
+					if result.Rewritten {
+						rewritten++
+					}
+				}
+				fmt.Printf("Rewrote %d commit(s). The previous history is kept in %s,\nrestore it with: git reset --hard %s\n",
+					rewritten, report.BackupRef, report.BackupRef)
+			} else if !hasRewords(results) {
+				fmt.Println("Nothing to reword.")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("force", false, "Rewrite commits that are already on a protected remote branch")
+	cmd.Flags().Bool("dry-run", false, "Only show the new messages")
+
+	return cmd
+}
+
+// rewordCommits lists the commits of the range, which must be linear and end at HEAD
+func rewordCommits(ctx context.Context, revRange string) ([]context_provider.Commit, error) {
+	if strings.Contains(revRange, "...") {
+		return nil, usageError("symmetric ranges are not supported, use <base>..HEAD")
+	}
+	base, tip, found := strings.Cut(revRange, "..")
+	if !found {
+		tip = "HEAD"
+	}
+	if tip == "" {
+		tip = "HEAD"
+	}
+
+	head, err := revParse("HEAD")
+	if err != nil {
+		return nil, err
+	}
+	tipHash, err := revParse(tip)
+	if err != nil {
+		return nil, err
+	}
+	if tipHash != head {
+		return nil, usageError("the range must end at HEAD, check out %s first", tip)
+	}
+
+	rangeArgs := []string{tip}
+	if base != "" {
+		rangeArgs = []string{base + ".." + tip}
+	}
+	commits, err := context_provider.Log(ctx, rangeArgs...)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commits in %s", revRange)
+	}
+	for _, commit := range commits {
+		if commit.IsMerge() {
+			return nil, fmt.Errorf("%s is a merge commit, reword only rewrites linear history", commit.ShortHash())
+		}
+	}
+	return commits, nil
+}
+
+// checkRewritable refuses to rewrite with local changes or pushed protected commits
+func checkRewritable(commits []context_provider.Commit, protected []string, force bool) error {
+	if err := checkCleanTree(); err != nil {
+		return err
+	}
+
+	if force {
+		return nil
+	}
+
+	// every later commit descends from the oldest, so it is enough to check that one
+	oldest := commits[0]
+	out, err := exec.Command("git", "for-each-ref", "--contains", oldest.Hash, "--format=%(refname:short)", "refs/remotes").Output()
+	if err != nil {
+		return gitError("failed to read remote branches", err)
+	}
+	for _, ref := range strings.Fields(string(out)) {
+		_, branch, ok := strings.Cut(ref, "/")
+		if ok && slices.Contains(protected, branch) {
+			return fmt.Errorf("%s is already on the protected branch %s, rewriting it rewrites published history (use --force to do it anyway)",
+				oldest.ShortHash(), ref)
+		}
+	}
+	return nil
+}
+
+// checkCleanTree refuses to move branches with local changes or during a rebase
+func checkCleanTree() error {
+	out, err := exec.Command("git", "status", "--porcelain", "--untracked-files=no").Output()
+	if err != nil {
+		return gitError("failed to read the working tree status", err)
+	}
+	if strings.TrimSpace(string(out)) != "" {
+		return fmt.Errorf("the working tree has uncommitted changes, commit or stash them first")
+	}
+	if rebaseInProgress() {
+		return fmt.Errorf("a rebase is in progress, finish or abort it first")
+	}
+	return nil
+}
+
+// selectRewords asks which new messages to use, false cancels
+func selectRewords(p *prompter, results []*rewordResult) bool {
+	if !hasRewords(results) {
+		return true
+	}
+
+	switch p.choose("Rewrite the commits? [Y]es, [S]elect each, [N]o", []string{"Y", "S", "N"}, "N") {
+	case "Y":
+		return true
+	case "S":
+	default:
+		return false
+	}
+
+	for _, result := range results {
+		if result.NewMessage == "" {
+			continue
+		}
+		fmt.Printf("\n%s\n%s\n", shortHash(result.Commit), result.NewMessage)
+		switch p.choose("Use the new message? [Y]es, [N]o, [E]dit", []string{"Y", "N", "E"}, "Y") {
+		case "N":
+			result.NewMessage = ""
+		case "E":
+			edited, err := editMessage(result.NewMessage)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v, keeping the generated message\n", err)
+				continue
+			}
+			result.NewMessage = edited
+		}
+	}
+	return true
+}
+
+func hasRewords(results []*rewordResult) bool {
+	for _, result := range results {
+		if result.NewMessage != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// rewordHistory saves a backup ref and replays the commits with their new
+// messages through a rebase driven by a prepared todo list
+func rewordHistory(commits []context_provider.Commit, results []*rewordResult) (string, error) {
+	head, err := revParse("HEAD")
+	if err != nil {
+		return "", err
+	}
+	backupRef, err := createBackupRef(head)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "git-genius-reword-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var todo strings.Builder
+	for i, commit := range commits {
+		fmt.Fprintf(&todo, "pick %s %s\n", commit.Hash, commit.Subject)
+		if results[i].NewMessage == "" {
+			continue
+		}
+		messageFile := filepath.Join(dir, commit.Hash+".msg")
+		if err := os.WriteFile(messageFile, []byte(results[i].NewMessage+"\n"), 0o600); err != nil {
+			return "", fmt.Errorf("failed to write commit message: %w", err)
+		}
+		fmt.Fprintf(&todo, "exec git commit --amend --only --allow-empty --no-verify --cleanup=whitespace --file %s\n", shell.Quote(messageFile))
+	}
+	todoFile := filepath.Join(dir, "todo")
+	if err := os.WriteFile(todoFile, []byte(todo.String()), 0o600); err != nil {
+		return "", fmt.Errorf("failed to write rebase todo list: %w", err)
+	}
+
+	args := []string{"rebase", "--interactive", "--no-autosquash"}
+	if parents := commits[0].Parents; len(parents) > 0 {
+		args = append(args, parents[0])
+	} else {
+		args = append(args, "--root")
+	}
+
+	rebase := exec.Command("git", args...)
+	rebase.Env = append(os.Environ(), "GIT_SEQUENCE_EDITOR=cp "+shell.Quote(todoFile), "GIT_EDITOR=true")
+	rebase.Stdout = os.Stderr
+	rebase.Stderr = os.Stderr
+	if err := rebase.Run(); err != nil {
+		// the abort restores the branch, the backup is not needed
+		if exec.Command("git", "rebase", "--abort").Run() == nil {
+			exec.Command("git", "update-ref", "-d", backupRef).Run()
+			return "", gitError("rebase failed and was aborted, the branch is unchanged", err)
+		}
+		return "", gitError(fmt.Sprintf("rebase failed, the original history is kept in %s", backupRef), err)
+	}
+
+	for _, result := range results {
+		result.Rewritten = result.NewMessage != ""
+	}
+	return backupRef, nil
+}
+
+// createBackupRef points a new ref under refs/git-genius/backup at hash,
+// never overwriting an earlier backup made in the same second
+func createBackupRef(hash string) (string, error) {
+	branch := "detached"
+	if out, err := exec.Command("git", "symbolic-ref", "--short", "-q", "HEAD").Output(); err == nil {
+		branch = strings.TrimSpace(string(out))
+	}
+
+	base := backupRefPrefix + branch + "/" + time.Now().Format("20060102-150405")
+	ref := base
+	for n := 2; ; n++ {
+		// the empty old value makes update-ref fail when the ref exists
+		out, err := exec.Command("git", "update-ref", ref, hash, "").CombinedOutput()
+		if err == nil {
+			return ref, nil
+		}
+		if n > 100 || !refExists(ref) {
+			return "", fmt.Errorf("failed to create backup ref %s: %v: %s", ref, err, strings.TrimSpace(string(out)))
+		}
+		ref = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+func refExists(ref string) bool {
+	return exec.Command("git", "rev-parse", "--verify", "--quiet", ref).Run() == nil
+}
+
+// printSideBySide shows the old and new message of every commit in two columns
+func printSideBySide(results []*rewordResult) {
+	width := 120
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 40 {
+		width = w
+	}
+	column := (width - 3) / 2
+
+	for _, result := range results {
+		fmt.Printf("\n%s\n", shortHash(result.Commit))
+		if result.Error != "" {
+			fmt.Printf("  kept, %s\n", result.Error)
+			continue
+		}
+		if result.NewMessage == "" {
+			fmt.Println("  unchanged")
+			continue
+		}
+
+		left := wrapColumn("current", result.OldMessage, column)
+		right := wrapColumn("new", result.NewMessage, column)
+		for i := 0; i < max(len(left), len(right)); i++ {
+			var l, r string
+			if i < len(left) {
+				l = left[i]
+			}
+			if i < len(right) {
+				r = right[i]
+			}
+			fmt.Printf("%s%s | %s\n", l, strings.Repeat(" ", column-utf8.RuneCountInString(l)), r)
+		}
+	}
+	fmt.Println()
+}
+
+// wrapColumn hard-wraps a message to width under a title and a rule
+func wrapColumn(title, message string, width int) []string {
+	lines := []string{title, strings.Repeat("-", width)}
+	for _, line := range strings.Split(message, "\n") {
+		runes := []rune(line)
+		for len(runes) > width {
+			lines = append(lines, string(runes[:width]))
+			runes = runes[width:]
+		}
+		lines = append(lines, string(runes))
+	}
+	return lines
+}
+
+func revParse(rev string) (string, error) {
+	out, err := exec.Command("git", "rev-parse", "--verify", "--quiet", rev+"^{commit}").Output()
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %s", rev)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCreateBackupRef(t *testing.T) {
+	tests := []struct {
+		name string
+		// checkout is what HEAD is switched to before the backups
+		checkout []string
+		want     string
+	}{
+		{name: "branch", checkout: []string{"switch", "--quiet", "-c", "feat/login"}, want: "feat/login"},
+		{name: "detached", checkout: []string{"switch", "--quiet", "--detach"}, want: "detached"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testRepo(t)
+			git(t, dir, "commit", "--quiet", "--allow-empty", "-m", "feat: add login")
+			git(t, dir, tt.checkout...)
+			pattern := regexp.MustCompile(`^` + regexp.QuoteMeta(backupRefPrefix+tt.want+"/") + `\d{8}-\d{6}(-\d+)?$`)
+
+			// backups made within the same second get numbered
+			refs := map[string]string{}
+			for i := 0; i < 3; i++ {
+				git(t, dir, "commit", "--quiet", "--allow-empty", "-m", "fix: typo")
+				hash := strings.TrimSpace(git(t, dir, "rev-parse", "HEAD"))
+				ref, err := createBackupRef(hash)
+				if err != nil {
+					t.Fatalf("createBackupRef() error = %v", err)
+				}
+				if !pattern.MatchString(ref) {
+					t.Errorf("createBackupRef() = %s, want it to match %s", ref, pattern)
+				}
+				if _, ok := refs[ref]; ok {
+					t.Errorf("createBackupRef() = %s again, want a new ref", ref)
+				}
+				refs[ref] = hash
+			}
+
+			// no backup was overwritten
+			for ref, hash := range refs {
+				if got := strings.TrimSpace(git(t, dir, "rev-parse", ref)); got != hash {
+					t.Errorf("%s = %s, want %s", ref, got, hash)
+				}
+			}
+		})
+	}
+}
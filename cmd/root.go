@@ -2,11 +2,13 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"git-genius/config"
 	"git-genius/sdk"
 	"os"
 	"os/exec"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -18,6 +20,9 @@ var RootCmd = &cobra.Command{
 	Long:               "Git Genius enhances your Git workflow with features like automated commit messages and PR generation using an LLM.",
 	Args:               cobra.ArbitraryArgs, // Allow any arguments
 	DisableFlagParsing: true,
+	// errors are printed by main, usage only on request
+	SilenceUsage:  true,
+	SilenceErrors: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
 			return cmd.Help() // Show help if no arguments are provided.
@@ -26,44 +31,86 @@ var RootCmd = &cobra.Command{
 		return runGitCommand(args)
 	},
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		ctx := context.Background()
-		cmd.SetContext(ctx)
+		cmd.SetContext(context.Background())
 
-		if cmd.HasSubCommands() && len(args) == 0 {
+		if cmd == cmd.Root() || cmd.Annotations[skipConfigAnnotation] == "true" {
 			return nil
 		}
 
-		// if the command is not recognised then nothing to initialise
-		if len(args) > 0 {
-			subCmd, _, _ := cmd.Root().Find(args)
-			if subCmd == nil || subCmd == cmd.Root() {
-				return nil
-			}
+		if cmd.HasSubCommands() && len(args) == 0 {
+			return nil
 		}
 
-		// Get the config path from the flag
-		configPath, _ := cmd.Flags().GetString("config")
-		// Initialize shared dependencies
-		cfg, err := config.LoadConfig(configPath)
-		if err != nil {
-			return fmt.Errorf("failed to load config: %v\n", err)
-		}
+		return loadDependencies(cmd, &sharedDeps, cmd.Annotations[skipSDKAnnotation] != "true")
+	},
+}
 
-		// Add issue ID to the config
-		issueID, _ := cmd.Flags().GetString("issue")
-		cfg.IssueID = issueID
+// loadDependencies loads the config and, with withSDK, validates it and
+// creates the SDK
+func loadDependencies(cmd *cobra.Command, dep *SharedDependencies, withSDK bool) error {
+	if err := loadConfigLayers(cmd, dep); err != nil {
+		return err
+	}
+	return resolveDependencies(cmd.Context(), dep, withSDK)
+}
 
-		sharedDeps.sdk, err = sdk.NewGitGeniusSDK(ctx, cfg)
-		if err != nil {
-			return fmt.Errorf("failed to create SDK: %v", err)
-		}
+// loadConfigLayers loads the config without resolving the credentials
+func loadConfigLayers(cmd *cobra.Command, dep *SharedDependencies) error {
+	// Get the config path and overrides from the flags
+	configPath, _ := cmd.Flags().GetString("config")
+	overrides, err := parseOverrides(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Initialize shared dependencies
+	cfg, err := config.LoadConfigLayers(configPath, overrides)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %v", err)
+	}
+
+	// Add issue ID to the config
+	issueID, _ := cmd.Flags().GetString("issue")
+	cfg.IssueID = issueID
+	dep.cfg = cfg
+
+	return nil
+}
+
+// resolveDependencies resolves the credentials of the loaded config within
+// ctx and, with withSDK, validates it and creates the SDK
+func resolveDependencies(ctx context.Context, dep *SharedDependencies, withSDK bool) error {
+	if err := dep.cfg.ResolveSecrets(ctx); err != nil {
+		return fmt.Errorf("failed to load config: %v", err)
+	}
 
+	if !withSDK {
 		return nil
-	},
+	}
+
+	if problems := dep.cfg.Validate(); len(problems) > 0 {
+		return fmt.Errorf("invalid config:\n%v", problems)
+	}
+
+	var err error
+	dep.sdk, err = sdk.NewGitGeniusSDK(ctx, dep.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create SDK: %v", err)
+	}
+
+	return nil
 }
 
+const (
+	// skipConfigAnnotation marks commands that load (or create) the config themselves
+	skipConfigAnnotation = "git-genius/skip-config"
+	// skipSDKAnnotation marks commands that only need the loaded config
+	skipSDKAnnotation = "git-genius/skip-sdk"
+)
+
 // SharedDependencies holds dependencies shared between subcommands
 type SharedDependencies struct {
+	cfg *config.Config
 	sdk sdk.GitGenius
 }
 
@@ -77,14 +124,50 @@ func Execute() error {
 func init() {
 	// Define persistent flags for the root command
 	RootCmd.PersistentFlags().String("config", "", "Path to the configuration file")
+	RootCmd.PersistentFlags().StringArray("set", nil, "Override a configuration value (key=value), may be repeated")
+	RootCmd.PersistentFlags().BoolP("yes", "y", false, "Do not ask questions, accept the generated result")
+	RootCmd.PersistentFlags().StringP("output", "o", outputText, "Output format (text, json, raw, sarif), not every command supports every format")
+	RootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &ExitError{Code: ExitUsage, Err: err}
+	})
 	// Add subcommands and pass shared dependencies
 	RootCmd.AddCommand(prCmd(&sharedDeps))
 	RootCmd.AddCommand(commitCmd(&sharedDeps))
+	RootCmd.AddCommand(configCmd(&sharedDeps))
+	RootCmd.AddCommand(initCmd())
+	RootCmd.AddCommand(doctorCmd())
+	RootCmd.AddCommand(promptCmd(&sharedDeps))
+	RootCmd.AddCommand(hookCmd(&sharedDeps))
+	RootCmd.AddCommand(rewordCmd(&sharedDeps))
+	RootCmd.AddCommand(squashMessageCmd(&sharedDeps))
+	RootCmd.AddCommand(splitCmd(&sharedDeps))
+	RootCmd.AddCommand(changelogCmd(&sharedDeps))
+	RootCmd.AddCommand(releaseCmd(&sharedDeps))
+	RootCmd.AddCommand(reviewCmd(&sharedDeps))
+	RootCmd.AddCommand(explainCmd(&sharedDeps))
+	RootCmd.AddCommand(askCmd(&sharedDeps))
+	RootCmd.AddCommand(indexCmd(&sharedDeps))
+	RootCmd.AddCommand(branchCmd(&sharedDeps))
+}
+
+// parseOverrides reads the --set key=value flags
+func parseOverrides(cmd *cobra.Command) (map[string]string, error) {
+	values, _ := cmd.Flags().GetStringArray("set")
+
+	overrides := map[string]string{}
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, usageError("invalid --set value %q, expected key=value", value)
+		}
+		overrides[key] = val
+	}
+	return overrides, nil
 }
 
 // runGitCommand forwards unrecognized commands to the Git CLI
 func runGitCommand(args []string) error {
-	fmt.Printf("Running fallback Git command with args: %v\n", args)
+	fmt.Fprintf(os.Stderr, "Running fallback Git command with args: %v\n", args)
 
 	cmd := exec.Command("git", args...)
 	cmd.Stdout = os.Stdout
@@ -92,6 +175,11 @@ func runGitCommand(args []string) error {
 	cmd.Stdin = os.Stdin
 
 	if err := cmd.Run(); err != nil {
+		// git has already reported the problem, only pass its status on
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &ExitError{Code: exitErr.ExitCode()}
+		}
 		return fmt.Errorf("failed to execute git command: %v", err)
 	}
 	return nil
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"git-genius/sdk"
+
+	"github.com/spf13/cobra"
+)
+
+// splitCommitResult is one commit of the --output json result of split
+type splitCommitResult struct {
+	Message string   `json:"message"`
+	Hunks   []int    `json:"hunks"`
+	Files   []string `json:"files"`
+	Commit  string   `json:"commit,omitempty"`
+}
+
+// splitResult is the --output json result of split
+type splitResult struct {
+	Commits   []*splitCommitResult `json:"commits"`
+	Committed bool                 `json:"committed"`
+}
+
+func splitCmd(dep *SharedDependencies) *cobra.Command {
+	var commitOpts gitCommitOptions
+
+	cmd := &cobra.Command{
+		Use:   "split",
+		Short: "Split the staged changes into atomic commits",
+		Long: "Propose a grouping of the staged hunks into logical commits with a message each, " +
+			"review it and commit the groups one by one. Only the index is touched, the working tree " +
+			"stays as it is. If staging or committing a group fails, the commits made so far are undone " +
+			"and the index is restored to what was staged.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			yes, _ := cmd.Flags().GetBool("yes")
+			output, err := outputFormat(cmd, outputText, outputJSON)
+			if err != nil {
+				return err
+			}
+
+			review := !yes && !dryRun && output == outputText
+			if review && !interactive(cmd) {
+				return usageError("stdin is not a terminal, use --yes to commit the proposed split or --dry-run to only show it")
+			}
+			if rebaseInProgress() {
+				return fmt.Errorf("a rebase is in progress, finish or abort it first")
+			}
+
+			plan, err := dep.sdk.ProposeSplit(ctx)
+			if err != nil {
+				return err
+			}
+
+			if output == outputText {
+				printSplitPlan(plan)
+			}
+			if review && !reviewSplitPlan(newPrompter(), plan) {
+				return &ExitError{Code: ExitCanceled, Err: errors.New("split canceled")}
+			}
+
+			var result splitResult
+			for _, commit := range plan.Commits {
+				commitResult := &splitCommitResult{Message: commit.Message}
+				for _, hunk := range commit.Hunks {
+					commitResult.Hunks = append(commitResult.Hunks, hunk.ID)
+					if path := hunk.File.Path(); len(commitResult.Files) == 0 || commitResult.Files[len(commitResult.Files)-1] != path {
+						commitResult.Files = append(commitResult.Files, path)
+					}
+				}
+				result.Commits = append(result.Commits, commitResult)
+			}
+
+			if !dryRun {
+				// keep stdout parseable, git reports on stderr instead
+				gitOutput := io.Writer(os.Stdout)
+				if output != outputText {
+					gitOutput = os.Stderr
+				}
+
+				hashes, err := applySplit(plan, commitOpts, gitOutput)
+				if err != nil {
+					return err
+				}
+				for i, hash := range hashes {
+					result.Commits[i].Commit = hash
+				}
+				result.Committed = true
+			}
+
+			if output == outputJSON {
+				return printJSON(result)
+			}
+			if result.Committed {
+				fmt.Printf("Split the staged changes into %d commit(s).\n", len(result.Commits))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "Only show the proposed commits")
+	cmd.Flags().BoolVarP(&commitOpts.noVerify, "no-verify", "n", false, "Bypass the pre-commit and commit-msg hooks")
+	cmd.Flags().BoolVarP(&commitOpts.signoff, "signoff", "s", false, "Add a Signed-off-by trailer")
+
+	return cmd
+}
+
+// printSplitPlan shows every proposed commit with its hunks
+func printSplitPlan(plan *sdk.SplitPlan) {
+	for i, commit := range plan.Commits {
+		fmt.Printf("\nCommit %d of %d\n", i+1, len(plan.Commits))
+		fmt.Println("-------------------------")
+		fmt.Println(commit.Message)
+		fmt.Println("-------------------------")
+		for _, hunk := range commit.Hunks {
+			fmt.Printf("  %s\n", hunk)
+		}
+	}
+	fmt.Println()
+}
+
+// reviewSplitPlan lets the user edit the messages until the plan is
+// accepted, false cancels
+func reviewSplitPlan(p *prompter, plan *sdk.SplitPlan) bool {
+	for {
+		switch p.choose("Create these commits? [Y]es, [E]dit a message, [N]o", []string{"Y", "E", "N"}, "N") {
+		case "Y":
+			return true
+		case "E":
+			commit := plan.Commits[0]
+			if len(plan.Commits) > 1 {
+				options := make([]string, len(plan.Commits))
+				for i := range plan.Commits {
+					options[i] = strconv.Itoa(i + 1)
+				}
+				n, _ := strconv.Atoi(p.choose("Which commit", options, "1"))
+				commit = plan.Commits[n-1]
+			}
+
+			edited, err := editMessage(commit.Message)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				continue
+			}
+			commit.Message = edited
+			printSplitPlan(plan)
+		default:
+			return false
+		}
+	}
+}
+
+// applySplit stages and commits the groups one by one on top of HEAD and
+// returns the new commit hashes. On failure HEAD and the index are put back.
+func applySplit(plan *sdk.SplitPlan, opts gitCommitOptions, output io.Writer) ([]string, error) {
+	out, err := exec.Command("git", "write-tree").Output()
+	if err != nil {
+		return nil, fmt.Errorf("the index has unresolved conflicts, resolve them first")
+	}
+	staged := strings.TrimSpace(string(out))
+	head, headErr := revParse("HEAD")
+
+	restore := func() {
+		if headErr == nil {
+			exec.Command("git", "reset", "--soft", head).Run()
+		} else {
+			exec.Command("git", "update-ref", "-d", "HEAD").Run()
+		}
+		exec.Command("git", "read-tree", staged).Run()
+		fmt.Fprintln(os.Stderr, "Split rolled back, the staged changes are as they were.")
+	}
+
+	// start from HEAD and stage one group at a time
+	args := []string{"read-tree", "HEAD"}
+	if headErr != nil {
+		args = []string{"read-tree", "--empty"}
+	}
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		exec.Command("git", "read-tree", staged).Run()
+		return nil, fmt.Errorf("failed to reset the index: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	var hashes []string
+	for i, commit := range plan.Commits {
+		apply := exec.Command("git", "apply", "--cached", "--whitespace=nowarn")
+		apply.Stdin = strings.NewReader(commit.Patch())
+		if out, err := apply.CombinedOutput(); err != nil {
+			restore()
+			return nil, fmt.Errorf("failed to stage commit %d: %v: %s", i+1, err, strings.TrimSpace(string(out)))
+		}
+
+		hash, err := performGitCommit(commit.Message, false, opts, output)
+		if err != nil {
+			restore()
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	// together the commits must record exactly what was staged
+	out, err = exec.Command("git", "write-tree").Output()
+	if err != nil || strings.TrimSpace(string(out)) != staged {
+		restore()
+		return nil, fmt.Errorf("the commits do not add up to the staged changes")
+	}
+
+	return hashes, nil
+}
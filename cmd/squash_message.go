@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"git-genius/config"
+	"git-genius/sdk"
+
+	"github.com/spf13/cobra"
+)
+
+// ways squash-message --apply squashes the branch
+const (
+	squashReset = "reset"
+	squashMerge = "merge"
+)
+
+// squashResult is the --output json result of squash-message
+type squashResult struct {
+	Message   string   `json:"message"`
+	Base      string   `json:"base"`
+	MergeBase string   `json:"merge_base"`
+	Commits   []string `json:"commits"`
+	CoAuthors []string `json:"co_authors,omitempty"`
+	Applied   bool     `json:"applied"`
+	Commit    string   `json:"commit,omitempty"`
+	BackupRef string   `json:"backup_ref,omitempty"`
+}
+
+func squashMessageCmd(dep *SharedDependencies) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "squash-message [base]",
+		Short: "Generate one commit message for squashing the current branch",
+		Long: "Generate a single commit message for the commits of the current branch since it forked " +
+			"from base, written from their cumulative diff. The authors of the commits and their " +
+			"co-authors are kept as Co-authored-by trailers. base defaults to pull_request.base_branch " +
+			"or the remote's default branch.\n\n" +
+			"--apply reset squashes the branch in place with a soft reset to the merge base, keeping the " +
+			"original tip under " + backupRefPrefix + ". --apply merge commits the squashed branch on " +
+			"top of base with git merge --squash and leaves the branch as it is.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			apply, _ := cmd.Flags().GetString("apply")
+			force, _ := cmd.Flags().GetBool("force")
+			yes, _ := cmd.Flags().GetBool("yes")
+			output, err := outputFormat(cmd)
+			if err != nil {
+				return err
+			}
+			if apply != "" && apply != squashReset && apply != squashMerge {
+				return usageError("unknown --apply %q, expected %s or %s", apply, squashReset, squashMerge)
+			}
+
+			if apply != "" && !yes && output != outputText {
+				return usageError("--apply needs review, use --yes to squash with the generated message")
+			}
+			review := apply != "" && !yes
+			if review && !interactive(cmd) {
+				return usageError("stdin is not a terminal, use --yes to squash with the generated message")
+			}
+
+			base, err := squashBase(args, dep.cfg, apply == squashMerge)
+			if err != nil {
+				return err
+			}
+			var branch string
+			if apply != "" {
+				if branch, err = currentBranch(); err != nil {
+					return err
+				}
+				if branch == base {
+					return usageError("%s is the base branch, check out the branch to squash first", branch)
+				}
+				if err := checkCleanTree(); err != nil {
+					return err
+				}
+			}
+
+			squash, err := dep.sdk.GenerateSquashMessage(ctx, base)
+			if err != nil {
+				return err
+			}
+			if apply == squashReset {
+				if err := checkRewritable(squash.Commits, dep.cfg.ProtectedBranches(), force); err != nil {
+					return err
+				}
+			}
+
+			if review {
+				if !reviewSquashMessage(newPrompter(), squash) {
+					return &ExitError{Code: ExitCanceled, Err: errors.New("squash canceled")}
+				}
+			} else if output == outputText {
+				fmt.Println(squash.Message)
+			}
+
+			result := squashResult{
+				Message:   squash.Message,
+				Base:      base,
+				MergeBase: squash.MergeBase,
+				CoAuthors: squash.CoAuthors,
+			}
+			for _, commit := range squash.Commits {
+				result.Commits = append(result.Commits, commit.Hash)
+			}
+
+			if apply != "" {
+				// keep stdout parseable, git reports on stderr instead
+				gitOutput := io.Writer(os.Stdout)
+				if output != outputText {
+					gitOutput = os.Stderr
+				}
+
+				if apply == squashReset {
+					result.Commit, result.BackupRef, err = squashInPlace(squash, gitOutput)
+				} else {
+					result.Commit, err = squashOnto(base, branch, squash.Message, gitOutput)
+				}
+				if err != nil {
+					return err
+				}
+				result.Applied = true
+			}
+
+			switch output {
+			case outputJSON:
+				return printJSON(result)
+			case outputRaw:
+				fmt.Println(result.Message)
+			default:
+				if result.BackupRef != "" {
+					fmt.Printf("Squashed %d commit(s) into %s. The previous history is kept in %s,\nrestore it with: git reset --hard %s\n",
+						len(result.Commits), shortHash(result.Commit), result.BackupRef, result.BackupRef)
+				} else if result.Applied {
+					fmt.Printf("Squashed %d commit(s) of %s into %s on %s. %s is unchanged, delete it with: git branch -D %s\n",
+						len(result.Commits), branch, shortHash(result.Commit), base, branch, branch)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("apply", "", "Squash the branch: reset (in place) or merge (onto base)")
+	cmd.Flags().Bool("force", false, "Squash commits that are already on a protected remote branch")
+
+	return cmd
+}
+
+// squashBase returns the branch to squash against: the argument, the pull
+// request base branch or the remote's default branch. The remote-tracking
+// branch stands in for a missing local one unless local is set.
+func squashBase(args []string, cfg *config.Config, local bool) (string, error) {
+	base := cfg.PullRequest.BaseBranch
+	if len(args) > 0 {
+		base = args[0]
+	}
+	if base == "" {
+		base = defaultBaseBranch()
+	}
+
+	if local {
+		if err := exec.Command("git", "show-ref", "--verify", "--quiet", "refs/heads/"+base).Run(); err != nil {
+			return "", fmt.Errorf("%s is not a local branch, --apply merge commits on one", base)
+		}
+		return base, nil
+	}
+
+	if _, err := revParse(base); err == nil {
+		return base, nil
+	}
+	if _, err := revParse("origin/" + base); err == nil {
+		return "origin/" + base, nil
+	}
+	return "", fmt.Errorf("unknown base branch %s", base)
+}
+
+// currentBranch returns the checked out branch
+func currentBranch() (string, error) {
+	out, err := exec.Command("git", "symbolic-ref", "--short", "-q", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("HEAD is detached, check out a branch first")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// reviewSquashMessage shows the message until it is accepted, false cancels
+func reviewSquashMessage(p *prompter, squash *sdk.SquashMessage) bool {
+	for {
+		fmt.Printf("\nSquash message for %d commit(s):\n", len(squash.Commits))
+		fmt.Println("-------------------------")
+		fmt.Println(squash.Message)
+		fmt.Println("-------------------------")
+
+		switch p.choose("Squash with this message? [Y]es, [E]dit, [N]o", []string{"Y", "E", "N"}, "N") {
+		case "Y":
+			return true
+		case "E":
+			edited, err := editMessage(squash.Message)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				continue
+			}
+			squash.Message = edited
+		default:
+			return false
+		}
+	}
+}
+
+// squashInPlace replaces the branch's commits with a single one through a
+// soft reset to the merge base, the original tip is kept in a backup ref
+func squashInPlace(squash *sdk.SquashMessage, output io.Writer) (string, string, error) {
+	head, err := revParse("HEAD")
+	if err != nil {
+		return "", "", err
+	}
+	backupRef, err := createBackupRef(head)
+	if err != nil {
+		return "", "", err
+	}
+
+	if out, err := exec.Command("git", "reset", "--soft", squash.MergeBase).CombinedOutput(); err != nil {
+		exec.Command("git", "update-ref", "-d", backupRef).Run()
+		return "", "", fmt.Errorf("failed to reset to %s: %v: %s", shortHash(squash.MergeBase), err, strings.TrimSpace(string(out)))
+	}
+
+	commit, err := performGitCommit(squash.Message, false, gitCommitOptions{}, output)
+	if err != nil {
+		// put the branch back where it was, the index still matches it
+		exec.Command("git", "reset", "--soft", head).Run()
+		exec.Command("git", "update-ref", "-d", backupRef).Run()
+		return "", "", err
+	}
+	return commit, backupRef, nil
+}
+
+// squashOnto commits the changes of branch as a single commit on base with
+// git merge --squash, going back to branch when that fails
+func squashOnto(base, branch, message string, output io.Writer) (string, error) {
+	if out, err := exec.Command("git", "checkout", "--quiet", base).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to check out %s: %v: %s", base, err, strings.TrimSpace(string(out)))
+	}
+	restore := func() {
+		exec.Command("git", "reset", "--merge").Run()
+		exec.Command("git", "checkout", "--quiet", branch).Run()
+	}
+
+	if out, err := exec.Command("git", "merge", "--squash", "--quiet", branch).CombinedOutput(); err != nil {
+		restore()
+		return "", fmt.Errorf("failed to merge %s into %s, resolve the conflicts with a regular merge: %v: %s",
+			branch, base, err, strings.TrimSpace(string(out)))
+	}
+
+	commit, err := performGitCommit(message, false, gitCommitOptions{}, output)
+	if err != nil {
+		restore()
+		return "", err
+	}
+	return commit, nil
+}
@@ -1,40 +1,200 @@
 package config
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
+	commitindex "git-genius/internal/commit_index"
 	llm "git-genius/internal/llm"
+	"git-genius/internal/prompt"
 	versioncontrol "git-genius/internal/version_control"
 )
 
 type Config struct {
-	ContextProviders []ProviderConfig     `yaml:"context_providers"`
-	IssueID          string               // dynamically set
-	LLM              LLMConfig            `yaml:"llm"`
-	VersionControl   VersionControlConfig `yaml:"version_control"`
+	Version          int                  `yaml:"version,omitempty"`
+	ContextProviders []ProviderConfig     `yaml:"context_providers,omitempty"`
+	IssueID          string               `yaml:"-"` // dynamically set
+	Pathspecs        []string             `yaml:"-"` // dynamically set, limits the git context
+	Amend            bool                 `yaml:"-"` // dynamically set, the git context includes HEAD
+	LLM              LLMConfig            `yaml:"llm,omitempty"`
+	VersionControl   VersionControlConfig `yaml:"version_control,omitempty"`
+	Commit           CommitConfig         `yaml:"commit,omitempty"`
+	PullRequest      PullRequestConfig    `yaml:"pull_request,omitempty"`
+	Prompts          PromptsConfig        `yaml:"prompts,omitempty"`
+	Hook             HookConfig           `yaml:"hook,omitempty"`
+	Rewrite          RewriteConfig        `yaml:"rewrite,omitempty"`
+	Review           ReviewConfig         `yaml:"review,omitempty"`
+	Index            IndexConfig          `yaml:"index,omitempty"`
+	Branch           BranchConfig         `yaml:"branch,omitempty"`
+
+	// Files lists the config files that were loaded, lowest precedence first
+	Files []string `yaml:"-"`
+	// Origins maps each effective key (e.g. "llm.name") to the layer it came from
+	Origins map[string]string `yaml:"-"`
+
+	// positions locates each key in the file it was read from
+	positions map[string]Position
+	// repoFile is the repository-local config file, if one was loaded
+	repoFile string
+}
+
+type CommitConfig struct {
+	Style            string   `yaml:"style,omitempty"`
+	Instructions     string   `yaml:"instructions,omitempty"`
+	Types            []string `yaml:"types,omitempty"`
+	Scopes           []string `yaml:"scopes,omitempty"`
+	MaxSubjectLength int      `yaml:"max_subject_length,omitempty"`
+	BodyWrap         int      `yaml:"body_wrap,omitempty"`
+	// IssuePattern is a regular expression every message must match, e.g. "[A-Z]+-[0-9]+"
+	IssuePattern string `yaml:"issue_pattern,omitempty"`
+}
+
+type PullRequestConfig struct {
+	BaseBranch string `yaml:"base_branch,omitempty"`
+}
+
+type RewriteConfig struct {
+	// ProtectedBranches lists branches whose pushed commits must not be rewritten
+	ProtectedBranches []string `yaml:"protected_branches,omitempty"`
+}
+
+// DefaultProtectedBranches are protected when rewrite.protected_branches is unset
+var DefaultProtectedBranches = []string{"main", "master"}
+
+type ReviewConfig struct {
+	// Rules are the repository's review guidelines added to the review prompt
+	Rules string `yaml:"rules,omitempty"`
+	// FailOn is the lowest severity of a finding that fails the review
+	FailOn string `yaml:"fail_on,omitempty"`
+}
+
+// severities of review findings, most severe first
+const (
+	ReviewSeverityError   = "error"
+	ReviewSeverityWarning = "warning"
+	ReviewSeverityInfo    = "info"
+)
+
+// ReviewFailNever keeps the review from failing whatever it finds
+const ReviewFailNever = "never"
+
+// SupportedReviewFailOn lists the values accepted for review.fail_on
+var SupportedReviewFailOn = []string{ReviewSeverityError, ReviewSeverityWarning, ReviewSeverityInfo, ReviewFailNever}
+
+type BranchConfig struct {
+	// Pattern builds branch names from {type}, {issue} and {slug}
+	Pattern string `yaml:"pattern,omitempty"`
+	// MaxLength bounds the length of proposed branch names
+	MaxLength int `yaml:"max_length,omitempty"`
+}
+
+// defaults of the branch section
+const (
+	DefaultBranchPattern   = "{type}/{issue}-{slug}"
+	DefaultBranchMaxLength = 60
+)
+
+type IndexConfig struct {
+	// Embedding is how indexed commits are matched: bm25 or ollama
+	Embedding string `yaml:"embedding,omitempty"`
+	// URL is the Ollama server computing the embeddings
+	URL string `yaml:"url,omitempty"`
+	// Model is the Ollama embedding model, e.g. nomic-embed-text
+	Model string `yaml:"model,omitempty"`
+	// MaxCommits bounds how many of the latest commits are indexed
+	MaxCommits int `yaml:"max_commits,omitempty"`
+	// Examples is how many similar past commits are given as style examples
+	Examples int `yaml:"examples,omitempty"`
+}
+
+// embedding functions of the commit index
+const (
+	IndexEmbeddingBM25   = "bm25"
+	IndexEmbeddingOllama = "ollama"
+)
+
+// SupportedIndexEmbeddings lists the values accepted for index.embedding
+var SupportedIndexEmbeddings = []string{IndexEmbeddingBM25, IndexEmbeddingOllama}
+
+// defaults of the index section
+const (
+	DefaultOllamaURL       = "http://localhost:11434"
+	DefaultIndexMaxCommits = 5000
+	DefaultIndexExamples   = 5
+)
+
+type HookConfig struct {
+	// Timeout bounds the time the git hooks wait for the LLM, e.g. "10s"
+	Timeout string `yaml:"timeout,omitempty"`
+	// Lint is what the commit-msg hook does with problems: off, warn or block
+	Lint string `yaml:"lint,omitempty"`
+}
+
+// lint modes of the commit-msg hook
+const (
+	HookLintOff   = "off"
+	HookLintWarn  = "warn"
+	HookLintBlock = "block"
+)
+
+// SupportedHookLintModes lists the values accepted for hook.lint
+var SupportedHookLintModes = []string{HookLintOff, HookLintWarn, HookLintBlock}
+
+type PromptsConfig struct {
+	// Dir holds <name>.tmpl files replacing the default prompts
+	Dir string `yaml:"dir,omitempty"`
+	// Templates maps prompt names to template files
+	Templates map[string]string `yaml:"templates,omitempty"`
 }
 
 type VersionControlConfig struct {
-	Provider string `yaml:"provider"`
-	Token    string `yaml:"token"`
+	Provider     string `yaml:"provider,omitempty"`
+	BaseURL      string `yaml:"base_url,omitempty"`
+	Token        Secret `yaml:"token,omitempty"`
+	TokenCmd     string `yaml:"token_cmd,omitempty"`
+	TokenKeyring string `yaml:"token_keyring,omitempty"`
 }
 
 type LLMConfig struct {
-	Name   string `yaml:"name"`
-	APIKey string `yaml:"api_key"`
+	Name          string `yaml:"name,omitempty"`
+	Model         string `yaml:"model,omitempty"`
+	APIKey        Secret `yaml:"api_key,omitempty"`
+	APIKeyCmd     string `yaml:"api_key_cmd,omitempty"`
+	APIKeyKeyring string `yaml:"api_key_keyring,omitempty"`
 }
 
 type ProviderConfig struct {
-	Name   string `yaml:"name"`
-	Path   string `yaml:"path"`
-	APIKey string `yaml:"api_key"`
+	Name          string `yaml:"name,omitempty"`
+	Path          string `yaml:"path,omitempty"`
+	APIKey        Secret `yaml:"api_key,omitempty"`
+	APIKeyCmd     string `yaml:"api_key_cmd,omitempty"`
+	APIKeyKeyring string `yaml:"api_key_keyring,omitempty"`
 }
 
+var (
+	// SupportedLLMs lists the values accepted for llm.name
+	SupportedLLMs = []string{"gemini"}
+	// SupportedVersionControls lists the values accepted for version_control.provider
+	SupportedVersionControls = []string{"github", "gitlab"}
+	// SupportedContextProviders lists the values accepted for context_providers[].name
+	SupportedContextProviders = []string{"git", "linear", "pr_template"}
+)
+
+// RepoConfigFile is the name of the repository-local configuration file
+const RepoConfigFile = ".git-genius.yaml"
+
+// schemaModeline points YAML language servers at the JSON Schema
+const schemaModeline = "# yaml-language-server: $schema=" + SchemaURL + "\n"
+
 // DefaultConfigPath returns the default configuration file path
 func DefaultConfigPath() string {
 	configDir, err := os.UserConfigDir()
@@ -45,43 +205,280 @@ func DefaultConfigPath() string {
 	return filepath.Join(configDir, "git-genius", "config.yaml")
 }
 
-// LoadConfig loads the configuration from the specified path
-func LoadConfig(path string) (*Config, error) {
+// LoadConfig loads the configuration by layering, from lowest to highest
+// precedence, the user config at path (or DefaultConfigPath), the
+// repository-local RepoConfigFile, GIT_GENIUS_* environment variables and
+// the given key=value overrides. Credentials are resolved within ctx.
+func LoadConfig(ctx context.Context, path string, overrides map[string]string) (*Config, error) {
+	cfg, err := LoadConfigLayers(path, overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.ResolveSecrets(ctx); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
 
+// LoadConfigLayers is LoadConfig without resolving the credentials, for
+// callers whose deadline for ResolveSecrets depends on the config
+func LoadConfigLayers(path string, overrides map[string]string) (*Config, error) {
+	explicit := path != ""
 	if path == "" {
 		path = DefaultConfigPath()
 	}
 
-	// Check if the file exists
-	if _, err := os.Stat(path); os.IsNotExist(err) {
+	var layers []*layer
+	var schemaErrors ValidationErrors
+	var repoFile string
+
+	global, err := loadFileLayer(path)
+	if errs, ok := err.(ValidationErrors); ok {
+		schemaErrors = append(schemaErrors, errs...)
+	} else if err != nil {
+		return nil, err
+	}
+	if global == nil && explicit {
 		return nil, fmt.Errorf("config file not found at path: %s", path)
 	}
+	if global != nil {
+		layers = append(layers, global)
+	}
 
-	// Open the file
-	file, err := os.Open(path)
+	if repoPath := RepoConfigPath(); repoPath != "" {
+		repo, err := loadFileLayer(repoPath)
+		if errs, ok := err.(ValidationErrors); ok {
+			schemaErrors = append(schemaErrors, errs...)
+		} else if err != nil {
+			return nil, err
+		}
+		if repo != nil {
+			schemaErrors = append(schemaErrors, checkRepoLayer(repo)...)
+			layers = append(layers, repo)
+			repoFile = repo.origin
+		}
+	}
+
+	if len(schemaErrors) > 0 {
+		return nil, schemaErrors
+	}
+
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("config file not found at path: %s", path)
+	}
+
+	layers = append(layers, envLayers()...)
+
+	flags, err := overrideLayers(overrides)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open config file: %w", err)
+		return nil, err
 	}
-	defer file.Close()
+	layers = append(layers, flags...)
 
-	// Decode YAML
-	var cfg Config
-	if err := yaml.NewDecoder(file).Decode(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	cfg, err := mergeLayers(layers)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range layers {
+		if l.file {
+			cfg.Files = append(cfg.Files, l.origin)
+		}
 	}
+	cfg.repoFile = repoFile
 
-	return &cfg, nil
+	return cfg, nil
+}
+
+// RepoConfigPath returns the path of the repository-local config file, or an
+// empty string when not inside a git repository
+func RepoConfigPath() string {
+	out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(strings.TrimSpace(string(out)), RepoConfigFile)
+}
+
+// Save writes the configuration to path, creating parent directories as needed.
+// The file may contain credentials so it is only readable by the user.
+func (cfg Config) Save(path string) error {
+	var content bytes.Buffer
+	content.WriteString(schemaModeline)
+	encoder := yaml.NewEncoder(&content)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, content.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return nil
 }
 
 func (cfg Config) NewLLM(ctx context.Context) (llm.LLM, error) {
 	switch cfg.LLM.Name {
 	case "gemini":
-		return llm.NewGemini(ctx, cfg.LLM.APIKey)
+		return llm.NewGemini(ctx, cfg.LLM.APIKey.Reveal(), cfg.LLM.Model)
 	default:
 		return nil, fmt.Errorf("unsupported LLM: %s", cfg.LLM.Name)
 	}
 }
 
+// DefaultHookTimeout is used when hook.timeout is not set
+const DefaultHookTimeout = 10 * time.Second
+
+// HookTimeout returns hook.timeout, or DefaultHookTimeout when it is unset
+func (cfg Config) HookTimeout() (time.Duration, error) {
+	if cfg.Hook.Timeout == "" {
+		return DefaultHookTimeout, nil
+	}
+	timeout, err := time.ParseDuration(cfg.Hook.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, expected e.g. 10s or 1m", cfg.Hook.Timeout)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return timeout, nil
+}
+
+// ProtectedBranches returns rewrite.protected_branches, or the defaults,
+// together with the pull request base branch
+func (cfg Config) ProtectedBranches() []string {
+	branches := cfg.Rewrite.ProtectedBranches
+	if len(branches) == 0 {
+		branches = DefaultProtectedBranches
+	}
+	if base := cfg.PullRequest.BaseBranch; base != "" && !slices.Contains(branches, base) {
+		branches = append(slices.Clone(branches), base)
+	}
+	return branches
+}
+
+// HookLint returns hook.lint, warn when it is unset
+func (cfg Config) HookLint() string {
+	if cfg.Hook.Lint == "" {
+		return HookLintWarn
+	}
+	return cfg.Hook.Lint
+}
+
+// ReviewFailOn returns review.fail_on, error when it is unset
+func (cfg Config) ReviewFailOn() string {
+	if cfg.Review.FailOn == "" {
+		return ReviewSeverityError
+	}
+	return cfg.Review.FailOn
+}
+
+// BranchPattern returns branch.pattern, DefaultBranchPattern when it is unset
+func (cfg Config) BranchPattern() string {
+	if cfg.Branch.Pattern == "" {
+		return DefaultBranchPattern
+	}
+	return cfg.Branch.Pattern
+}
+
+// BranchMaxLength returns branch.max_length, DefaultBranchMaxLength when it is unset
+func (cfg Config) BranchMaxLength() int {
+	if cfg.Branch.MaxLength == 0 {
+		return DefaultBranchMaxLength
+	}
+	return cfg.Branch.MaxLength
+}
+
+// IndexMaxCommits returns index.max_commits, DefaultIndexMaxCommits when it is unset
+func (cfg Config) IndexMaxCommits() int {
+	if cfg.Index.MaxCommits == 0 {
+		return DefaultIndexMaxCommits
+	}
+	return cfg.Index.MaxCommits
+}
+
+// IndexExamples returns index.examples, DefaultIndexExamples when it is unset
+func (cfg Config) IndexExamples() int {
+	if cfg.Index.Examples == 0 {
+		return DefaultIndexExamples
+	}
+	return cfg.Index.Examples
+}
+
+// NewEmbedder returns the embedding function of the commit index, nil for
+// the lexical BM25 ranking
+func (cfg Config) NewEmbedder() commitindex.Embedder {
+	if cfg.Index.Embedding != IndexEmbeddingOllama {
+		return nil
+	}
+	url := cfg.Index.URL
+	if url == "" {
+		url = DefaultOllamaURL
+	}
+	return commitindex.NewOllamaEmbedder(url, cfg.Index.Model)
+}
+
+// NewPromptLoader finds prompt overrides in prompts.templates, prompts.dir and
+// the prompts directory next to the user config. Relative paths are resolved
+// against the directory of the config file that set them, paths set by the
+// repository's config are kept inside the repository.
+func (cfg Config) NewPromptLoader() *prompt.Loader {
+	loader := &prompt.Loader{Files: map[string]string{}, Roots: map[string]string{}}
+
+	for name, path := range cfg.Prompts.Templates {
+		key := joinKey("prompts.templates", name)
+		loader.Files[name] = cfg.resolvePath(key, path)
+		if root, ok := cfg.repoRoot(key); ok {
+			loader.Roots[loader.Files[name]] = root
+		}
+	}
+	if cfg.Prompts.Dir != "" {
+		dir := cfg.resolvePath("prompts.dir", cfg.Prompts.Dir)
+		loader.Dirs = append(loader.Dirs, dir)
+		if root, ok := cfg.repoRoot("prompts.dir"); ok {
+			loader.Roots[dir] = root
+		}
+	}
+	if configDir, err := os.UserConfigDir(); err == nil {
+		loader.Dirs = append(loader.Dirs, filepath.Join(configDir, "git-genius", "prompts"))
+	}
+
+	return loader
+}
+
+// repoRoot returns the repository top level when key was set by the
+// repository's config file
+func (cfg Config) repoRoot(key string) (string, bool) {
+	if cfg.repoFile == "" || cfg.Origin(key) != cfg.repoFile {
+		return "", false
+	}
+	return filepath.Dir(cfg.repoFile), true
+}
+
+// resolvePath makes a relative path set in a config file relative to that
+// file. Home directories are only expanded outside of the repository's config.
+func (cfg Config) resolvePath(key, path string) string {
+	if _, repo := cfg.repoRoot(key); !repo && strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	if pos, ok := cfg.positions[key]; ok {
+		return filepath.Join(filepath.Dir(pos.File), path)
+	}
+	return path
+}
+
 // NewPRCreator generates the appropriate PRCreator based on the configuration
 func (cfg Config) NewPRCreator(ctx context.Context) (versioncontrol.PRCreator, error) {
 
@@ -92,8 +489,13 @@ func (cfg Config) NewPRCreator(ctx context.Context) (versioncontrol.PRCreator, e
 		if versionControl.Token == "" {
 			return nil, fmt.Errorf("GitHub token is required for GitHub PR creation")
 		}
-		return versioncontrol.NewGitHubManager(ctx, versionControl.Token)
+		return versioncontrol.NewGitHubManager(ctx, versionControl.Token.Reveal(), versionControl.BaseURL)
+	case "gitlab":
+		if versionControl.Token == "" {
+			return nil, fmt.Errorf("GitLab token is required for GitLab merge request creation")
+		}
+		return versioncontrol.NewGitLabManager(ctx, versionControl.Token.Reveal(), versionControl.BaseURL)
 	default:
-		return nil, fmt.Errorf("unsupported context_provider: %s", versionControl.Provider)
+		return nil, fmt.Errorf("unsupported version_control provider: %s", versionControl.Provider)
 	}
 }
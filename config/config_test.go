@@ -0,0 +1,101 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git-genius/internal/prompt"
+)
+
+func TestNewPromptLoaderRepoPaths(t *testing.T) {
+	outsideDir := t.TempDir()
+	outside := filepath.Join(outsideDir, "commit.tmpl")
+	if err := os.WriteFile(outside, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		user string
+		repo string
+		// setup prepares the repository at root after the config was loaded
+		setup func(t *testing.T, root string)
+		// prompt is the prompt looked up, wantErr whether it must be refused
+		prompt  string
+		wantErr bool
+	}{
+		{
+			name:   "repo template inside",
+			repo:   "prompts:\n  templates:\n    commit: prompts/commit.tmpl\n",
+			prompt: prompt.Commit,
+		},
+		{
+			name:   "user template outside",
+			user:   "prompts:\n  templates:\n    commit: " + outside + "\n",
+			prompt: prompt.Commit,
+		},
+		{
+			name: "repo template turned into a symlink",
+			repo: "prompts:\n  templates:\n    commit: prompts/commit.tmpl\n",
+			setup: func(t *testing.T, root string) {
+				if err := os.Symlink(outsideDir, filepath.Join(root, "prompts")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			prompt:  prompt.Commit,
+			wantErr: true,
+		},
+		{
+			name: "symlink in repo prompts dir",
+			repo: "prompts:\n  dir: prompts\n",
+			setup: func(t *testing.T, root string) {
+				if err := os.Mkdir(filepath.Join(root, "prompts"), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Symlink(outside, filepath.Join(root, "prompts", "branch.tmpl")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			prompt:  prompt.Branch,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			layers := []*layer{fileLayer(t, "config.yaml", "llm:\n  name: gemini\n"+tt.user)}
+			var repo *layer
+			if tt.repo != "" {
+				repo = fileLayer(t, RepoConfigFile, tt.repo)
+				if errs := checkRepoLayer(repo); len(errs) > 0 {
+					t.Fatalf("checkRepoLayer() = %v", errs)
+				}
+				layers = append(layers, repo)
+			}
+			cfg, err := mergeLayers(layers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if repo != nil {
+				cfg.repoFile = repo.origin
+				if tt.setup != nil {
+					tt.setup(t, filepath.Dir(repo.origin))
+				}
+			}
+
+			_, err = cfg.NewPromptLoader().Source(tt.prompt)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "outside of the repository") {
+					t.Errorf("Source(%s) error = %v, want an outside of the repository error", tt.prompt, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Source(%s) error = %v", tt.prompt, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,464 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	repopath "git-genius/internal/repo_path"
+)
+
+const (
+	envPrefix      = "GIT_GENIUS_"
+	originDefault  = "default"
+	originOverride = "flag --set"
+)
+
+// layer is a single source of configuration values
+type layer struct {
+	origin string
+	node   *yaml.Node
+	file   bool
+}
+
+// loadFileLayer reads a YAML config file into a layer. A missing file is not
+// an error and yields a nil layer.
+func loadFileLayer(path string) (*layer, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	// an empty file is a valid, empty layer
+	if len(doc.Content) == 0 {
+		return &layer{origin: path, node: &yaml.Node{Kind: yaml.MappingNode}, file: true}, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse config file %s: top level must be a mapping", path)
+	}
+
+	l := &layer{origin: path, node: root, file: true}
+	if errs := checkSchema(root, path); len(errs) > 0 {
+		return l, errs
+	}
+
+	return l, nil
+}
+
+// repoCredentialMessage says where credentials go instead of the repository file
+const repoCredentialMessage = "credentials cannot be set in " + RepoConfigFile + ", a cloned repository could " +
+	"run commands or read secrets with them; set it in the user config, a GIT_GENIUS_* environment variable or --set"
+
+// repoKeys are the keys, and sections, a repository-local config file may
+// set. Endpoints are left out so that a cloned repository can't send the
+// user's tokens or code to a host of its choosing.
+var repoKeys = []string{
+	"version",
+	"context_providers[].name",
+	"context_providers[].path",
+	"llm.name",
+	"llm.model",
+	"commit",
+	"pull_request",
+	"prompts",
+	"hook",
+	"rewrite",
+	"review",
+	"branch",
+	"index.embedding",
+	"index.model",
+	"index.max_commits",
+	"index.examples",
+}
+
+// repoPathKeys are the keys naming files that are read and sent to the LLM,
+// a repository-local config file may only point them inside the repository
+var repoPathKeys = []string{
+	"prompts.dir",
+	"prompts.templates",
+	"context_providers[].path",
+}
+
+// checkRepoLayer reports the keys a repository-local config file must not
+// set. The file comes with the repository, so it is not trusted with
+// credentials, their commands or keyring references, nor with endpoints or
+// files outside of the repository.
+func checkRepoLayer(l *layer) ValidationErrors {
+	var errs ValidationErrors
+	root := filepath.Dir(l.origin)
+	walkNode(l.node, "", func(key string, n *yaml.Node) {
+		if key == "" || n.Kind != yaml.ScalarNode {
+			return
+		}
+		message := ""
+		switch {
+		case isCredentialKey(key):
+			message = repoCredentialMessage
+		case !isRepoKey(key):
+			message = "cannot be set in " + RepoConfigFile + ", set it in the user config, a GIT_GENIUS_* " +
+				"environment variable or --set"
+		case isRepoPathKey(key):
+			if message = repopath.Check(root, n.Value); message == "" {
+				return
+			}
+		default:
+			return
+		}
+		errs = append(errs, &ValidationError{
+			Key:      key,
+			Message:  message,
+			Position: &Position{File: l.origin, Line: n.Line, Column: n.Column},
+		})
+	})
+	return errs
+}
+
+// isRepoKey reports whether a repository-local config file may set key
+func isRepoKey(key string) bool {
+	key = indexPattern.ReplaceAllString(key, "[]")
+	for _, allowed := range repoKeys {
+		if key == allowed || strings.HasPrefix(key, allowed+".") || strings.HasPrefix(key, allowed+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// isRepoPathKey reports whether key names a file or directory to read
+func isRepoPathKey(key string) bool {
+	key = indexPattern.ReplaceAllString(key, "[]")
+	for _, path := range repoPathKeys {
+		if key == path || strings.HasPrefix(key, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// isCredentialKey reports whether key holds a credential, the command
+// printing it or its keyring reference
+func isCredentialKey(key string) bool {
+	for _, suffix := range []string{"", "_cmd", "_keyring"} {
+		if trimmed, ok := strings.CutSuffix(key, suffix); ok && IsSecretKey(trimmed) {
+			return true
+		}
+	}
+	return false
+}
+
+// envLayers collects GIT_GENIUS_* variables for every known scalar key, e.g.
+// GIT_GENIUS_LLM_NAME overrides llm.name
+func envLayers() []*layer {
+	var layers []*layer
+	for _, key := range KnownKeys() {
+		name := EnvVarName(key)
+		if value, ok := os.LookupEnv(name); ok {
+			layers = append(layers, scalarLayer("env "+name, key, value))
+		}
+	}
+	return layers
+}
+
+// overrideLayers builds layers from key=value pairs passed on the command line
+func overrideLayers(overrides map[string]string) ([]*layer, error) {
+	known := map[string]bool{}
+	for _, key := range KnownKeys() {
+		known[key] = true
+	}
+
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		if !known[key] {
+			return nil, fmt.Errorf("unknown config key: %s", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var layers []*layer
+	for _, key := range keys {
+		layers = append(layers, scalarLayer(originOverride+" "+key, key, overrides[key]))
+	}
+	return layers, nil
+}
+
+// scalarLayer builds a layer holding a single value
+func scalarLayer(origin, key, value string) *layer {
+	l := &layer{origin: origin, node: &yaml.Node{Kind: yaml.MappingNode}}
+	setPath(l.node, strings.Split(key, "."), value)
+	return l
+}
+
+// EnvVarName returns the environment variable that overrides the given key
+func EnvVarName(key string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// KnownKeys returns the dotted paths of every scalar key in Config
+func KnownKeys() []string {
+	var keys []string
+	collectKeys(reflect.TypeOf(Config{}), "", &keys)
+	sort.Strings(keys)
+	return keys
+}
+
+func collectKeys(t reflect.Type, prefix string, keys *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		key := joinKey(prefix, name)
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			collectKeys(field.Type, key, keys)
+		case reflect.Slice, reflect.Map:
+			// lists and maps can only be set from files
+		default:
+			*keys = append(*keys, key)
+		}
+	}
+}
+
+// mergeLayers merges the layers in order of precedence and decodes the result
+func mergeLayers(layers []*layer) (*Config, error) {
+	merged := &yaml.Node{Kind: yaml.MappingNode}
+	origins := map[string]string{}
+	files := map[string]bool{}
+
+	for _, l := range layers {
+		mergeNode(merged, l.node, "", l.origin, origins)
+		files[l.origin] = l.file
+	}
+
+	// merged nodes keep the line and column of the file they were read from
+	positions := map[string]Position{}
+	walkNode(merged, "", func(key string, n *yaml.Node) {
+		if key == "" || n.Line == 0 {
+			return
+		}
+		if origin := nodeOrigin(origins, key); files[origin] {
+			positions[key] = Position{File: origin, Line: n.Line, Column: n.Column}
+		}
+	})
+
+	var cfg Config
+	if err := merged.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	cfg.Origins = origins
+	cfg.positions = positions
+
+	return &cfg, nil
+}
+
+// mergeNode overlays src onto dst. Mappings are merged key by key, anything
+// else (scalars and lists) replaces the existing value wholesale. Items of a
+// replaced list keep the keys they don't set from the item of the same name,
+// e.g. the api_key of the linear context provider.
+func mergeNode(dst, src *yaml.Node, prefix, origin string, origins map[string]string) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		keyNode, valueNode := src.Content[i], src.Content[i+1]
+		key := joinKey(prefix, keyNode.Value)
+
+		existing := mappingValue(dst, keyNode.Value)
+		if existing != nil && existing.Kind == yaml.MappingNode && valueNode.Kind == yaml.MappingNode {
+			mergeNode(existing, valueNode, key, origin, origins)
+			continue
+		}
+
+		var inherited map[string]string
+		if existing != nil && existing.Kind == yaml.SequenceNode && valueNode.Kind == yaml.SequenceNode {
+			valueNode, inherited = inheritNamedItems(existing, valueNode, key, origins)
+		}
+
+		// forget the origins of whatever is being replaced
+		for k := range origins {
+			if k == key || strings.HasPrefix(k, key+".") || strings.HasPrefix(k, key+"[") {
+				delete(origins, k)
+			}
+		}
+
+		if existing != nil {
+			*existing = *valueNode
+		} else {
+			dst.Content = append(dst.Content, keyNode, valueNode)
+		}
+		for leaf := range flattenNode(valueNode, key) {
+			origins[leaf] = origin
+		}
+		for leaf, leafOrigin := range inherited {
+			origins[leaf] = leafOrigin
+		}
+	}
+}
+
+// inheritNamedItems copies src, adding to each mapping item the keys it
+// lacks from the item of dst with the same name. It returns the copy and
+// the origins of the inherited leaves.
+func inheritNamedItems(dst, src *yaml.Node, key string, origins map[string]string) (*yaml.Node, map[string]string) {
+	merged := *src
+	merged.Content = make([]*yaml.Node, len(src.Content))
+	inherited := map[string]string{}
+
+	for i, item := range src.Content {
+		merged.Content[i] = item
+		name := mappingValue(item, "name")
+		if item.Kind != yaml.MappingNode || name == nil {
+			continue
+		}
+		for j, previous := range dst.Content {
+			previousName := mappingValue(previous, "name")
+			if previous.Kind != yaml.MappingNode || previousName == nil || previousName.Value != name.Value {
+				continue
+			}
+
+			copied := *item
+			copied.Content = append([]*yaml.Node(nil), item.Content...)
+			for k := 0; k+1 < len(previous.Content); k += 2 {
+				field := previous.Content[k].Value
+				if mappingValue(item, field) != nil {
+					continue
+				}
+				copied.Content = append(copied.Content, previous.Content[k], previous.Content[k+1])
+				from := fmt.Sprintf("%s[%d].%s", key, j, field)
+				for leaf := range flattenNode(previous.Content[k+1], from) {
+					inherited[fmt.Sprintf("%s[%d].%s", key, i, field)+strings.TrimPrefix(leaf, from)] = origins[leaf]
+				}
+			}
+			merged.Content[i] = &copied
+			break
+		}
+	}
+	return &merged, inherited
+}
+
+// nodeOrigin returns the origin of key, or of the first leaf below it
+func nodeOrigin(origins map[string]string, key string) string {
+	if origin, ok := origins[key]; ok {
+		return origin
+	}
+
+	var children []string
+	for k := range origins {
+		if strings.HasPrefix(k, key+".") || strings.HasPrefix(k, key+"[") {
+			children = append(children, k)
+		}
+	}
+	if len(children) == 0 {
+		return ""
+	}
+	sort.Strings(children)
+	return origins[children[0]]
+}
+
+// mappingValue returns the value node for key in a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setPath sets a scalar value at the given path, creating mappings as needed
+func setPath(node *yaml.Node, path []string, value string) {
+	for i, part := range path {
+		child := mappingValue(node, part)
+		if i == len(path)-1 {
+			scalar := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+			if child != nil {
+				*child = *scalar
+			} else {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: part}, scalar)
+			}
+			return
+		}
+		if child == nil || child.Kind != yaml.MappingNode {
+			child = &yaml.Node{Kind: yaml.MappingNode}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: part}, child)
+		}
+		node = child
+	}
+}
+
+// walkNode calls fn for node and every node below it with its dotted path
+func walkNode(node *yaml.Node, prefix string, fn func(key string, node *yaml.Node)) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			walkNode(child, prefix, fn)
+		}
+		return
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			walkNode(node.Content[i+1], joinKey(prefix, node.Content[i].Value), fn)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			walkNode(child, prefix+"["+strconv.Itoa(i)+"]", fn)
+		}
+	}
+	fn(prefix, node)
+}
+
+// flattenNode returns the scalar leaves under node keyed by their dotted path
+func flattenNode(node *yaml.Node, prefix string) map[string]string {
+	leaves := map[string]string{}
+	walkNode(node, prefix, func(key string, n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode {
+			leaves[key] = n.Value
+		}
+	})
+	return leaves
+}
+
+// Values returns every effective value of the config keyed by its dotted path
+func (cfg Config) Values() (map[string]string, error) {
+	var node yaml.Node
+	if err := node.Encode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	values := flattenNode(&node, "")
+	for key, value := range values {
+		if value == "" {
+			delete(values, key)
+		}
+	}
+	return values, nil
+}
+
+// Origin returns the layer the given key was read from
+func (cfg Config) Origin(key string) string {
+	if origin, ok := cfg.Origins[key]; ok {
+		return origin
+	}
+	return originDefault
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
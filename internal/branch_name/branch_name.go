@@ -0,0 +1,118 @@
+// Package branchname builds branch names from a pattern such as
+// "{type}/{issue}-{slug}" and makes them valid git ref names.
+package branchname
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// placeholders of a branch name pattern
+const (
+	PlaceholderType  = "{type}"
+	PlaceholderIssue = "{issue}"
+	PlaceholderSlug  = "{slug}"
+)
+
+// Placeholders lists the placeholders a pattern may contain
+var Placeholders = []string{PlaceholderType, PlaceholderIssue, PlaceholderSlug}
+
+// separators join the parts of a branch name
+const separators = "-_./"
+
+var (
+	placeholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+	// invalidChars are replaced, git allows more but these are awkward in shells
+	invalidChars  = regexp.MustCompile(`[^A-Za-z0-9._/-]+`)
+	separatorRuns = regexp.MustCompile(`[-_./]{2,}`)
+)
+
+// Fields are the values of the placeholders
+type Fields struct {
+	Type  string
+	Issue string
+	Slug  string
+}
+
+// UnknownPlaceholders returns the placeholders of pattern that are not in
+// Placeholders
+func UnknownPlaceholders(pattern string) []string {
+	var unknown []string
+	for _, placeholder := range placeholderPattern.FindAllString(pattern, -1) {
+		if !slices.Contains(Placeholders, placeholder) && !slices.Contains(unknown, placeholder) {
+			unknown = append(unknown, placeholder)
+		}
+	}
+	return unknown
+}
+
+// Format fills pattern with fields and sanitizes the result. Placeholders
+// without a value are left out together with their separator. The slug is
+// shortened at a word boundary to keep the name within maxLength, 0
+// meaning no limit.
+func Format(pattern string, fields Fields, maxLength int) string {
+	fill := func(slug string) string {
+		return Sanitize(strings.NewReplacer(
+			PlaceholderType, Sanitize(fields.Type),
+			PlaceholderIssue, Sanitize(fields.Issue),
+			PlaceholderSlug, slug,
+		).Replace(pattern))
+	}
+
+	slug := Slugify(fields.Slug)
+	name := fill(slug)
+	for maxLength > 0 && len(name) > maxLength && slug != "" {
+		cut := strings.LastIndexByte(slug, '-')
+		if cut < 0 {
+			// a single long word
+			slug = strings.Trim(slug[:max(0, len(slug)-(len(name)-maxLength))], separators)
+		} else {
+			slug = slug[:cut]
+		}
+		name = fill(slug)
+	}
+	if maxLength > 0 && len(name) > maxLength {
+		name = Sanitize(name[:maxLength])
+	}
+	return name
+}
+
+// Slugify lowercases text and joins its words with dashes, e.g. "Add OAuth
+// login" becomes "add-oauth-login"
+func Slugify(text string) string {
+	slug := invalidChars.ReplaceAllString(strings.ToLower(text), "-")
+	slug = strings.NewReplacer(".", "-", "/", "-", "_", "-").Replace(slug)
+	return strings.Trim(separatorRuns.ReplaceAllString(slug, "-"), "-")
+}
+
+// Sanitize turns name into a valid branch name following the rules of git
+// check-ref-format: no spaces or special characters, no empty components,
+// no component starting with a dot or ending with .lock and no leading dash.
+func Sanitize(name string) string {
+	name = invalidChars.ReplaceAllString(name, "-")
+	// a run of separators is replaced by its most significant one
+	name = separatorRuns.ReplaceAllStringFunc(name, func(run string) string {
+		for _, sep := range []string{"/", "-", "_"} {
+			if strings.Contains(run, sep) {
+				return sep
+			}
+		}
+		return "."
+	})
+
+	var components []string
+	for _, component := range strings.Split(name, "/") {
+		for {
+			trimmed := strings.TrimSuffix(strings.Trim(component, separators), ".lock")
+			if trimmed == component {
+				break
+			}
+			component = trimmed
+		}
+		if component != "" {
+			components = append(components, component)
+		}
+	}
+	return strings.Join(components, "/")
+}
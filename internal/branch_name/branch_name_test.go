@@ -0,0 +1,89 @@
+package branchname
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "valid", in: "feat/eng-12-add-login", want: "feat/eng-12-add-login"},
+		{name: "spaces and specials", in: "feat/add login~now^?", want: "feat/add-login-now"},
+		{name: "separator runs", in: "feat//add--login__x..y", want: "feat/add-login_x.y"},
+		{name: "mixed run keeps the slash", in: "feat-/add", want: "feat/add"},
+		{name: "leading dash", in: "-feat", want: "feat"},
+		{name: "component starting with a dot", in: "feat/.hidden", want: "feat/hidden"},
+		{name: "lock suffix", in: "feat/x.lock/y", want: "feat/x/y"},
+		{name: "repeated lock suffix", in: "x.lock.lock", want: "x"},
+		{name: "trailing slash and dot", in: "feat/add./", want: "feat/add"},
+		{name: "at brace", in: "feat@{1}", want: "feat-1"},
+		{name: "only invalid", in: "~^:", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Sanitize(tt.in)
+			if got != tt.want {
+				t.Errorf("Sanitize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if got != "" {
+				checkRefFormat(t, got)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	fields := Fields{Type: "feat", Issue: "ENG-12", Slug: "Add OAuth login for GitHub"}
+
+	tests := []struct {
+		name      string
+		pattern   string
+		fields    Fields
+		maxLength int
+		want      string
+	}{
+		{name: "default pattern", pattern: "{type}/{issue}-{slug}", fields: fields, want: "feat/ENG-12-add-oauth-login-for-github"},
+		{name: "no issue", pattern: "{type}/{issue}-{slug}", fields: Fields{Type: "fix", Slug: "Handle nil"}, want: "fix/handle-nil"},
+		{name: "no type", pattern: "{type}/{issue}-{slug}", fields: Fields{Issue: "#42", Slug: "Handle nil"}, want: "42-handle-nil"},
+		{name: "literal prefix", pattern: "users/alex/{slug}", fields: fields, want: "users/alex/add-oauth-login-for-github"},
+		{name: "shortened at a word", pattern: "{type}/{issue}-{slug}", fields: fields, maxLength: 30, want: "feat/ENG-12-add-oauth-login"},
+		{name: "single long word", pattern: "{type}/{slug}", fields: Fields{Type: "feat", Slug: "supercalifragilistic"}, maxLength: 12, want: "feat/superca"},
+		{name: "slug dropped", pattern: "{type}/{issue}-{slug}", fields: fields, maxLength: 12, want: "feat/ENG-12"},
+		{name: "cut without slug", pattern: "{type}/{issue}", fields: Fields{Type: "feature", Issue: "PROJECT-12345"}, maxLength: 10, want: "feature/PR"},
+		{name: "unsafe values", pattern: "{type}/{slug}", fields: Fields{Type: "fix..", Slug: "../etc/passwd"}, want: "fix/etc-passwd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Format(tt.pattern, tt.fields, tt.maxLength)
+			if got != tt.want {
+				t.Errorf("Format(%q) = %q, want %q", tt.pattern, got, tt.want)
+			}
+			if tt.maxLength > 0 && len(got) > tt.maxLength {
+				t.Errorf("Format(%q) = %q is longer than %d", tt.pattern, got, tt.maxLength)
+			}
+			checkRefFormat(t, got)
+		})
+	}
+}
+
+func TestUnknownPlaceholders(t *testing.T) {
+	got := UnknownPlaceholders("{type}/{user}-{slug}-{user}-{ticket}")
+	if want := []string{"{user}", "{ticket}"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UnknownPlaceholders() = %v, want %v", got, want)
+	}
+}
+
+// checkRefFormat asks git whether name is a valid branch name
+func checkRefFormat(t *testing.T, name string) {
+	t.Helper()
+	if err := exec.Command("git", "check-ref-format", "--branch", name).Run(); err != nil {
+		t.Errorf("git check-ref-format --branch %q: %v", name, err)
+	}
+}
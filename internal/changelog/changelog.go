@@ -0,0 +1,381 @@
+// Package changelog builds Keep a Changelog sections from commit messages.
+package changelog
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	commitstyle "git-genius/internal/commit_style"
+)
+
+// sections of a release, in the order they are written
+const (
+	SectionBreaking   = "Breaking Changes"
+	SectionAdded      = "Added"
+	SectionChanged    = "Changed"
+	SectionDeprecated = "Deprecated"
+	SectionRemoved    = "Removed"
+	SectionFixed      = "Fixed"
+	SectionSecurity   = "Security"
+)
+
+// Sections lists the sections entries are sorted into
+var Sections = []string{SectionAdded, SectionChanged, SectionDeprecated, SectionRemoved, SectionFixed, SectionSecurity}
+
+// typeSections maps Conventional Commits types to sections, types that are
+// missing only appear with Options.All
+var typeSections = map[string]string{
+	"feat":     SectionAdded,
+	"fix":      SectionFixed,
+	"perf":     SectionChanged,
+	"refactor": SectionChanged,
+	"revert":   SectionChanged,
+	"security": SectionSecurity,
+}
+
+// verbSections sorts plain messages by the verb they start with
+var verbSections = map[string]string{
+	"add":        SectionAdded,
+	"implement":  SectionAdded,
+	"introduce":  SectionAdded,
+	"support":    SectionAdded,
+	"fix":        SectionFixed,
+	"resolve":    SectionFixed,
+	"correct":    SectionFixed,
+	"remove":     SectionRemoved,
+	"delete":     SectionRemoved,
+	"drop":       SectionRemoved,
+	"deprecate":  SectionDeprecated,
+	"security":   SectionSecurity,
+	"vulnerable": SectionSecurity,
+}
+
+// KeepAChangelogHeader starts a new CHANGELOG.md
+const KeepAChangelogHeader = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/).
+`
+
+var (
+	pullRequestSuffix = regexp.MustCompile(`\s*\(#(\d+)\)$`)
+	mergeRequestLine  = regexp.MustCompile(`(?m)^See merge request \S*!(\d+)$`)
+	issueReference    = regexp.MustCompile(`#\d+`)
+	wordPattern       = regexp.MustCompile(`^[A-Za-z]+`)
+)
+
+// issueTokens are the footers that reference issues
+var issueTokens = []string{"closes", "close", "fixes", "fix", "resolves", "resolve", "refs", "ref", "references"}
+
+// Options tune how commit messages are read
+type Options struct {
+	// IssuePattern matches issue keys such as ABC-123 in messages
+	IssuePattern *regexp.Regexp
+	// All lists docs, tests, chores and other internal changes under Changed
+	All bool
+}
+
+// Entry is one change of a release
+type Entry struct {
+	Hash    string `json:"hash"`
+	Type    string `json:"type,omitempty"`
+	Scope   string `json:"scope,omitempty"`
+	Subject string `json:"subject"`
+	// Section is where the entry is listed, empty when it is left out
+	Section string `json:"section,omitempty"`
+	// Breaking holds the breaking change notes, the subject when the message
+	// only marks the change as breaking
+	Breaking     []string `json:"breaking,omitempty"`
+	PullRequests []int    `json:"pull_requests,omitempty"`
+	Issues       []string `json:"issues,omitempty"`
+}
+
+// Release is a version and its changes
+type Release struct {
+	// Version is the tag, or "Unreleased"
+	Version string `json:"version"`
+	// Date is the YYYY-MM-DD release date, empty when unreleased
+	Date string `json:"date,omitempty"`
+	// From and To are the revisions the release spans, used for the compare link
+	From    string   `json:"from,omitempty"`
+	To      string   `json:"to"`
+	Entries []*Entry `json:"entries"`
+}
+
+// Unreleased is the version of changes that are not tagged yet
+const Unreleased = "Unreleased"
+
+// NewEntry reads the type, scope, pull request and issues of a commit message
+func NewEntry(hash, message string, opts Options) *Entry {
+	msg := commitstyle.Parse(message)
+	entry := &Entry{Hash: hash, Type: strings.ToLower(msg.Type), Scope: msg.Scope, Subject: msg.Subject}
+	if entry.Type == "" {
+		if t, subject, ok := commitstyle.GitmojiType(msg.Header); ok {
+			entry.Type, entry.Subject = t, subject
+		}
+	}
+
+	// squash merges end the header with the pull request, e.g. "(#12)"
+	if m := pullRequestSuffix.FindStringSubmatch(entry.Subject); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		entry.PullRequests = append(entry.PullRequests, n)
+		entry.Subject = entry.Subject[:len(entry.Subject)-len(m[0])]
+	}
+	// GitLab's squash and merge commits name the merge request in the body
+	if m := mergeRequestLine.FindStringSubmatch(message); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		entry.AddPullRequest(n)
+	}
+
+	entry.Breaking = msg.BreakingChanges()
+	if msg.Breaking && len(entry.Breaking) == 0 {
+		entry.Breaking = []string{entry.Subject}
+	}
+
+	for _, footer := range msg.Footers {
+		if slices.Contains(issueTokens, strings.ToLower(footer.Token)) {
+			entry.addIssues(issueReference.FindAllString(footer.Token+footer.Separator+footer.Value, -1))
+		}
+	}
+	if opts.IssuePattern != nil {
+		entry.addIssues(opts.IssuePattern.FindAllString(message, -1))
+	}
+
+	entry.Section = section(entry, opts.All)
+	return entry
+}
+
+// ApplyLabels sorts an entry without a type by the labels of its pull
+// request, e.g. bug or enhancement
+func (e *Entry) ApplyLabels(labels []string, all bool) {
+	for _, label := range labels {
+		switch strings.ToLower(label) {
+		case "breaking", "breaking change", "breaking-change":
+			if len(e.Breaking) == 0 {
+				e.Breaking = []string{e.Subject}
+			}
+		case "bug", "fix":
+			if e.Type == "" {
+				e.Type = "fix"
+			}
+		case "enhancement", "feature":
+			if e.Type == "" {
+				e.Type = "feat"
+			}
+		case "security":
+			if e.Type == "" {
+				e.Type = "security"
+			}
+		}
+	}
+	e.Section = section(e, all)
+}
+
+// AddPullRequest links the entry to a pull request
+func (e *Entry) AddPullRequest(n int) {
+	if !slices.Contains(e.PullRequests, n) {
+		e.PullRequests = append(e.PullRequests, n)
+	}
+}
+
+func (e *Entry) addIssues(issues []string) {
+	for _, issue := range issues {
+		if !slices.Contains(e.Issues, issue) {
+			e.Issues = append(e.Issues, issue)
+		}
+	}
+}
+
+func section(entry *Entry, all bool) string {
+	if entry.Type != "" {
+		if s, ok := typeSections[entry.Type]; ok {
+			return s
+		}
+		if all || len(entry.Breaking) > 0 {
+			return SectionChanged
+		}
+		return ""
+	}
+
+	// "Adds", "Added" and "Add" all count
+	verb := strings.ToLower(wordPattern.FindString(entry.Subject))
+	for _, suffix := range []string{"", "s", "d", "es", "ed"} {
+		if s, ok := verbSections[strings.TrimSuffix(verb, suffix)]; ok && strings.HasSuffix(verb, suffix) {
+			return s
+		}
+	}
+	return SectionChanged
+}
+
+// Links builds the URLs of a repository's web UI, e.g. https://github.com/owner/repo
+type Links struct {
+	Repo string
+	// GitLab switches to GitLab's /-/ paths and !N merge request references
+	GitLab bool
+}
+
+func (l *Links) pullRequest(n int) string {
+	switch {
+	case l == nil:
+		return fmt.Sprintf("#%d", n)
+	case l.GitLab:
+		return fmt.Sprintf("[!%d](%s/-/merge_requests/%d)", n, l.Repo, n)
+	default:
+		return fmt.Sprintf("[#%d](%s/pull/%d)", n, l.Repo, n)
+	}
+}
+
+func (l *Links) issue(issue string) string {
+	if l == nil || !strings.HasPrefix(issue, "#") {
+		return issue
+	}
+	return fmt.Sprintf("[%s](%s)", issue, l.url("issues/"+issue[1:]))
+}
+
+func (l *Links) commit(hash string) string {
+	short := hash
+	if len(short) > 7 {
+		short = short[:7]
+	}
+	if l == nil {
+		return short
+	}
+	return fmt.Sprintf("[%s](%s)", short, l.url("commit/"+hash))
+}
+
+// url returns the web UI URL of a repository page such as "commit/<hash>"
+func (l *Links) url(page string) string {
+	if l.GitLab {
+		return l.Repo + "/-/" + page
+	}
+	return l.Repo + "/" + page
+}
+
+// Markdown renders the release as a Keep a Changelog section, breaking
+// changes first and entries grouped by scope within each section
+func (r *Release) Markdown(links *Links) string {
+	var b strings.Builder
+
+	heading := "[" + r.Version + "]"
+	if links != nil && r.From != "" {
+		heading += fmt.Sprintf("(%s)", links.url("compare/"+r.From+"..."+r.To))
+	}
+	if r.Date != "" {
+		heading += " - " + r.Date
+	}
+	b.WriteString("## " + heading + "\n")
+
+	var breaking []string
+	for _, entry := range r.Entries {
+		for _, note := range entry.Breaking {
+			breaking = append(breaking, scoped(entry.Scope, note))
+		}
+	}
+	if len(breaking) > 0 {
+		b.WriteString("\n### " + SectionBreaking + "\n\n")
+		for _, note := range breaking {
+			b.WriteString("- " + strings.ReplaceAll(note, "\n", "\n  ") + "\n")
+		}
+	}
+
+	for _, name := range Sections {
+		var entries []*Entry
+		for _, entry := range r.Entries {
+			if entry.Section == name {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		// unscoped entries first, then by scope, keeping the commit order
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Scope < entries[j].Scope })
+
+		b.WriteString("\n### " + name + "\n\n")
+		for _, entry := range entries {
+			b.WriteString("- " + scoped(entry.Scope, entry.Subject) + " (" + entry.references(links) + ")\n")
+		}
+	}
+
+	return b.String()
+}
+
+func (e *Entry) references(links *Links) string {
+	var refs []string
+	for _, n := range e.PullRequests {
+		refs = append(refs, links.pullRequest(n))
+	}
+	refs = append(refs, links.commit(e.Hash))
+	text := strings.Join(refs, ", ")
+
+	if len(e.Issues) > 0 {
+		issues := make([]string, len(e.Issues))
+		for i, issue := range e.Issues {
+			issues[i] = links.issue(issue)
+		}
+		text += ", closes " + strings.Join(issues, ", ")
+	}
+	return text
+}
+
+func scoped(scope, text string) string {
+	if scope == "" {
+		return text
+	}
+	return "**" + scope + ":** " + text
+}
+
+// Prepend inserts a release section above the newest one in the changelog
+// at path, creating the file when it doesn't exist. The section replaces an
+// Unreleased section at the top, the released version takes its changes
+// over.
+func Prepend(path, section, version string) error {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		content = []byte(KeepAChangelogHeader)
+	} else if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	start, end := len(lines), len(lines)
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "## ") {
+			continue
+		}
+		if start == len(lines) {
+			start, end = i, i
+			if strings.HasPrefix(line, "## ["+Unreleased+"]") {
+				// drop the Unreleased section up to the next heading
+				end = len(lines)
+				for j := i + 1; j < len(lines); j++ {
+					if strings.HasPrefix(lines[j], "## ") {
+						end = j
+						break
+					}
+				}
+			}
+		}
+		if version != Unreleased && strings.HasPrefix(line, "## ["+version+"]") {
+			return fmt.Errorf("%s already has a section for %s", path, version)
+		}
+	}
+
+	head := strings.TrimRight(strings.Join(lines[:start], ""), "\n") + "\n\n"
+	rest := strings.Join(lines[end:], "")
+	updated := head + strings.TrimRight(section, "\n") + "\n"
+	if rest != "" {
+		updated += "\n" + rest
+	}
+
+	if err := os.WriteFile(path, []byte(updated), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
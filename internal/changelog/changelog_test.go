@@ -0,0 +1,185 @@
+package changelog
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNewEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		opts    Options
+		want    Entry
+	}{
+		{
+			name:    "feature",
+			message: "feat(api): add pagination",
+			want:    Entry{Type: "feat", Scope: "api", Subject: "add pagination", Section: SectionAdded},
+		},
+		{
+			name:    "fix with issue footer",
+			message: "fix: handle empty input\n\nCloses #12",
+			want:    Entry{Type: "fix", Subject: "handle empty input", Section: SectionFixed, Issues: []string{"#12"}},
+		},
+		{
+			name:    "squash merge suffix",
+			message: "fix: handle empty input (#34)",
+			want:    Entry{Type: "fix", Subject: "handle empty input", Section: SectionFixed, PullRequests: []int{34}},
+		},
+		{
+			name:    "gitlab merge request",
+			message: "feat: export csv\n\nSee merge request group/project!56",
+			want:    Entry{Type: "feat", Subject: "export csv", Section: SectionAdded, PullRequests: []int{56}},
+		},
+		{
+			name:    "breaking marker",
+			message: "feat!: drop the v1 api",
+			want:    Entry{Type: "feat", Subject: "drop the v1 api", Section: SectionAdded, Breaking: []string{"drop the v1 api"}},
+		},
+		{
+			name:    "breaking footer",
+			message: "refactor: rename config keys\n\nBREAKING CHANGE: llm.key is now llm.api_key",
+			want: Entry{Type: "refactor", Subject: "rename config keys", Section: SectionChanged,
+				Breaking: []string{"llm.key is now llm.api_key"}},
+		},
+		{
+			name:    "chore left out",
+			message: "chore: bump dependencies",
+			want:    Entry{Type: "chore", Subject: "bump dependencies"},
+		},
+		{
+			name:    "chore with all",
+			message: "chore: bump dependencies",
+			opts:    Options{All: true},
+			want:    Entry{Type: "chore", Subject: "bump dependencies", Section: SectionChanged},
+		},
+		{
+			name:    "plain message by verb",
+			message: "Removed the legacy exporter",
+			want:    Entry{Subject: "Removed the legacy exporter", Section: SectionRemoved},
+		},
+		{
+			name:    "plain message without known verb",
+			message: "Update readme",
+			want:    Entry{Subject: "Update readme", Section: SectionChanged},
+		},
+		{
+			name:    "issue pattern",
+			message: "fix: retry uploads for ENG-42",
+			opts:    Options{IssuePattern: regexp.MustCompile(`\bENG-\d+\b`)},
+			want:    Entry{Type: "fix", Subject: "retry uploads for ENG-42", Section: SectionFixed, Issues: []string{"ENG-42"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.Hash = "abc1234"
+			got := NewEntry("abc1234", tt.message, tt.opts)
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("NewEntry() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepend(t *testing.T) {
+	v1 := "## [v1.0.0] - 2026-01-01\n\n### Added\n\n- first release\n"
+	unreleased := "## [Unreleased]\n\n### Fixed\n\n- pending fix\n"
+
+	tests := []struct {
+		name     string
+		existing string
+		section  string
+		version  string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:    "new file",
+			section: v1,
+			version: "v1.0.0",
+			want:    KeepAChangelogHeader + "\n" + v1,
+		},
+		{
+			name:     "above the newest release",
+			existing: KeepAChangelogHeader + "\n" + v1,
+			section:  "## [v1.1.0] - 2026-02-01\n\n- second\n",
+			version:  "v1.1.0",
+			want:     KeepAChangelogHeader + "\n## [v1.1.0] - 2026-02-01\n\n- second\n\n" + v1,
+		},
+		{
+			name:     "release takes over unreleased",
+			existing: KeepAChangelogHeader + "\n" + unreleased + "\n" + v1,
+			section:  "## [v1.1.0] - 2026-02-01\n\n- pending fix\n",
+			version:  "v1.1.0",
+			want:     KeepAChangelogHeader + "\n## [v1.1.0] - 2026-02-01\n\n- pending fix\n\n" + v1,
+		},
+		{
+			name:     "unreleased replaces unreleased",
+			existing: KeepAChangelogHeader + "\n" + unreleased + "\n" + v1,
+			section:  "## [Unreleased]\n\n- newer fix\n",
+			version:  Unreleased,
+			want:     KeepAChangelogHeader + "\n## [Unreleased]\n\n- newer fix\n\n" + v1,
+		},
+		{
+			name:     "unreleased above releases",
+			existing: KeepAChangelogHeader + "\n" + v1,
+			section:  unreleased,
+			version:  Unreleased,
+			want:     KeepAChangelogHeader + "\n" + unreleased + "\n" + v1,
+		},
+		{
+			name:     "without sections",
+			existing: "# Changelog\n",
+			section:  v1,
+			version:  "v1.0.0",
+			want:     "# Changelog\n\n" + v1,
+		},
+		{
+			name:     "existing version",
+			existing: KeepAChangelogHeader + "\n" + unreleased + "\n" + v1,
+			section:  v1,
+			version:  "v1.0.0",
+			wantErr:  "already has a section for v1.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+			if tt.existing != "" {
+				if err := os.WriteFile(path, []byte(tt.existing), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := Prepend(path, tt.section, tt.version)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Prepend() error = %v, want %q", err, tt.wantErr)
+				}
+				content, _ := os.ReadFile(path)
+				if string(content) != tt.existing {
+					t.Errorf("Prepend() changed the file on error:\n%s", content)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Prepend() error = %v", err)
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.want {
+				t.Errorf("Prepend() wrote:\n%s\nwant:\n%s", content, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package commitindex
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters, the usual defaults
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// bm25 scores every document sharing a term with text
+func (idx *Index) bm25(text string) []Match {
+	query := tokenize(text)
+	if len(query) == 0 || len(idx.Documents) == 0 {
+		return nil
+	}
+
+	if idx.tokens == nil {
+		idx.tokens = make(map[*Document][]string, len(idx.Documents))
+		for _, doc := range idx.Documents {
+			idx.tokens[doc] = tokenize(doc.text())
+		}
+	}
+
+	terms := map[string]bool{}
+	for _, term := range query {
+		terms[term] = true
+	}
+
+	// term frequencies of the query terms per document
+	frequencies := make(map[*Document]map[string]int, len(idx.Documents))
+	df := map[string]int{}
+	total := 0
+	for _, doc := range idx.Documents {
+		tokens := idx.tokens[doc]
+		total += len(tokens)
+		var tf map[string]int
+		for _, token := range tokens {
+			if !terms[token] {
+				continue
+			}
+			if tf == nil {
+				tf = map[string]int{}
+			}
+			if tf[token] == 0 {
+				df[token]++
+			}
+			tf[token]++
+		}
+		if tf != nil {
+			frequencies[doc] = tf
+		}
+	}
+
+	n := float64(len(idx.Documents))
+	avgLength := float64(total) / n
+	var matches []Match
+	for _, doc := range idx.Documents {
+		tf := frequencies[doc]
+		if tf == nil {
+			continue
+		}
+		length := float64(len(idx.tokens[doc]))
+		score := 0.0
+		for term := range terms {
+			f := float64(tf[term])
+			if f == 0 {
+				continue
+			}
+			idf := math.Log(1 + (n-float64(df[term])+0.5)/(float64(df[term])+0.5))
+			score += idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*length/avgLength))
+		}
+		matches = append(matches, Match{Document: doc, Score: score})
+	}
+	return matches
+}
+
+// tokenize splits text into lowercase words, paths and identifiers into
+// their parts, e.g. "cmd/root.go" into cmd, root and go
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := words[:0]
+	for _, word := range words {
+		if len(word) > 1 {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,87 @@
+package commitindex
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "words", text: "Fix the Login flow", want: []string{"fix", "the", "login", "flow"}},
+		{name: "paths", text: "cmd/root.go", want: []string{"cmd", "root", "go"}},
+		{name: "identifiers", text: "parse_remote-url v2", want: []string{"parse", "remote", "url", "v2"}},
+		{name: "single characters dropped", text: "a b cd 1 22", want: []string{"cd", "22"}},
+		{name: "unicode", text: "Änderung übernommen", want: []string{"änderung", "übernommen"}},
+		{name: "empty", text: " -- ", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBM25(t *testing.T) {
+	idx := &Index{Documents: []*Document{
+		{Hash: "login", Message: "fix: handle expired tokens in the login flow", Summary: "auth/login.go"},
+		{Hash: "readme", Message: "docs: describe the login setup", Summary: "README.md"},
+		{Hash: "cache", Message: "perf: cache remote lookups", Summary: "internal/remote.go"},
+		{Hash: "tokens", Message: "refactor: rename token helpers, token refresh and token expiry", Summary: "auth/token.go"},
+	}}
+
+	tests := []struct {
+		name  string
+		query string
+		// want is the order of the matching documents
+		want []string
+	}{
+		{name: "single term", query: "cache", want: []string{"cache"}},
+		{name: "rarer terms weigh more", query: "login expired", want: []string{"login", "readme"}},
+		{name: "term frequency", query: "token", want: []string{"tokens"}},
+		{name: "path parts", query: "auth/login.go", want: []string{"login", "tokens", "readme", "cache"}},
+		{name: "case insensitive", query: "CACHE Remote", want: []string{"cache"}},
+		{name: "no shared term", query: "kubernetes", want: nil},
+		{name: "empty query", query: "!", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := idx.Search(context.Background(), nil, tt.query, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, match := range matches {
+				if match.Score <= 0 {
+					t.Errorf("%s scored %f, want a positive score", match.Hash, match.Score)
+				}
+				got = append(got, match.Hash)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBM25Limit(t *testing.T) {
+	idx := &Index{}
+	for _, hash := range []string{"a", "b", "c"} {
+		idx.Documents = append(idx.Documents, &Document{Hash: hash, Message: "fix: login"})
+	}
+	matches, err := idx.Search(context.Background(), nil, "login", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Errorf("Search() returned %d matches, want 2", len(matches))
+	}
+}
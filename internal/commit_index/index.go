@@ -0,0 +1,375 @@
+// Package commitindex keeps a local index of commit messages and change
+// summaries for finding past commits similar to a change. Commits are
+// matched with embeddings when an Embedder is configured and with BM25 over
+// their words otherwise.
+package commitindex
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// formatVersion changes when the file format does, older files are rebuilt
+const formatVersion = 1
+
+// batchSize is how many commits are read and embedded at a time
+const batchSize = 64
+
+// maxEmbedText bounds the text embedded per commit, in bytes
+const maxEmbedText = 2000
+
+// Embedder turns texts into vectors, similar texts into close vectors
+type Embedder interface {
+	// Name identifies the model, documents embedded by another model are
+	// embedded again
+	Name() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Document is an indexed commit
+type Document struct {
+	Hash    string
+	Author  string
+	Date    string
+	Message string
+	// Summary lists the changed files with their added and removed lines
+	Summary   string
+	Embedding []float32
+}
+
+// Match is a document found by Search
+type Match struct {
+	*Document
+	Score float64
+}
+
+// Index is the set of indexed commits of a repository
+type Index struct {
+	Version int
+	// Model is the Embedder the documents were embedded with, empty when
+	// they were not
+	Model     string
+	Documents []*Document
+
+	path   string
+	byHash map[string]*Document
+	// tokens caches the BM25 terms of each document
+	tokens map[*Document][]string
+}
+
+// DefaultPath returns where the index of the current repository is stored,
+// in the git directory shared by all worktrees
+func DefaultPath() (string, error) {
+	out, err := exec.Command("git", "rev-parse", "--path-format=absolute", "--git-common-dir").Output()
+	if err != nil {
+		return "", fmt.Errorf("not inside a git repository")
+	}
+	return filepath.Join(strings.TrimSpace(string(out)), "git-genius", "index.gob"), nil
+}
+
+// Open loads the index at path, an empty one when it doesn't exist yet or
+// was written by another version
+func Open(path string) (*Index, error) {
+	idx := &Index{Version: formatVersion}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return idx.init(path), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open commit index: %w", err)
+	}
+	defer file.Close()
+
+	if err := gob.NewDecoder(file).Decode(idx); err != nil {
+		return nil, fmt.Errorf("failed to read commit index %s, rebuild it: %w", path, err)
+	}
+	if idx.Version != formatVersion {
+		idx = &Index{Version: formatVersion}
+	}
+	return idx.init(path), nil
+}
+
+func (idx *Index) init(path string) *Index {
+	idx.path = path
+	idx.byHash = map[string]*Document{}
+	for _, doc := range idx.Documents {
+		idx.byHash[doc.Hash] = doc
+	}
+	return idx
+}
+
+// Path returns where the index is stored
+func (idx *Index) Path() string {
+	return idx.path
+}
+
+// Save writes the index, replacing the file atomically
+func (idx *Index) Save() error {
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create commit index directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(idx.path), "index-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write commit index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(idx); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write commit index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write commit index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), idx.path); err != nil {
+		return fmt.Errorf("failed to write commit index: %w", err)
+	}
+	return nil
+}
+
+// Update indexes the latest maxCommits non-merge commits of HEAD that are
+// not indexed yet, drops the commits HEAD no longer reaches, e.g. after a
+// rebase or an amend, and embeds every document without an embedding of the
+// embedder's model, nil meaning BM25 only. The index is saved as it
+// progresses, so an interrupted update is resumed by the next one. progress
+// is called after each batch with the number of commits done.
+func (idx *Index) Update(ctx context.Context, embedder Embedder, maxCommits int, progress func(done, total int)) (int, error) {
+	out, err := exec.Command("git", "rev-list", "--no-merges", "HEAD").Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list commits: %w", err)
+	}
+	hashes := strings.Fields(string(out))
+	pruned := idx.prune(hashes)
+
+	var missing []string
+	for _, hash := range hashes[:min(maxCommits, len(hashes))] {
+		if idx.byHash[hash] == nil {
+			missing = append(missing, hash)
+		}
+	}
+
+	if embedder != nil && embedder.Name() != idx.Model {
+		for _, doc := range idx.Documents {
+			doc.Embedding = nil
+		}
+		idx.Model = embedder.Name()
+	}
+	var unembedded []*Document
+	if embedder != nil {
+		for _, doc := range idx.Documents {
+			if doc.Embedding == nil {
+				unembedded = append(unembedded, doc)
+			}
+		}
+	}
+
+	total := len(missing) + len(unembedded)
+	done := 0
+	if err := idx.embed(ctx, embedder, unembedded); err != nil {
+		return 0, err
+	}
+	done += len(unembedded)
+	if len(unembedded) > 0 && progress != nil {
+		progress(done, total)
+	}
+
+	added := 0
+	for start := 0; start < len(missing); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return added, err
+		}
+		batch := missing[start:min(start+batchSize, len(missing))]
+		docs, err := readDocuments(batch)
+		if err != nil {
+			return added, err
+		}
+		if err := idx.embed(ctx, embedder, docs); err != nil {
+			return added, err
+		}
+
+		for _, doc := range docs {
+			idx.Documents = append(idx.Documents, doc)
+			idx.byHash[doc.Hash] = doc
+		}
+		idx.tokens = nil
+		added += len(docs)
+		if err := idx.Save(); err != nil {
+			return added, err
+		}
+
+		done += len(batch)
+		if progress != nil {
+			progress(done, total)
+		}
+	}
+
+	if (len(unembedded) > 0 || pruned) && len(missing) == 0 {
+		return added, idx.Save()
+	}
+	return added, nil
+}
+
+// prune drops the documents of commits missing from reachable and reports
+// whether there were any
+func (idx *Index) prune(reachable []string) bool {
+	keep := make(map[string]bool, len(reachable))
+	for _, hash := range reachable {
+		keep[hash] = true
+	}
+
+	var docs []*Document
+	for _, doc := range idx.Documents {
+		if keep[doc.Hash] {
+			docs = append(docs, doc)
+		} else {
+			delete(idx.byHash, doc.Hash)
+		}
+	}
+	if len(docs) == len(idx.Documents) {
+		return false
+	}
+	idx.Documents = docs
+	idx.tokens = nil
+	return true
+}
+
+// embed sets the embeddings of docs in batches
+func (idx *Index) embed(ctx context.Context, embedder Embedder, docs []*Document) error {
+	if embedder == nil {
+		return nil
+	}
+	for start := 0; start < len(docs); start += batchSize {
+		batch := docs[start:min(start+batchSize, len(docs))]
+		texts := make([]string, len(batch))
+		for i, doc := range batch {
+			texts[i] = doc.text()
+		}
+		vectors, err := embedder.Embed(ctx, texts)
+		if err != nil {
+			return err
+		}
+		if len(vectors) != len(batch) {
+			return fmt.Errorf("embedding model returned %d embeddings for %d texts", len(vectors), len(batch))
+		}
+		for i, doc := range batch {
+			doc.Embedding = vectors[i]
+		}
+	}
+	return nil
+}
+
+// text is what is embedded and searched for a document
+func (d *Document) text() string {
+	text := d.Message + "\n\n" + d.Summary
+	if len(text) > maxEmbedText {
+		// cut at the start of a rune, not inside one
+		cut := maxEmbedText
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut]
+	}
+	return text
+}
+
+// readDocuments reads the messages and changed files of commits
+func readDocuments(hashes []string) ([]*Document, error) {
+	// commits start with RS and their fields are separated by NUL, the
+	// numstat lines follow the message
+	args := append([]string{"log", "--no-walk=unsorted", "--no-renames", "--numstat", "--format=%x1e%H%x00%an <%ae>%x00%as%x00%B%x00"}, hashes...)
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commits: %w", err)
+	}
+
+	var docs []*Document
+	for _, record := range strings.Split(string(out), "\x1e") {
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\x00", 5)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("failed to parse git log output")
+		}
+		docs = append(docs, &Document{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Date:    fields[2],
+			Message: strings.TrimSpace(fields[3]),
+			Summary: changeSummary(fields[4]),
+		})
+	}
+	return docs, nil
+}
+
+// changeSummary turns numstat lines into "<path> +<added> -<removed>" lines
+func changeSummary(numstat string) string {
+	var files []string
+	for _, line := range strings.Split(strings.TrimSpace(numstat), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		// binary files have no line counts
+		if fields[0] == "-" {
+			files = append(files, fields[2]+" (binary)")
+			continue
+		}
+		files = append(files, fmt.Sprintf("%s +%s -%s", fields[2], fields[0], fields[1]))
+	}
+	return strings.Join(files, "\n")
+}
+
+// Search returns the k documents most similar to text. With the embedder
+// the documents were embedded with, documents are ranked by cosine
+// similarity, otherwise by BM25.
+func (idx *Index) Search(ctx context.Context, embedder Embedder, text string, k int) ([]Match, error) {
+	var matches []Match
+	if embedder != nil && embedder.Name() == idx.Model {
+		vectors, err := embedder.Embed(ctx, []string{text})
+		if err != nil {
+			return nil, err
+		}
+		if len(vectors) != 1 {
+			return nil, fmt.Errorf("embedding model returned %d embeddings for 1 text", len(vectors))
+		}
+		for _, doc := range idx.Documents {
+			if doc.Embedding != nil {
+				matches = append(matches, Match{Document: doc, Score: cosine(vectors[0], doc.Embedding)})
+			}
+		}
+	} else {
+		matches = idx.bm25(text)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
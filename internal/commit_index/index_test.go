@@ -0,0 +1,92 @@
+package commitindex
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDocumentText(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    int
+	}{
+		{name: "short", message: "fix: login", want: len("fix: login\n\n")},
+		{name: "ascii cut", message: strings.Repeat("a", maxEmbedText+10), want: maxEmbedText},
+		// "ü" takes two bytes, the last one would be split at the limit
+		{name: "rune kept whole", message: strings.Repeat("a", maxEmbedText-1) + "ü", want: maxEmbedText - 1},
+		{name: "wide runes", message: strings.Repeat("日", maxEmbedText), want: maxEmbedText - maxEmbedText%3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := (&Document{Message: tt.message}).text()
+			if len(text) != tt.want {
+				t.Errorf("len(text()) = %d, want %d", len(text), tt.want)
+			}
+			if !utf8.ValidString(text) {
+				t.Errorf("text() is not valid UTF-8")
+			}
+		})
+	}
+}
+
+func TestUpdatePrunesRewrittenCommits(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Sam")
+	t.Setenv("GIT_AUTHOR_EMAIL", "sam@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Sam")
+	t.Setenv("GIT_COMMITTER_EMAIL", "sam@example.com")
+	t.Setenv("GIT_DIR", filepath.Join(dir, ".git"))
+	t.Setenv("GIT_WORK_TREE", dir)
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "--quiet", dir)
+	git("commit", "--quiet", "--allow-empty", "-m", "feat: add login")
+	git("commit", "--quiet", "--allow-empty", "-m", "fix: typo")
+	first := git("rev-parse", "HEAD~1")
+
+	path := filepath.Join(t.TempDir(), "index.gob")
+	idx, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added, err := idx.Update(context.Background(), nil, 10, nil); err != nil || added != 2 {
+		t.Fatalf("Update() = %d, %v, want 2 commits added", added, err)
+	}
+
+	git("commit", "--quiet", "--amend", "--allow-empty", "-m", "fix: spelling")
+	amended := git("rev-parse", "HEAD")
+	if _, err := idx.Update(context.Background(), nil, 10, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the saved index must have forgotten the replaced commit too
+	saved, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, idx := range []*Index{idx, saved} {
+		var hashes []string
+		for _, doc := range idx.Documents {
+			hashes = append(hashes, doc.Hash)
+		}
+		if want := []string{first, amended}; !reflect.DeepEqual(hashes, want) {
+			t.Errorf("indexed commits = %v, want %v", hashes, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package commitindex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ollamaTimeout bounds each request, a batch of commits included
+const ollamaTimeout = 2 * time.Minute
+
+// OllamaEmbedder embeds texts with a model served by Ollama, or any server
+// implementing its /api/embed endpoint
+type OllamaEmbedder struct {
+	client  *http.Client
+	baseURL string
+	model   string
+}
+
+// NewOllamaEmbedder creates an embedder for the model served at baseURL,
+// e.g. http://localhost:11434
+func NewOllamaEmbedder(baseURL, model string) *OllamaEmbedder {
+	return &OllamaEmbedder{
+		client:  &http.Client{Timeout: ollamaTimeout},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   model,
+	}
+}
+
+// Name identifies the model, e.g. ollama:nomic-embed-text
+func (o *OllamaEmbedder) Name() string {
+	return "ollama:" + o.model
+}
+
+// Embed returns one embedding per text
+func (o *OllamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	encoded, err := json.Marshal(map[string]interface{}{
+		"model": o.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/api/embed", bytes.NewReader(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Ollama URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to contact Ollama at %s: %w", o.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("ollama responded with status: %d, message: %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
+	}
+
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode Ollama response: %w", err)
+	}
+	return result.Embeddings, nil
+}
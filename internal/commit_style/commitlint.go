@@ -0,0 +1,176 @@
+package commitstyle
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// commitlintFiles are the config files commitlint looks for, in its order
+var commitlintFiles = []string{
+	".commitlintrc",
+	".commitlintrc.json",
+	".commitlintrc.yaml",
+	".commitlintrc.yml",
+	".commitlintrc.js",
+	".commitlintrc.cjs",
+	".commitlintrc.mjs",
+	".commitlintrc.ts",
+	"commitlint.config.js",
+	"commitlint.config.cjs",
+	"commitlint.config.mjs",
+	"commitlint.config.ts",
+}
+
+// Commitlint holds the rules read from a repository's commitlint config
+type Commitlint struct {
+	Path              string
+	Types             []string
+	Scopes            []string
+	HeaderMaxLength   int
+	BodyMaxLineLength int
+}
+
+// Apply fills the rules left unset by the git-genius config
+func (c *Commitlint) Apply(r Rules) Rules {
+	if c == nil {
+		return r
+	}
+	if len(r.Types) == 0 {
+		r.Types = c.Types
+	}
+	if len(r.Scopes) == 0 {
+		r.Scopes = c.Scopes
+	}
+	if r.MaxSubjectLength == 0 {
+		r.MaxSubjectLength = c.HeaderMaxLength
+	}
+	if r.BodyWrap == 0 {
+		r.BodyWrap = c.BodyMaxLineLength
+	}
+	return r
+}
+
+// LoadCommitlint reads the commitlint config of the repository at root. It
+// returns nil when the repository has none.
+func LoadCommitlint(root string) (*Commitlint, error) {
+	for _, name := range commitlintFiles {
+		path := filepath.Join(root, name)
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		switch filepath.Ext(name) {
+		case "", ".json", ".yaml", ".yml":
+			return parseCommitlintData(path, content)
+		default:
+			return parseCommitlintScript(path, content), nil
+		}
+	}
+
+	// package.json may carry the config under the "commitlint" key
+	content, err := os.ReadFile(filepath.Join(root, "package.json"))
+	if err != nil {
+		return nil, nil
+	}
+	var pkg struct {
+		Commitlint json.RawMessage `json:"commitlint"`
+	}
+	if err := json.Unmarshal(content, &pkg); err != nil || len(pkg.Commitlint) == 0 {
+		return nil, nil
+	}
+	return parseCommitlintData(filepath.Join(root, "package.json"), pkg.Commitlint)
+}
+
+// parseCommitlintData reads rules such as "type-enum": [2, "always", [...]]
+// from JSON or YAML
+func parseCommitlintData(path string, content []byte) (*Commitlint, error) {
+	var data struct {
+		Rules map[string][]interface{} `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(content, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	c := &Commitlint{Path: path}
+	for rule, value := range data.Rules {
+		// [level, applicable, value], level 0 disables the rule
+		if len(value) < 3 || fmt.Sprint(value[0]) == "0" || value[1] != "always" {
+			continue
+		}
+		switch rule {
+		case "type-enum":
+			c.Types = toStrings(value[2])
+		case "scope-enum":
+			c.Scopes = toStrings(value[2])
+		case "header-max-length":
+			c.HeaderMaxLength = toInt(value[2])
+		case "body-max-line-length":
+			c.BodyMaxLineLength = toInt(value[2])
+		}
+	}
+	return c, nil
+}
+
+var (
+	scriptEnumRule   = regexp.MustCompile(`['"]?(type-enum|scope-enum)['"]?\s*:\s*\[\s*[12]\s*,\s*['"]always['"]\s*,\s*\[([^\]]*)\]`)
+	scriptLengthRule = regexp.MustCompile(`['"]?(header-max-length|body-max-line-length)['"]?\s*:\s*\[\s*[12]\s*,\s*['"]always['"]\s*,\s*(\d+)\s*\]`)
+	quotedString     = regexp.MustCompile(`['"]([^'"]+)['"]`)
+)
+
+// parseCommitlintScript picks literal rules out of a JS/TS config without
+// evaluating it
+func parseCommitlintScript(path string, content []byte) *Commitlint {
+	c := &Commitlint{Path: path}
+
+	for _, m := range scriptEnumRule.FindAllStringSubmatch(string(content), -1) {
+		var values []string
+		for _, q := range quotedString.FindAllStringSubmatch(m[2], -1) {
+			values = append(values, q[1])
+		}
+		if m[1] == "type-enum" {
+			c.Types = values
+		} else {
+			c.Scopes = values
+		}
+	}
+
+	for _, m := range scriptLengthRule.FindAllStringSubmatch(string(content), -1) {
+		n, _ := strconv.Atoi(m[2])
+		if m[1] == "header-max-length" {
+			c.HeaderMaxLength = n
+		} else {
+			c.BodyMaxLineLength = n
+		}
+	}
+
+	return c
+}
+
+func toStrings(value interface{}) []string {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	var out []string
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func toInt(value interface{}) int {
+	n, _ := strconv.Atoi(fmt.Sprint(value))
+	return n
+}
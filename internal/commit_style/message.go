@@ -0,0 +1,133 @@
+package commitstyle
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Message is a commit message split into its parts
+type Message struct {
+	Header string
+	Body   string
+	// Footers are the git trailers and BREAKING CHANGE notes of the last paragraph
+	Footers []Footer
+
+	// Conventional Commits fields, empty when the header doesn't follow the spec
+	Type     string
+	Scope    string
+	Breaking bool
+	Subject  string
+}
+
+// Footer is a "Token: value" or "Token #value" line at the end of a message
+type Footer struct {
+	Token string
+	Value string
+	// Separator is ": " or " #", empty means ": "
+	Separator string
+}
+
+var (
+	conventionalHeader = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?(!)?: (.*)$`)
+	footerLine         = regexp.MustCompile(`^(BREAKING CHANGE|BREAKING-CHANGE|[A-Za-z][A-Za-z0-9-]*)(: | #)(.*)$`)
+	blankLines         = regexp.MustCompile(`\n\s*\n`)
+)
+
+// Parse splits a raw commit message into header, body and footers
+func Parse(raw string) *Message {
+	raw = strings.TrimSpace(strings.ReplaceAll(raw, "\r\n", "\n"))
+	header, rest, _ := strings.Cut(raw, "\n")
+
+	msg := &Message{Header: strings.TrimSpace(header), Subject: strings.TrimSpace(header)}
+	if m := conventionalHeader.FindStringSubmatch(msg.Header); m != nil {
+		msg.Type = m[1]
+		msg.Scope = m[2]
+		msg.Breaking = m[3] == "!"
+		msg.Subject = m[4]
+	}
+
+	paragraphs := splitParagraphs(strings.TrimSpace(rest))
+	if n := len(paragraphs); n > 0 {
+		if footers, ok := parseFooters(paragraphs[n-1]); ok {
+			msg.Footers = footers
+			paragraphs = paragraphs[:n-1]
+		}
+	}
+	msg.Body = strings.Join(paragraphs, "\n\n")
+
+	for _, footer := range msg.Footers {
+		if isBreakingToken(footer.Token) {
+			msg.Breaking = true
+		}
+	}
+
+	return msg
+}
+
+// String renders the message back into git's format
+func (m *Message) String() string {
+	var b strings.Builder
+	b.WriteString(m.Header)
+	if m.Body != "" {
+		b.WriteString("\n\n" + m.Body)
+	}
+	if len(m.Footers) > 0 {
+		b.WriteString("\n")
+		for _, footer := range m.Footers {
+			b.WriteString("\n" + footer.String())
+		}
+	}
+	return b.String()
+}
+
+func (f Footer) String() string {
+	if f.Separator == "" {
+		return f.Token + ": " + f.Value
+	}
+	return f.Token + f.Separator + f.Value
+}
+
+// BreakingChanges returns the descriptions of the BREAKING CHANGE footers
+func (m *Message) BreakingChanges() []string {
+	var notes []string
+	for _, footer := range m.Footers {
+		if isBreakingToken(footer.Token) {
+			notes = append(notes, footer.Value)
+		}
+	}
+	return notes
+}
+
+// parseFooters parses a paragraph made only of footers, continuation lines
+// are appended to the previous footer
+func parseFooters(paragraph string) ([]Footer, bool) {
+	var footers []Footer
+	for _, line := range strings.Split(paragraph, "\n") {
+		if m := footerLine.FindStringSubmatch(line); m != nil {
+			footers = append(footers, Footer{Token: m[1], Value: m[3], Separator: m[2]})
+			continue
+		}
+		if len(footers) == 0 || !strings.HasPrefix(line, " ") {
+			return nil, false
+		}
+		footers[len(footers)-1].Value += "\n" + line
+	}
+	return footers, len(footers) > 0
+}
+
+func splitParagraphs(text string) []string {
+	if text == "" {
+		return nil
+	}
+	var paragraphs []string
+	for _, p := range blankLines.Split(text, -1) {
+		if p = strings.Trim(p, "\n"); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
+
+func isBreakingToken(token string) bool {
+	return token == "BREAKING CHANGE" || token == "BREAKING-CHANGE"
+}
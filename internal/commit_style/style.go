@@ -0,0 +1,378 @@
+package commitstyle
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Style string
+
+const (
+	ConventionalStyle Style = "conventional"
+	GitmojiStyle      Style = "gitmoji"
+	PlainStyle        Style = "plain"
+	CustomStyle       Style = "custom"
+)
+
+// Styles lists every supported style
+var Styles = []Style{ConventionalStyle, GitmojiStyle, PlainStyle, CustomStyle}
+
+const (
+	defaultMaxSubjectLength = 72
+	defaultBodyWrap         = 72
+)
+
+// DefaultTypes are the types of @commitlint/config-conventional
+var DefaultTypes = []string{"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test"}
+
+// gitmojis maps conventional types to their gitmoji, used to repair messages
+var gitmojis = map[string]string{
+	"feat":     ":sparkles:",
+	"fix":      ":bug:",
+	"docs":     ":memo:",
+	"style":    ":lipstick:",
+	"refactor": ":recycle:",
+	"perf":     ":zap:",
+	"test":     ":white_check_mark:",
+	"build":    ":package:",
+	"ci":       ":construction_worker:",
+	"chore":    ":wrench:",
+	"revert":   ":rewind:",
+}
+
+// Rules describe what a valid commit message looks like
+type Rules struct {
+	Style Style
+	// CustomInstructions replace the built-in style description for the custom style
+	CustomInstructions string
+	Types              []string
+	Scopes             []string
+	MaxSubjectLength   int
+	BodyWrap           int
+}
+
+// Violation is a single way a message breaks the rules
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Rule, v.Message)
+}
+
+// WithDefaults fills unset limits and types
+func (r Rules) WithDefaults() Rules {
+	if r.Style == "" {
+		r.Style = PlainStyle
+	}
+	if r.MaxSubjectLength <= 0 {
+		r.MaxSubjectLength = defaultMaxSubjectLength
+	}
+	if r.BodyWrap <= 0 {
+		r.BodyWrap = defaultBodyWrap
+	}
+	if len(r.Types) == 0 && r.Style == ConventionalStyle {
+		r.Types = DefaultTypes
+	}
+	return r
+}
+
+// Instructions describes the rules for the LLM prompt
+func (r Rules) Instructions() string {
+	r = r.WithDefaults()
+
+	var b strings.Builder
+	switch r.Style {
+	case ConventionalStyle:
+		b.WriteString("Follow the Conventional Commits 1.0.0 specification: a header \"<type>(<scope>)!: <subject>\" where the scope and ! are optional.\n")
+		fmt.Fprintf(&b, "Allowed types: %s.\n", strings.Join(r.Types, ", "))
+		if len(r.Scopes) > 0 {
+			fmt.Fprintf(&b, "Allowed scopes: %s.\n", strings.Join(r.Scopes, ", "))
+		}
+		b.WriteString("Write the subject in the imperative mood, starting with a lowercase letter and without a trailing period.\n")
+		b.WriteString("Mark breaking changes with ! after the type or scope and a \"BREAKING CHANGE: <description>\" footer.\n")
+	case GitmojiStyle:
+		b.WriteString("Start the header with a single gitmoji shortcode such as :sparkles: for features or :bug: for fixes, followed by a space and the subject.\n")
+		b.WriteString("Write the subject in the imperative mood without a trailing period.\n")
+	case CustomStyle:
+		b.WriteString(strings.TrimSpace(r.CustomInstructions) + "\n")
+	default:
+		b.WriteString("Write a short summary line in the imperative mood without a trailing period.\n")
+	}
+	fmt.Fprintf(&b, "Keep the header under %d characters. ", r.MaxSubjectLength)
+	fmt.Fprintf(&b, "If a body is needed, separate it from the header with a blank line and wrap it at %d characters.\n", r.BodyWrap)
+	b.WriteString("Reply with the commit message only, as plain text without markdown code fences or commentary.")
+
+	return b.String()
+}
+
+var gitmojiPrefix = regexp.MustCompile(`^(:[a-z0-9_+-]+:)\s`)
+
+// GitmojiType returns the conventional type of a header starting with one of
+// the gitmoji shortcodes used for the types, and the rest of the header
+func GitmojiType(header string) (string, string, bool) {
+	m := gitmojiPrefix.FindStringSubmatch(header)
+	if m == nil {
+		return "", "", false
+	}
+	for t, emoji := range gitmojis {
+		if emoji == m[1] {
+			return t, strings.TrimSpace(header[len(m[0]):]), true
+		}
+	}
+	return "", "", false
+}
+
+// Validate checks the message against the rules
+func Validate(raw string, r Rules) []Violation {
+	r = r.WithDefaults()
+	msg := Parse(raw)
+	var violations []Violation
+	add := func(rule, format string, args ...interface{}) {
+		violations = append(violations, Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.Contains(raw, "```") {
+		add("format", "message contains markdown code fences")
+	}
+
+	if msg.Header == "" {
+		add("header", "header is empty")
+		return violations
+	}
+	if n := utf8.RuneCountInString(msg.Header); n > r.MaxSubjectLength {
+		add("header-max-length", "header is %d characters, the limit is %d", n, r.MaxSubjectLength)
+	}
+
+	switch r.Style {
+	case ConventionalStyle:
+		if msg.Type == "" {
+			add("type", "header must look like \"<type>(<scope>): <subject>\"")
+			break
+		}
+		if !slices.Contains(r.Types, msg.Type) {
+			add("type-enum", "type %q is not one of: %s", msg.Type, strings.Join(r.Types, ", "))
+		}
+		if msg.Scope != "" && len(r.Scopes) > 0 {
+			for _, scope := range strings.Split(msg.Scope, ",") {
+				if !slices.Contains(r.Scopes, strings.TrimSpace(scope)) {
+					add("scope-enum", "scope %q is not one of: %s", scope, strings.Join(r.Scopes, ", "))
+				}
+			}
+		}
+		if strings.TrimSpace(msg.Subject) == "" {
+			add("subject-empty", "subject is empty")
+		} else if startsWithSentenceCase(msg.Subject) {
+			add("subject-case", "subject must start with a lowercase letter")
+		}
+	case GitmojiStyle:
+		if !gitmojiPrefix.MatchString(msg.Header) && !startsWithEmoji(msg.Header) {
+			add("gitmoji", "header must start with a gitmoji such as :sparkles:")
+		}
+	}
+
+	if strings.HasSuffix(msg.Header, ".") {
+		add("subject-full-stop", "header must not end with a period")
+	}
+
+	lines := strings.Split(strings.TrimSpace(raw), "\n")
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		add("body-leading-blank", "the header must be followed by a blank line")
+	}
+	for _, line := range strings.Split(msg.Body, "\n") {
+		if utf8.RuneCountInString(line) > r.BodyWrap && strings.Contains(strings.TrimSpace(line), " ") {
+			add("body-max-line-length", "body line exceeds %d characters: %q", r.BodyWrap, truncate(line, 40))
+			break
+		}
+	}
+
+	for _, footer := range msg.Footers {
+		if isBreakingToken(footer.Token) && strings.TrimSpace(footer.Value) == "" {
+			add("footer", "BREAKING CHANGE footer needs a description")
+		}
+	}
+
+	return violations
+}
+
+var (
+	codeFence  = regexp.MustCompile("(?m)^\\s*```[a-zA-Z]*\\s*$")
+	listMarker = regexp.MustCompile(`^([-*] |\d+\. )`)
+	preamble   = regexp.MustCompile(`(?i)^\s*(\*\*)?(commit message|here is the commit message|here's the commit message)[^\n]*:(\*\*)?\s*\n`)
+)
+
+// Clean removes markdown fences, preambles and stray quoting LLMs wrap
+// messages in
+func Clean(raw string) string {
+	cleaned := strings.ReplaceAll(raw, "\r\n", "\n")
+	cleaned = preamble.ReplaceAllString(cleaned, "")
+	cleaned = codeFence.ReplaceAllString(cleaned, "")
+	cleaned = strings.TrimSpace(cleaned)
+
+	header, rest, _ := strings.Cut(cleaned, "\n")
+	header = strings.Trim(strings.TrimSpace(header), "`*\"")
+	header = strings.TrimPrefix(header, "# ")
+
+	if rest == "" {
+		return header
+	}
+	return header + "\n" + rest
+}
+
+// Fix repairs what can be repaired locally: case, punctuation, lengths,
+// blank lines and wrapping
+func Fix(raw string, r Rules) string {
+	r = r.WithDefaults()
+	msg := Parse(Clean(raw))
+
+	switch r.Style {
+	case ConventionalStyle:
+		if msg.Type == "" {
+			// without a type to go on, fall back to the most neutral one
+			msg.Type = closestType("", r.Types)
+			msg.Subject = msg.Header
+		}
+		if msg.Type != "" {
+			bang := strings.Contains(msg.Header, "!:")
+			msg.Type = strings.ToLower(msg.Type)
+			if !slices.Contains(r.Types, msg.Type) {
+				msg.Type = closestType(msg.Type, r.Types)
+			}
+			msg.Subject = lowerFirst(strings.TrimSpace(msg.Subject))
+			msg.Header = msg.Type
+			if msg.Scope != "" {
+				msg.Header += "(" + msg.Scope + ")"
+			}
+			if bang {
+				msg.Header += "!"
+			}
+			msg.Header += ": " + msg.Subject
+		}
+	case GitmojiStyle:
+		if !gitmojiPrefix.MatchString(msg.Header) && !startsWithEmoji(msg.Header) {
+			emoji := ":sparkles:"
+			if msg.Type != "" {
+				if e, ok := gitmojis[strings.ToLower(msg.Type)]; ok {
+					emoji = e
+				}
+				msg.Header = msg.Subject
+			}
+			msg.Header = emoji + " " + msg.Header
+		}
+	}
+
+	msg.Header = strings.TrimRight(strings.TrimSpace(msg.Header), ".")
+	msg.Header = truncateWords(msg.Header, r.MaxSubjectLength)
+	msg.Body = wrap(msg.Body, r.BodyWrap)
+
+	return msg.String()
+}
+
+// closestType maps common synonyms onto an allowed type
+func closestType(t string, types []string) string {
+	synonyms := map[string]string{
+		"feature": "feat", "features": "feat", "add": "feat",
+		"bugfix": "fix", "hotfix": "fix", "bug": "fix",
+		"doc": "docs", "documentation": "docs",
+		"tests": "test", "testing": "test",
+		"refactoring": "refactor", "performance": "perf",
+	}
+	if mapped, ok := synonyms[t]; ok && slices.Contains(types, mapped) {
+		return mapped
+	}
+	for _, candidate := range types {
+		if t != "" && (strings.HasPrefix(t, candidate) || strings.HasPrefix(candidate, t)) {
+			return candidate
+		}
+	}
+	if slices.Contains(types, "chore") {
+		return "chore"
+	}
+	return t
+}
+
+// wrap re-flows paragraphs longer than width, leaving lists, indented blocks
+// and unbreakable lines alone
+func wrap(body string, width int) string {
+	if body == "" {
+		return body
+	}
+
+	var out []string
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if utf8.RuneCountInString(line) <= width || strings.HasPrefix(line, " ") || !strings.Contains(trimmed, " ") {
+			out = append(out, line)
+			continue
+		}
+
+		// keep list markers hanging
+		indent := ""
+		if m := listMarker.FindString(line); m != "" {
+			indent = strings.Repeat(" ", len(m))
+		}
+
+		current := ""
+		for _, word := range strings.Fields(line) {
+			if current != "" && utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > width {
+				out = append(out, current)
+				current = indent + word
+				continue
+			}
+			if current == "" || current == indent {
+				current += word
+			} else {
+				current += " " + word
+			}
+		}
+		out = append(out, current)
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// truncateWords shortens s to at most max runes at a word boundary
+func truncateWords(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	short := string([]rune(s)[:max])
+	if cut := strings.LastIndex(short, " "); cut > len(short)/2 {
+		return strings.TrimRight(short[:cut], " ,;:-")
+	}
+	return short
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max]) + "…"
+}
+
+// startsWithSentenceCase reports "Add x" but not acronyms such as "API x"
+func startsWithSentenceCase(s string) bool {
+	runes := []rune(strings.TrimSpace(s))
+	if len(runes) == 0 || !unicode.IsUpper(runes[0]) {
+		return false
+	}
+	return len(runes) == 1 || !unicode.IsUpper(runes[1])
+}
+
+func lowerFirst(s string) string {
+	if !startsWithSentenceCase(s) {
+		return s
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
+
+func startsWithEmoji(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r >= 0x2190 && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
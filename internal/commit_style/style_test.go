@@ -0,0 +1,109 @@
+package commitstyle
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want Message
+	}{
+		{
+			name: "header only",
+			raw:  "feat: add login",
+			want: Message{Header: "feat: add login", Type: "feat", Subject: "add login"},
+		},
+		{
+			name: "scope and breaking marker",
+			raw:  "fix(api)!: drop v1 routes",
+			want: Message{Header: "fix(api)!: drop v1 routes", Type: "fix", Scope: "api", Breaking: true, Subject: "drop v1 routes"},
+		},
+		{
+			name: "plain header",
+			raw:  "Update the readme",
+			want: Message{Header: "Update the readme", Subject: "Update the readme"},
+		},
+		{
+			name: "body",
+			raw:  "feat: add login\n\nFirst paragraph.\n\nSecond paragraph.",
+			want: Message{Header: "feat: add login", Type: "feat", Subject: "add login", Body: "First paragraph.\n\nSecond paragraph."},
+		},
+		{
+			name: "footers",
+			raw:  "fix: handle nil\n\nExplain why.\n\nRefs #12\nReviewed-by: Sam",
+			want: Message{Header: "fix: handle nil", Type: "fix", Subject: "handle nil", Body: "Explain why.",
+				Footers: []Footer{{Token: "Refs", Separator: " #", Value: "12"}, {Token: "Reviewed-by", Separator: ": ", Value: "Sam"}}},
+		},
+		{
+			name: "breaking change footer with continuation",
+			raw:  "refactor: rename keys\n\nBREAKING CHANGE: llm.key is now\n  llm.api_key",
+			want: Message{Header: "refactor: rename keys", Type: "refactor", Subject: "rename keys", Breaking: true,
+				Footers: []Footer{{Token: "BREAKING CHANGE", Separator: ": ", Value: "llm.key is now\n  llm.api_key"}}},
+		},
+		{
+			name: "last paragraph that is not a footer",
+			raw:  "docs: explain setup\n\nSee the wiki: it has more.\nSecond line.",
+			want: Message{Header: "docs: explain setup", Type: "docs", Subject: "explain setup", Body: "See the wiki: it has more.\nSecond line."},
+		},
+		{
+			name: "crlf and surrounding blank lines",
+			raw:  "\r\nchore: tidy\r\n\r\nBody.\r\n\r\n",
+			want: Message{Header: "chore: tidy", Type: "chore", Subject: "tidy", Body: "Body."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.raw)
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	conventional := Rules{Style: ConventionalStyle}
+
+	tests := []struct {
+		name  string
+		raw   string
+		rules Rules
+		want  []string
+	}{
+		{name: "valid conventional", raw: "feat(api): add pagination\n\nPages hold 50 items.", rules: conventional},
+		{name: "not conventional", raw: "Add pagination", rules: conventional, want: []string{"type"}},
+		{name: "unknown type", raw: "feature: add pagination", rules: conventional, want: []string{"type-enum"}},
+		{name: "custom types", raw: "feature: add pagination", rules: Rules{Style: ConventionalStyle, Types: []string{"feature"}}},
+		{name: "unknown scope", raw: "feat(web,api): add pagination", rules: Rules{Style: ConventionalStyle, Scopes: []string{"api"}}, want: []string{"scope-enum"}},
+		{name: "sentence case subject", raw: "feat: Add pagination", rules: conventional, want: []string{"subject-case"}},
+		{name: "full stop", raw: "feat: add pagination.", rules: conventional, want: []string{"subject-full-stop"}},
+		{name: "header too long", raw: "feat: " + strings.Repeat("a", 20), rules: Rules{Style: ConventionalStyle, MaxSubjectLength: 20}, want: []string{"header-max-length"}},
+		{name: "no blank line after header", raw: "feat: add pagination\nPages hold 50 items.", rules: conventional, want: []string{"body-leading-blank"}},
+		{name: "long body line", raw: "feat: add pagination\n\n" + strings.Repeat("word ", 20), rules: conventional, want: []string{"body-max-line-length"}},
+		{name: "long url in body", raw: "feat: add pagination\n\nhttps://example.com/" + strings.Repeat("a", 80), rules: conventional},
+		{name: "empty breaking change", raw: "feat: add pagination\n\nBREAKING CHANGE: \nRefs #12", rules: conventional, want: []string{"footer"}},
+		{name: "code fences", raw: "```\nfeat: add pagination\n```", rules: Rules{Style: PlainStyle}, want: []string{"format", "body-leading-blank"}},
+		{name: "empty", raw: "  \n", rules: conventional, want: []string{"header"}},
+		{name: "gitmoji code", raw: ":sparkles: add pagination", rules: Rules{Style: GitmojiStyle}},
+		{name: "gitmoji emoji", raw: "✨ add pagination", rules: Rules{Style: GitmojiStyle}},
+		{name: "missing gitmoji", raw: "add pagination", rules: Rules{Style: GitmojiStyle}, want: []string{"gitmoji"}},
+		{name: "plain", raw: "Add pagination", rules: Rules{Style: PlainStyle}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, violation := range Validate(tt.raw, tt.rules) {
+				got = append(got, violation.Rule)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() rules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
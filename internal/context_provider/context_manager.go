@@ -1,7 +1,9 @@
 package context_provider
 
 import (
+	"context"
 	"errors"
+	"fmt"
 
 	"git-genius/config"
 )
@@ -20,29 +22,21 @@ func NewContextManager(cfg *config.Config) *ContextManager {
 	return &ContextManager{Config: cfg}
 }
 
-func (cm *ContextManager) CollectContext() (*Context, error) {
+func (cm *ContextManager) CollectContext(ctx context.Context) (*Context, error) {
 	context := &Context{}
 
 	for _, contextProviderConfig := range cm.Config.ContextProviders {
 
-		var contextProvider ContextProvider
-		switch contextProviderConfig.Name {
-		case string(LinearContextProviderType):
-			contextProvider = &LinearContextProvider{
-				APIKey:  contextProviderConfig.APIKey,
-				IssueID: cm.Config.IssueID,
-			}
-		case string(GitContextProviderType):
-			contextProvider = &GitContextProvider{}
-		case string(PRTemplateContextProviderType):
-			contextProvider = &PRTemplateContextProvider{
-				FilePath: contextProviderConfig.Path,
-			}
-		default:
-			return nil, errors.New("unknown context_provider: " + contextProviderConfig.Name)
+		contextProvider, err := NewContextProvider(contextProviderConfig, cm.Config.IssueID)
+		if err != nil {
+			return nil, err
+		}
+		if git, ok := contextProvider.(*GitContextProvider); ok {
+			git.Pathspecs = cm.Config.Pathspecs
+			git.Amend = cm.Config.Amend
 		}
 
-		data, err := contextProvider.FetchContext()
+		data, err := contextProvider.FetchContext(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -62,3 +56,42 @@ func (cm *ContextManager) CollectContext() (*Context, error) {
 
 	return context, nil
 }
+
+// NewContextProvider creates the context provider described by the configuration
+func NewContextProvider(providerConfig config.ProviderConfig, issueID string) (ContextProvider, error) {
+	switch providerConfig.Name {
+	case string(LinearContextProviderType):
+		return &LinearContextProvider{
+			APIKey:  providerConfig.APIKey.Reveal(),
+			IssueID: issueID,
+		}, nil
+	case string(GitContextProviderType):
+		return &GitContextProvider{}, nil
+	case string(PRTemplateContextProviderType):
+		return &PRTemplateContextProvider{
+			FilePath: providerConfig.Path,
+		}, nil
+	default:
+		return nil, errors.New("unknown context_provider: " + providerConfig.Name)
+	}
+}
+
+// FetchIssue looks an issue up in the configured issue tracker, nil when no
+// tracker is configured
+func (cm *ContextManager) FetchIssue(ctx context.Context, issueID string) (*LinearContext, error) {
+	for _, providerConfig := range cm.Config.ContextProviders {
+		if providerConfig.Name != string(LinearContextProviderType) {
+			continue
+		}
+		contextProvider, err := NewContextProvider(providerConfig, issueID)
+		if err != nil {
+			return nil, err
+		}
+		data, err := contextProvider.FetchContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch issue %s: %w", issueID, err)
+		}
+		return data.(*LinearContext), nil
+	}
+	return nil, nil
+}
@@ -3,11 +3,22 @@ package context_provider
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os/exec"
+	"strings"
 )
 
-type GitContextProvider struct{}
+type GitContextProvider struct {
+	// Pathspecs limits the diff to matching paths
+	Pathspecs []string
+	// Amend diffs against the parent of HEAD, as the commit replaces HEAD
+	Amend bool
+}
+
+// emptyTree is the hash of git's empty tree, the base of a root commit
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
 
 type GitContext struct {
 	Diff            string   // The diff of changes
@@ -19,21 +30,21 @@ func (gc *GitContext) IsEmpty() bool {
 	return gc.Diff == "" && len(gc.NewFiles) == 0 && len(gc.PreviousMessage) == 0
 }
 
-func (gp *GitContextProvider) FetchContext() (ProvidedContext, error) {
+func (gp *GitContextProvider) FetchContext(ctx context.Context) (ProvidedContext, error) {
 	// Fetch the diff
-	diff, err := gp.getDiff()
+	diff, err := gp.getDiff(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch Git diff: %w", err)
 	}
 
 	// Fetch the new files
-	newFiles, err := gp.getNewFiles()
+	newFiles, err := gp.getNewFiles(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch new files: %w", err)
 	}
 
 	// Fetch the previous commit messages
-	previousMessages, err := gp.getPreviousMessages()
+	previousMessages, err := gp.getPreviousMessages(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch previous commit messages: %w", err)
 	}
@@ -45,8 +56,29 @@ func (gp *GitContextProvider) FetchContext() (ProvidedContext, error) {
 	}, nil
 }
 
-func (gp *GitContextProvider) getDiff() (string, error) {
-	cmd := exec.Command("git", "diff", "--staged")
+// RepoRoot returns the top level directory of the current repository
+func RepoRoot(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("not inside a git repository: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (gp *GitContextProvider) getDiff(ctx context.Context) (string, error) {
+	args := []string{"diff", "--staged"}
+	if gp.Amend {
+		base := emptyTree
+		if out, err := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", "HEAD^").Output(); err == nil {
+			base = strings.TrimSpace(string(out))
+		}
+		args = append(args, base)
+	}
+	if len(gp.Pathspecs) > 0 {
+		args = append(append(args, "--"), gp.Pathspecs...)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
 	out, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch diff: %w", err)
@@ -54,8 +86,12 @@ func (gp *GitContextProvider) getDiff() (string, error) {
 	return string(out), nil
 }
 
-func (gp *GitContextProvider) getNewFiles() ([]string, error) {
-	cmd := exec.Command("git", "status", "--porcelain")
+func (gp *GitContextProvider) getNewFiles(ctx context.Context) ([]string, error) {
+	args := []string{"status", "--porcelain"}
+	if len(gp.Pathspecs) > 0 {
+		args = append(append(args, "--"), gp.Pathspecs...)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
 	out, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch new files: %w", err)
@@ -78,11 +114,16 @@ func (gp *GitContextProvider) getNewFiles() ([]string, error) {
 	return newFiles, nil
 }
 
-func (gp *GitContextProvider) getPreviousMessages() ([]string, error) {
-	cmd := exec.Command("git", "log", "-n", "20", "--pretty=format:%s")
+func (gp *GitContextProvider) getPreviousMessages(ctx context.Context) ([]string, error) {
+	// the first commit of a repository has no history yet
+	if err := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", "HEAD").Run(); err != nil {
+		return nil, ctx.Err()
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "log", "-n", "20", "--pretty=format:%s")
 	out, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch previous commit messages: %w", err)
+		return nil, fmt.Errorf("failed to run git log: %w", gitStderr(err))
 	}
 
 	// Parse commit messages into a slice
@@ -97,3 +138,200 @@ func (gp *GitContextProvider) getPreviousMessages() ([]string, error) {
 
 	return messages, nil
 }
+
+// Commit is a commit read from the history
+type Commit struct {
+	Hash    string
+	Parents []string
+	Author  string
+	// Date is the author date as YYYY-MM-DD
+	Date    string
+	Subject string
+	// Message is the full commit message
+	Message string
+}
+
+// IsMerge reports whether the commit has more than one parent
+func (c Commit) IsMerge() bool {
+	return len(c.Parents) > 1
+}
+
+// ShortHash returns the abbreviated hash
+func (c Commit) ShortHash() string {
+	if len(c.Hash) > 7 {
+		return c.Hash[:7]
+	}
+	return c.Hash
+}
+
+// Log returns the commits selected by the git log arguments, e.g. a
+// "main..HEAD" range, oldest first
+func Log(ctx context.Context, args ...string) ([]Commit, error) {
+	// fields are separated by NUL and commits by RS, neither appears in messages
+	gitArgs := append([]string{"log", "--reverse", "--format=%H%x00%P%x00%an <%ae>%x00%as%x00%s%x00%B%x1e"}, args...)
+	out, err := exec.CommandContext(ctx, "git", gitArgs...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commits: %w", gitStderr(err))
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(string(out), "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		fields := strings.SplitN(record, "\x00", 6)
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("failed to parse git log output")
+		}
+		commits = append(commits, Commit{
+			Hash:    fields[0],
+			Parents: strings.Fields(fields[1]),
+			Author:  fields[2],
+			Date:    fields[3],
+			Subject: fields[4],
+			Message: strings.TrimSpace(fields[5]),
+		})
+	}
+	return commits, nil
+}
+
+// CommitContext returns the git context of an existing commit: its own diff,
+// the files it added and the messages of the commits before it
+func CommitContext(ctx context.Context, commit Commit) (*GitContext, error) {
+	out, err := exec.CommandContext(ctx, "git", "show", "--format=", "--patch", commit.Hash).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch diff of %s: %w", commit.ShortHash(), gitStderr(err))
+	}
+	diff := string(out)
+
+	out, err = exec.CommandContext(ctx, "git", "show", "--format=", "--name-only", "--diff-filter=A", commit.Hash).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch new files of %s: %w", commit.ShortHash(), gitStderr(err))
+	}
+	newFiles := strings.Fields(string(out))
+
+	var previousMessages []string
+	if len(commit.Parents) > 0 {
+		out, err = exec.CommandContext(ctx, "git", "log", "-n", "20", "--pretty=format:%s", commit.Parents[0]).Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch previous commit messages: %w", gitStderr(err))
+		}
+		previousMessages = strings.Split(strings.TrimSpace(string(out)), "\n")
+	}
+
+	return &GitContext{
+		Diff:            diff,
+		NewFiles:        newFiles,
+		PreviousMessage: previousMessages,
+	}, nil
+}
+
+// gitStderr adds what git printed on stderr to a failed command's error
+func gitStderr(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err
+}
+
+// MergeBase returns the best common ancestor of two revisions
+func MergeBase(ctx context.Context, a, b string) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", "merge-base", a, b).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the merge base of %s and %s: %w", a, b, gitStderr(err))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Diff returns the changes between two revisions
+func Diff(ctx context.Context, from, to string) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", "diff", from, to).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch diff between %s and %s: %w", from, to, gitStderr(err))
+	}
+	return string(out), nil
+}
+
+// StagedPatch returns the staged changes, binary files included, as a patch
+// git apply accepts back regardless of the user's diff settings
+func StagedPatch(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", "diff", "--cached", "--binary", "--no-color", "--no-ext-diff",
+		"--src-prefix=a/", "--dst-prefix=b/").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch staged diff: %w", gitStderr(err))
+	}
+	return string(out), nil
+}
+
+// ReviewDiff returns the changes between two revisions, or the staged changes
+// when from is empty, with contextLines lines of unchanged code around each
+// hunk. Renames are detected and the output parses with the patch package
+// regardless of the user's diff settings.
+func ReviewDiff(ctx context.Context, from, to string, contextLines int) (string, error) {
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--find-renames",
+		"--src-prefix=a/", "--dst-prefix=b/", fmt.Sprintf("--unified=%d", contextLines)}
+	if from == "" {
+		args = append(args, "--cached")
+	} else {
+		args = append(args, from, to)
+	}
+	out, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch diff: %w", gitStderr(err))
+	}
+	return string(out), nil
+}
+
+// Show returns the changes of a commit, limited to paths when given. A merge
+// is diffed against its first parent, the combined diff of a clean merge is
+// empty.
+func Show(ctx context.Context, rev string, paths ...string) (string, error) {
+	args := append([]string{"show", "--format=", "--patch", "--diff-merges=first-parent", "--no-color",
+		"--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/", rev, "--"}, paths...)
+	out, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch diff of %s: %w", rev, gitStderr(err))
+	}
+	return string(out), nil
+}
+
+// RangeDiff returns the changes of a "<from>..<to>" or "<from>...<to>" range
+func RangeDiff(ctx context.Context, revRange string) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", "diff", "--no-color", "--no-ext-diff",
+		"--src-prefix=a/", "--dst-prefix=b/", revRange, "--").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch diff of %s: %w", revRange, gitStderr(err))
+	}
+	return string(out), nil
+}
+
+// FileHistory returns the n latest commits touching path up to rev, newest
+// first, as LogSummary lines
+func FileHistory(ctx context.Context, path, rev string, n int) ([]string, error) {
+	lines, err := LogSummary(ctx, fmt.Sprintf("--max-count=%d", n), rev, "--", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the history of %s: %w", path, err)
+	}
+	return lines, nil
+}
+
+// LogSummaryFormat is the git log format of LogSummary
+const LogSummaryFormat = "--format=%h %as %an: %s"
+
+// LogSummary returns one "<hash> <date> <author>: <subject>" line per commit
+// selected by the git log arguments, newest first
+func LogSummary(ctx context.Context, args ...string) ([]string, error) {
+	out, err := exec.CommandContext(ctx, "git", append([]string{"log", LogSummaryFormat}, args...)...).Output()
+	if err != nil {
+		return nil, gitStderr(err)
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
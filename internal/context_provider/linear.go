@@ -2,12 +2,17 @@ package context_provider
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"regexp"
 )
 
+// LinearIssuePattern matches Linear issue identifiers such as ENG-123
+var LinearIssuePattern = regexp.MustCompile(`\b[A-Z][A-Z0-9]{1,9}-[0-9]+\b`)
+
 type LinearContextProvider struct {
 	APIKey  string
 	IssueID string
@@ -22,7 +27,7 @@ func (lc *LinearContext) IsEmpty() bool {
 	return lc.Title == "" && lc.Description == ""
 }
 
-func (lp *LinearContextProvider) FetchContext() (ProvidedContext, error) {
+func (lp *LinearContextProvider) FetchContext(ctx context.Context) (ProvidedContext, error) {
 	if lp.IssueID == "" {
 		return nil, fmt.Errorf("issueID cannot be empty")
 	}
@@ -34,41 +39,67 @@ func (lp *LinearContextProvider) FetchContext() (ProvidedContext, error) {
 			description
 		}
 	}`
+	var result struct {
+		Data struct {
+			Issue LinearContext `json:"issue"`
+		} `json:"data"`
+	}
+
+	if err := lp.graphql(ctx, query, map[string]string{"id": lp.IssueID}, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Data.Issue.Title == "" {
+		return nil, fmt.Errorf("issue not found")
+	}
+
+	return &result.Data.Issue, nil
+}
+
+// Ping checks that the API key is valid
+func (lp *LinearContextProvider) Ping(ctx context.Context) error {
+	var result struct {
+		Data struct {
+			Viewer struct {
+				ID string `json:"id"`
+			} `json:"viewer"`
+		} `json:"data"`
+	}
+
+	if err := lp.graphql(ctx, `query { viewer { id } }`, nil, &result); err != nil {
+		return err
+	}
+	if result.Data.Viewer.ID == "" {
+		return fmt.Errorf("linear api did not return the authenticated user")
+	}
+	return nil
+}
+
+// graphql sends a query to the Linear API and decodes the response into result
+func (lp *LinearContextProvider) graphql(ctx context.Context, query string, variables map[string]string, result interface{}) error {
 	reqBody, _ := json.Marshal(map[string]interface{}{
-		"query": query,
-		"variables": map[string]string{
-			"id": lp.IssueID,
-		},
+		"query":     query,
+		"variables": variables,
 	})
-	req, _ := http.NewRequest("POST", "https://api.linear.app/graphql", bytes.NewBuffer(reqBody))
+	req, _ := http.NewRequestWithContext(ctx, "POST", "https://api.linear.app/graphql", bytes.NewBuffer(reqBody))
 	req.Header.Set("Authorization", lp.APIKey)
 	req.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to contact Linear API: %w", err)
+		return fmt.Errorf("failed to contact Linear API: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("linear api responded with status: %d, message: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	var result struct {
-		Data struct {
-			Issue LinearContext `json:"issue"`
-		} `json:"data"`
+		return fmt.Errorf("linear api responded with status: %d, message: %s", resp.StatusCode, string(bodyBytes))
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode Linear response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode Linear response: %w", err)
 	}
 
-	if result.Data.Issue.Title == "" {
-		return nil, fmt.Errorf("issue not found")
-	}
-
-	return &result.Data.Issue, nil
+	return nil
 }
@@ -1,8 +1,10 @@
 package context_provider
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 )
 
 type PRTemplateContextProvider struct {
@@ -17,7 +19,7 @@ func (pt *PRTemplateContext) IsEmpty() bool {
 	return pt.Template == ""
 }
 
-func (pt *PRTemplateContextProvider) FetchContext() (ProvidedContext, error) {
+func (pt *PRTemplateContextProvider) FetchContext(ctx context.Context) (ProvidedContext, error) {
 	if pt.FilePath == "" {
 		return nil, fmt.Errorf("PR template path is not configured")
 	}
@@ -29,3 +31,30 @@ func (pt *PRTemplateContextProvider) FetchContext() (ProvidedContext, error) {
 
 	return &PRTemplateContext{Template: string(content)}, nil
 }
+
+// prTemplateLocations are the paths GitHub and GitLab look for PR templates in
+var prTemplateLocations = []string{
+	".github/pull_request_template.md",
+	".github/PULL_REQUEST_TEMPLATE.md",
+	"pull_request_template.md",
+	"PULL_REQUEST_TEMPLATE.md",
+	"docs/pull_request_template.md",
+	"docs/PULL_REQUEST_TEMPLATE.md",
+	".github/PULL_REQUEST_TEMPLATE/*.md",
+	".gitlab/merge_request_templates/*.md",
+}
+
+// DetectPRTemplates returns the PR templates found in the repository at root,
+// relative to root
+func DetectPRTemplates(root string) []string {
+	var found []string
+	for _, location := range prTemplateLocations {
+		matches, _ := filepath.Glob(filepath.Join(root, location))
+		for _, match := range matches {
+			if rel, err := filepath.Rel(root, match); err == nil {
+				found = append(found, rel)
+			}
+		}
+	}
+	return found
+}
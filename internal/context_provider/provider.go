@@ -1,5 +1,7 @@
 package context_provider
 
+import "context"
+
 type ContextProviderType string
 
 const (
@@ -9,9 +11,15 @@ const (
 )
 
 type ContextProvider interface {
-	FetchContext() (ProvidedContext, error)
+	FetchContext(ctx context.Context) (ProvidedContext, error)
 }
 
 type ProvidedContext interface {
 	IsEmpty() bool
 }
+
+// Pinger is implemented by context providers backed by a remote service
+type Pinger interface {
+	// Ping checks that the service is reachable and the credentials are valid
+	Ping(ctx context.Context) error
+}
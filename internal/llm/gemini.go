@@ -2,45 +2,166 @@ package llms
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	genai "github.com/google/generative-ai-go/genai"
 	"google.golang.org/api/option"
 )
 
+const defaultGeminiModel = "gemini-1.5-flash"
+
+// GeminiModels lists the models accepted for llm.model
+var GeminiModels = []string{
+	"gemini-1.5-flash",
+	"gemini-1.5-flash-8b",
+	"gemini-1.5-pro",
+	"gemini-2.0-flash",
+	"gemini-2.0-flash-lite",
+	"gemini-2.5-flash",
+	"gemini-2.5-pro",
+}
+
 type Gemini struct {
 	Client *genai.Client
+	model  string
+	apiKey string
 }
 
-func NewGemini(ctx context.Context, apiKey string) (*Gemini, error) {
+// NewGemini creates a Gemini client, model defaults to gemini-1.5-flash
+func NewGemini(ctx context.Context, apiKey, model string) (*Gemini, error) {
 	client, err := genai.NewClient(ctx, option.WithAPIKey((apiKey)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
 	}
-	return &Gemini{Client: client}, nil
+	if model == "" {
+		model = defaultGeminiModel
+	}
+	return &Gemini{Client: client, model: model, apiKey: apiKey}, nil
 }
 
-func (g *Gemini) GenerateResponse(ctx context.Context, prompt string, maxTokens int) (string, error) {
-	model := g.Client.GenerativeModel("gemini-1.5-flash")
-	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+func (g *Gemini) GenerateResponse(ctx context.Context, prompt string, maxTokens int, opts ...Option) (string, error) {
+	resp, err := g.generateContent(ctx, prompt, 1, NewOptions(opts...))
 	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %w", err)
+		return "", err
 	}
 
 	// select the first candidate
 	if len(resp.Candidates) == 0 {
 		return "", nil
 	}
+	return candidateText(resp.Candidates[0]), nil
+}
+
+// GenerateCandidates returns up to n responses from a single request using
+// Gemini's candidate count. Models may return fewer candidates than asked for.
+func (g *Gemini) GenerateCandidates(ctx context.Context, prompt string, maxTokens, n int, opts ...Option) ([]string, error) {
+	resp, err := g.generateContent(ctx, prompt, n, NewOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []string
+	for _, candidate := range resp.Candidates {
+		if text := candidateText(candidate); text != "" {
+			responses = append(responses, text)
+		}
+	}
+	return responses, nil
+}
 
-	candidate := resp.Candidates[0]
+func (g *Gemini) generateContent(ctx context.Context, prompt string, candidates int, options *Options) (*genai.GenerateContentResponse, error) {
+	model := g.Client.GenerativeModel(g.model)
+	if options.JSONSchema != nil {
+		model.ResponseMIMEType = "application/json"
+		model.ResponseSchema = toGeminiSchema(options.JSONSchema)
+	}
+	if options.SystemInstruction != "" {
+		model.SystemInstruction = genai.NewUserContent(genai.Text(options.SystemInstruction))
+	}
+	if candidates > 1 {
+		model.SetCandidateCount(int32(candidates))
+	}
+
+	var resp *genai.GenerateContentResponse
+	var err error
+	if len(options.History) > 0 {
+		chat := model.StartChat()
+		for _, message := range options.History {
+			chat.History = append(chat.History, &genai.Content{
+				Role:  message.Role,
+				Parts: []genai.Part{genai.Text(message.Content)},
+			})
+		}
+		resp, err = chat.SendMessage(ctx, genai.Text(prompt))
+	} else {
+		resp, err = model.GenerateContent(ctx, genai.Text(prompt))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate content: %w", g.redact(err))
+	}
+	return resp, nil
+}
+
+func candidateText(candidate *genai.Candidate) string {
 	if candidate.Content == nil {
-		return "", nil
+		return ""
 	}
 
 	var content string
 	for _, part := range candidate.Content.Parts {
 		content += fmt.Sprint(part)
 	}
+	return content
+}
+
+// Ping checks that the API key can access the model
+func (g *Gemini) Ping(ctx context.Context) error {
+	if _, err := g.Client.GenerativeModel(g.model).Info(ctx); err != nil {
+		return fmt.Errorf("failed to reach Gemini: %w", g.redact(err))
+	}
+	return nil
+}
+
+// toGeminiSchema converts a schema to Gemini's response schema
+func toGeminiSchema(s *Schema) *genai.Schema {
+	if s == nil {
+		return nil
+	}
 
-	return content, nil
+	types := map[SchemaType]genai.Type{
+		TypeObject:  genai.TypeObject,
+		TypeArray:   genai.TypeArray,
+		TypeString:  genai.TypeString,
+		TypeInteger: genai.TypeInteger,
+		TypeNumber:  genai.TypeNumber,
+		TypeBoolean: genai.TypeBoolean,
+	}
+
+	schema := &genai.Schema{
+		Type:        types[s.Type],
+		Description: s.Description,
+		Enum:        s.Enum,
+		Required:    s.Required,
+		Items:       toGeminiSchema(s.Items),
+	}
+	if len(s.Enum) > 0 {
+		schema.Format = "enum"
+	}
+	if len(s.Properties) > 0 {
+		schema.Properties = map[string]*genai.Schema{}
+		for name, property := range s.Properties {
+			schema.Properties[name] = toGeminiSchema(property)
+		}
+	}
+	return schema
+}
+
+// redact removes the API key from errors, the client puts it in request URLs
+func (g *Gemini) redact(err error) error {
+	if g.apiKey == "" || !strings.Contains(err.Error(), g.apiKey) {
+		return err
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), g.apiKey, "********"))
 }
@@ -3,5 +3,69 @@ package llms
 import "context"
 
 type LLM interface {
-	GenerateResponse(ctx context.Context, prompt string, maxTokens int) (string, error)
+	GenerateResponse(ctx context.Context, prompt string, maxTokens int, opts ...Option) (string, error)
+	// Ping checks that the backend is reachable and the credentials are valid
+	Ping(ctx context.Context) error
+}
+
+// CandidateGenerator is implemented by backends that can return several
+// alternative responses to one request
+type CandidateGenerator interface {
+	GenerateCandidates(ctx context.Context, prompt string, maxTokens, n int, opts ...Option) ([]string, error)
+}
+
+// Options tune a single request. Backends ignore the options they don't
+// support, so callers must not rely on them being honoured.
+type Options struct {
+	// JSONSchema asks for a JSON response matching the schema
+	JSONSchema *Schema
+	// SystemInstruction steers the model for the whole request
+	SystemInstruction string
+	// History holds earlier turns of the conversation, oldest first
+	History []Message
+}
+
+// roles of a conversation turn
+const (
+	RoleUser  = "user"
+	RoleModel = "model"
+)
+
+// Message is one turn of a conversation
+type Message struct {
+	Role    string
+	Content string
+}
+
+type Option func(*Options)
+
+// WithJSONSchema requests schema-constrained JSON output where the backend
+// supports it
+func WithJSONSchema(schema *Schema) Option {
+	return func(o *Options) {
+		o.JSONSchema = schema
+	}
+}
+
+// WithSystemInstruction sets the system instruction of the request
+func WithSystemInstruction(instruction string) Option {
+	return func(o *Options) {
+		o.SystemInstruction = instruction
+	}
+}
+
+// WithHistory sends the prompt as the next turn of a conversation
+func WithHistory(history []Message) Option {
+	return func(o *Options) {
+		o.History = history
+	}
+}
+
+// NewOptions applies opts in order
+func NewOptions(opts ...Option) *Options {
+	o := &Options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
 }
@@ -0,0 +1,22 @@
+package llms
+
+type SchemaType string
+
+const (
+	TypeObject  SchemaType = "object"
+	TypeArray   SchemaType = "array"
+	TypeString  SchemaType = "string"
+	TypeInteger SchemaType = "integer"
+	TypeNumber  SchemaType = "number"
+	TypeBoolean SchemaType = "boolean"
+)
+
+// Schema is a backend-agnostic subset of JSON Schema describing structured output
+type Schema struct {
+	Type        SchemaType
+	Description string
+	Properties  map[string]*Schema
+	Required    []string
+	Items       *Schema
+	Enum        []string
+}
@@ -0,0 +1,268 @@
+package patch
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// File is the part of a git diff that changes one file
+type File struct {
+	// Header holds the lines before the first hunk, from "diff --git" on,
+	// including the whole patch of binary files
+	Header  []string
+	OldPath string
+	NewPath string
+	Hunks   []*Hunk
+
+	New     bool
+	Deleted bool
+	Renamed bool
+	Binary  bool
+}
+
+// Hunk is a "@@ -a,b +c,d @@" section of a file's diff
+type Hunk struct {
+	Header   string
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	// Lines are the context, added, removed and "\ No newline" lines
+	Lines []string
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// Parse splits the output of git diff into files and hunks
+func Parse(diff string) ([]*File, error) {
+	var files []*File
+	var file *File
+	var hunk *Hunk
+
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	if diff == "" {
+		lines = nil
+	}
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			file = &File{Header: []string{line}}
+			file.OldPath, file.NewPath = gitPaths(line)
+			files = append(files, file)
+			hunk = nil
+
+		case file == nil:
+			return nil, fmt.Errorf("line %d: expected a \"diff --git\" line", i+1)
+
+		case strings.HasPrefix(line, "@@ ") && !file.Binary:
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: malformed hunk header %q", i+1, line)
+			}
+			hunk = &Hunk{
+				Header:   line,
+				OldStart: atoi(m[1]),
+				OldLines: count(m[2]),
+				NewStart: atoi(m[3]),
+				NewLines: count(m[4]),
+			}
+			file.Hunks = append(file.Hunks, hunk)
+
+		case hunk != nil:
+			if line != "" && !strings.ContainsRune(" +-\\", rune(line[0])) {
+				return nil, fmt.Errorf("line %d: unexpected line in hunk of %s", i+1, file.Path())
+			}
+			hunk.Lines = append(hunk.Lines, line)
+
+		default:
+			file.Header = append(file.Header, line)
+			switch {
+			case strings.HasPrefix(line, "--- ") && line != "--- /dev/null":
+				file.OldPath = stripPrefix(line[4:])
+			case strings.HasPrefix(line, "+++ ") && line != "+++ /dev/null":
+				file.NewPath = stripPrefix(line[4:])
+			case strings.HasPrefix(line, "new file mode"):
+				file.New = true
+			case strings.HasPrefix(line, "deleted file mode"):
+				file.Deleted = true
+			case strings.HasPrefix(line, "rename from "):
+				file.Renamed = true
+				file.OldPath = strings.TrimPrefix(line, "rename from ")
+			case strings.HasPrefix(line, "rename to "):
+				file.NewPath = strings.TrimPrefix(line, "rename to ")
+			case line == "GIT binary patch" || strings.HasPrefix(line, "Binary files "):
+				file.Binary = true
+			}
+		}
+	}
+	return files, nil
+}
+
+// ParseHunks reads the hunks of one file's diff without the git header, as
+// the GitHub and GitLab APIs return them
+func ParseHunks(diff string) ([]*Hunk, error) {
+	if diff == "" {
+		return nil, nil
+	}
+	files, err := Parse("diff --git a/file b/file\n" + diff)
+	if err != nil {
+		return nil, err
+	}
+	return files[0].Hunks, nil
+}
+
+// Path returns the path of the file after the change, or before it for
+// deletions
+func (f *File) Path() string {
+	if f.Deleted {
+		return f.OldPath
+	}
+	return f.NewPath
+}
+
+// Splittable reports whether the hunks only change content, so that each can
+// be applied on its own. Creations, deletions, renames, mode changes and
+// binary files have to be applied as a whole.
+func (f *File) Splittable() bool {
+	if f.Binary || len(f.Hunks) == 0 {
+		return false
+	}
+	for _, line := range f.Header[1:] {
+		if !strings.HasPrefix(line, "index ") && !strings.HasPrefix(line, "--- ") && !strings.HasPrefix(line, "+++ ") {
+			return false
+		}
+	}
+	return true
+}
+
+// Patch renders the file's header and the given hunks, in diff order, as a
+// patch git apply accepts
+func (f *File) Patch(hunks []*Hunk) string {
+	selected := map[*Hunk]bool{}
+	for _, hunk := range hunks {
+		selected[hunk] = true
+	}
+
+	var b strings.Builder
+	for _, line := range f.Header {
+		b.WriteString(line + "\n")
+	}
+	for _, hunk := range f.Hunks {
+		if selected[hunk] {
+			b.WriteString(hunk.String())
+		}
+	}
+	return b.String()
+}
+
+// String renders the whole diff of the file
+func (f *File) String() string {
+	return f.Patch(f.Hunks)
+}
+
+// String renders the hunk's header and lines
+func (h *Hunk) String() string {
+	var b strings.Builder
+	b.WriteString(h.Header + "\n")
+	for _, line := range h.Lines {
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// Stats counts the added and removed lines
+func (h *Hunk) Stats() (added, removed int) {
+	for _, line := range h.Lines {
+		if strings.HasPrefix(line, "+") {
+			added++
+		} else if strings.HasPrefix(line, "-") {
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// Location is where a line of the new version of a file appears in its diff
+type Location struct {
+	// Position counts the lines from the first hunk header, as GitHub's
+	// review API does: the line below it is 1 and later hunk headers count
+	Position int
+	// OldLine is the line in the old version for unchanged lines, 0 for
+	// added ones
+	OldLine int
+	// Text is the content of the line, without the diff prefix
+	Text string
+}
+
+// Locate finds line of the new version in the diff, false when no hunk
+// shows it
+func (f *File) Locate(line int) (Location, bool) {
+	position := 0
+	for i, hunk := range f.Hunks {
+		if i > 0 {
+			position++
+		}
+		oldLine, newLine := hunk.OldStart, hunk.NewStart
+		for _, text := range hunk.Lines {
+			position++
+			switch {
+			case strings.HasPrefix(text, "\\"):
+			case strings.HasPrefix(text, "-"):
+				oldLine++
+			case strings.HasPrefix(text, "+"):
+				if newLine == line {
+					return Location{Position: position, Text: text[1:]}, true
+				}
+				newLine++
+			default:
+				if newLine == line {
+					return Location{Position: position, OldLine: oldLine, Text: strings.TrimPrefix(text, " ")}, true
+				}
+				oldLine++
+				newLine++
+			}
+		}
+	}
+	return Location{}, false
+}
+
+// gitPaths reads the paths of a "diff --git a/x b/x" line, the ---/+++ lines
+// override them when present
+func gitPaths(line string) (string, string) {
+	paths := strings.TrimPrefix(line, "diff --git ")
+	// without a rename both halves are the same path
+	if half := len(paths) / 2; len(paths)%2 == 1 && paths[half] == ' ' {
+		a, b := stripPrefix(paths[:half]), stripPrefix(paths[half+1:])
+		if a == b {
+			return a, b
+		}
+	}
+	if i := strings.LastIndex(paths, " b/"); i >= 0 {
+		return stripPrefix(paths[:i]), stripPrefix(paths[i+1:])
+	}
+	return paths, paths
+}
+
+// stripPrefix removes the a/ or b/ of a diff path
+func stripPrefix(path string) string {
+	path = strings.TrimSuffix(path, "\t")
+	if strings.HasPrefix(path, "a/") || strings.HasPrefix(path, "b/") {
+		return path[2:]
+	}
+	return path
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// count reads a hunk line count, which git leaves out when it is 1
+func count(s string) int {
+	if s == "" {
+		return 1
+	}
+	return atoi(s)
+}
@@ -0,0 +1,196 @@
+package patch
+
+import (
+	"strings"
+	"testing"
+)
+
+const modifiedDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ package main
++
+ import "fmt"
+ func main() {}
+@@ -10,2 +11,2 @@ func helper() {
+-	return 1
++	return 2
+ }
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		diff    string
+		want    []File
+		hunks   []int
+		wantErr string
+	}{
+		{
+			name: "empty",
+			diff: "",
+		},
+		{
+			name:  "modified",
+			diff:  modifiedDiff,
+			want:  []File{{OldPath: "main.go", NewPath: "main.go"}},
+			hunks: []int{2},
+		},
+		{
+			name:  "new file",
+			diff:  "diff --git a/new.txt b/new.txt\nnew file mode 100644\nindex 0000000..3333333\n--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1 @@\n+hello\n",
+			want:  []File{{OldPath: "new.txt", NewPath: "new.txt", New: true}},
+			hunks: []int{1},
+		},
+		{
+			name:  "deleted file",
+			diff:  "diff --git a/old.txt b/old.txt\ndeleted file mode 100644\nindex 3333333..0000000\n--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n",
+			want:  []File{{OldPath: "old.txt", NewPath: "old.txt", Deleted: true}},
+			hunks: []int{1},
+		},
+		{
+			name:  "rename",
+			diff:  "diff --git a/a dir/x.go b/b dir/x.go\nsimilarity index 100%\nrename from a dir/x.go\nrename to b dir/x.go\n",
+			want:  []File{{OldPath: "a dir/x.go", NewPath: "b dir/x.go", Renamed: true}},
+			hunks: []int{0},
+		},
+		{
+			name:  "binary",
+			diff:  "diff --git a/logo.png b/logo.png\nindex 4444444..5555555 100644\nBinary files a/logo.png and b/logo.png differ\n",
+			want:  []File{{OldPath: "logo.png", NewPath: "logo.png", Binary: true}},
+			hunks: []int{0},
+		},
+		{
+			name:  "two files",
+			diff:  modifiedDiff + "diff --git a/new.txt b/new.txt\nnew file mode 100644\n--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1 @@\n+hello\n",
+			want:  []File{{OldPath: "main.go", NewPath: "main.go"}, {OldPath: "new.txt", NewPath: "new.txt", New: true}},
+			hunks: []int{2, 1},
+		},
+		{
+			name:    "no diff header",
+			diff:    "--- a/main.go\n",
+			wantErr: `line 1: expected a "diff --git" line`,
+		},
+		{
+			name:    "malformed hunk header",
+			diff:    "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -x +1 @@\n",
+			wantErr: "line 4: malformed hunk header",
+		},
+		{
+			name:    "unexpected hunk line",
+			diff:    "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n*oops\n",
+			wantErr: "line 5: unexpected line in hunk of main.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := Parse(tt.diff)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(files) != len(tt.want) {
+				t.Fatalf("Parse() returned %d files, want %d", len(files), len(tt.want))
+			}
+			for i, file := range files {
+				want := tt.want[i]
+				if file.OldPath != want.OldPath || file.NewPath != want.NewPath {
+					t.Errorf("file %d paths = %q, %q, want %q, %q", i, file.OldPath, file.NewPath, want.OldPath, want.NewPath)
+				}
+				if file.New != want.New || file.Deleted != want.Deleted || file.Renamed != want.Renamed || file.Binary != want.Binary {
+					t.Errorf("file %d flags = new %v deleted %v renamed %v binary %v, want %v %v %v %v", i,
+						file.New, file.Deleted, file.Renamed, file.Binary, want.New, want.Deleted, want.Renamed, want.Binary)
+				}
+				if len(file.Hunks) != tt.hunks[i] {
+					t.Errorf("file %d has %d hunks, want %d", i, len(file.Hunks), tt.hunks[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseHunkHeader(t *testing.T) {
+	files, err := Parse(modifiedDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hunk := files[0].Hunks[1]
+	if hunk.OldStart != 10 || hunk.OldLines != 2 || hunk.NewStart != 11 || hunk.NewLines != 2 {
+		t.Errorf("hunk = -%d,%d +%d,%d, want -10,2 +11,2", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+	}
+	if added, removed := hunk.Stats(); added != 1 || removed != 1 {
+		t.Errorf("Stats() = %d, %d, want 1, 1", added, removed)
+	}
+}
+
+func TestFilePatch(t *testing.T) {
+	files, err := Parse(modifiedDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := files[0]
+	header := "diff --git a/main.go b/main.go\nindex 1111111..2222222 100644\n--- a/main.go\n+++ b/main.go\n"
+	first := "@@ -1,3 +1,4 @@\n package main\n+\n import \"fmt\"\n func main() {}\n"
+	second := "@@ -10,2 +11,2 @@ func helper() {\n-\treturn 1\n+\treturn 2\n }\n"
+
+	tests := []struct {
+		name  string
+		hunks []*Hunk
+		want  string
+	}{
+		{name: "all hunks", hunks: file.Hunks, want: modifiedDiff},
+		{name: "first hunk", hunks: file.Hunks[:1], want: header + first},
+		{name: "second hunk", hunks: file.Hunks[1:], want: header + second},
+		{name: "diff order", hunks: []*Hunk{file.Hunks[1], file.Hunks[0]}, want: modifiedDiff},
+		{name: "no hunks", hunks: nil, want: header},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := file.Patch(tt.hunks); got != tt.want {
+				t.Errorf("Patch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocate(t *testing.T) {
+	files, err := Parse(modifiedDiff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := files[0]
+
+	tests := []struct {
+		name string
+		line int
+		want Location
+		ok   bool
+	}{
+		{name: "context line", line: 1, want: Location{Position: 1, OldLine: 1, Text: "package main"}, ok: true},
+		{name: "added line", line: 2, want: Location{Position: 2, Text: ""}, ok: true},
+		{name: "context after addition", line: 3, want: Location{Position: 3, OldLine: 2, Text: `import "fmt"`}, ok: true},
+		// the second hunk header counts as a position
+		{name: "second hunk", line: 11, want: Location{Position: 7, Text: "\treturn 2"}, ok: true},
+		{name: "context in second hunk", line: 12, want: Location{Position: 8, OldLine: 11, Text: "}"}, ok: true},
+		{name: "between hunks", line: 6, ok: false},
+		{name: "after the last hunk", line: 100, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := file.Locate(tt.line)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("Locate(%d) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+// Package prompt renders the prompts sent to the LLM from text/template files.
+//
+// Every prompt is a template file named <name>.tmpl. The file body is the
+// prompt itself and an optional {{define "system"}}...{{end}} block holds the
+// system instruction. Defaults are embedded in the binary and can be replaced
+// per user or per repository through the prompts section of the config.
+package prompt
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	repopath "git-genius/internal/repo_path"
+)
+
+// names of the prompts
+const (
+	Commit       = "commit"
+	CommitRepair = "commit_repair"
+	CommitRevise = "commit_revise"
+	CommitReview = "commit_review"
+	Squash       = "squash"
+	Split        = "split"
+	CodeReview   = "code_review"
+	Explain      = "explain"
+	ExplainAsk   = "explain_ask"
+	AskPlan      = "ask_plan"
+	AskAnswer    = "ask_answer"
+	Branch       = "branch"
+	PullRequest  = "pull_request"
+	ReleaseNotes = "release_notes"
+	JSONRepair   = "json_repair"
+)
+
+// Names lists every prompt that can be overridden
+var Names = []string{Commit, CommitRepair, CommitRevise, CommitReview, Squash, Split, CodeReview, Explain, ExplainAsk, AskPlan, AskAnswer, Branch, PullRequest, ReleaseNotes, JSONRepair}
+
+// SourceDefault is the source of prompts embedded in the binary
+const SourceDefault = "default"
+
+//go:embed templates/*.tmpl
+var defaults embed.FS
+
+// Data holds the variables available to the templates. Fields that don't
+// apply to a prompt are left empty.
+type Data struct {
+	// Diff is the staged diff (commit, commit_review, branch), the diff of the
+	// branch against its merge base (squash), the staged diff as numbered
+	// hunks (split), the changes to review with numbered lines (code_review)
+	// or the changes being explained (explain)
+	Diff string
+	// Files lists newly added or untracked files (commit, commit_review) or
+	// the top-level entries of the repository (ask_plan)
+	Files []string
+	// Commits are previous commit messages (commit), the commits of the
+	// branch (squash, pull_request), the changes of a release (release_notes)
+	// or the commits being explained (explain)
+	Commits []string
+	// Examples are the messages of similar past commits found in the commit
+	// index, to follow their style (commit, pull_request)
+	Examples []string
+	// History lists the earlier commits of the files touched by the commits
+	// being explained (explain)
+	History []string
+	// Issue is the linked issue, nil when there is none
+	Issue *Issue
+	// Version is the version being released (release_notes)
+	Version string
+	// Target is the revision, range or path being explained (explain)
+	Target string
+	// Question is a follow-up question, the conversation so far is sent
+	// along with it (explain_ask), or a question about the history
+	// (ask_plan, ask_answer)
+	Question string
+	// Results are the git commands run for Question, each followed by its
+	// output (ask_answer)
+	Results []string
+	// Types are the kinds of change a branch can be for (branch)
+	Types []string
+	// Template is the repository's PR template (pull_request)
+	Template string
+	// Rules describes the commit message style to follow (commit,
+	// commit_repair, commit_revise, commit_review, squash, split) or the
+	// repository's review rules (code_review)
+	Rules string
+	// Message is the commit message to repair or review (commit_repair,
+	// commit_review), or the current message of a commit being reworded (commit)
+	Message string
+	// Problems lists what is wrong with Message (commit_repair)
+	Problems []string
+	// Feedback is the user's instruction for revising the last message
+	// (commit_revise), the conversation so far is sent along with it
+	Feedback string
+	// Response is the malformed LLM response (json_repair)
+	Response string
+	// Error is why Response could not be parsed (json_repair)
+	Error string
+}
+
+// Issue is an issue from the tracker
+type Issue struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// Rendered is a prompt ready to be sent
+type Rendered struct {
+	Name string `json:"name"`
+	// Source is the file the template was read from, or "default"
+	Source string `json:"source"`
+	System string `json:"system,omitempty"`
+	Prompt string `json:"prompt"`
+}
+
+// Loader finds the template of each prompt. Overrides are looked up in
+// Files first, then as <name>.tmpl in each of Dirs, then in the defaults.
+type Loader struct {
+	Files map[string]string
+	Dirs  []string
+	// Roots maps the entries of Files and Dirs set by a repository's config
+	// to the repository, templates found through them must stay inside it
+	Roots map[string]string
+}
+
+// Source returns where the template of name is read from
+func (l *Loader) Source(name string) (string, error) {
+	if !slices.Contains(Names, name) {
+		return "", fmt.Errorf("unknown prompt %q, expected one of: %s", name, strings.Join(Names, ", "))
+	}
+	if path, ok := l.Files[name]; ok {
+		return path, l.confine(path, path)
+	}
+	for _, dir := range l.Dirs {
+		path := filepath.Join(dir, name+".tmpl")
+		if _, err := os.Stat(path); err == nil {
+			return path, l.confine(dir, path)
+		}
+	}
+	return SourceDefault, nil
+}
+
+// confine checks that path, found through entry, stays inside the root of entry
+func (l *Loader) confine(entry, path string) error {
+	root, ok := l.Roots[entry]
+	if !ok || repopath.Inside(root, path) {
+		return nil
+	}
+	return fmt.Errorf("prompt template %s is outside of the repository %s", path, root)
+}
+
+// Render executes the template of name with data
+func (l *Loader) Render(name string, data Data) (*Rendered, error) {
+	source, err := l.Source(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := l.parse(name, source)
+	if err != nil {
+		return nil, err
+	}
+
+	var prompt bytes.Buffer
+	if err := tmpl.Execute(&prompt, data); err != nil {
+		return nil, fmt.Errorf("failed to render prompt %s from %s: %w", name, source, err)
+	}
+
+	var system bytes.Buffer
+	if tmpl.Lookup("system") != nil {
+		if err := tmpl.ExecuteTemplate(&system, "system", data); err != nil {
+			return nil, fmt.Errorf("failed to render system instruction of prompt %s from %s: %w", name, source, err)
+		}
+	}
+
+	return &Rendered{
+		Name:   name,
+		Source: source,
+		System: strings.TrimSpace(system.String()),
+		Prompt: strings.TrimSpace(prompt.String()),
+	}, nil
+}
+
+// Check parses the template of name without rendering it
+func (l *Loader) Check(name string) error {
+	source, err := l.Source(name)
+	if err != nil {
+		return err
+	}
+	_, err = l.parse(name, source)
+	return err
+}
+
+func (l *Loader) parse(name, source string) (*template.Template, error) {
+	var content []byte
+	var err error
+	if source == SourceDefault {
+		content, err = defaults.ReadFile("templates/" + name + ".tmpl")
+	} else {
+		content, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt %s: %w", name, err)
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt %s from %s: %w", name, source, err)
+	}
+	return tmpl, nil
+}
+
+// Default returns the embedded template of name
+func Default(name string) (string, error) {
+	content, err := defaults.ReadFile("templates/" + name + ".tmpl")
+	if err != nil {
+		return "", fmt.Errorf("unknown prompt %q, expected one of: %s", name, strings.Join(Names, ", "))
+	}
+	return string(content), nil
+}
@@ -0,0 +1,64 @@
+// Package repopath keeps paths read from a repository's own config inside
+// that repository.
+package repopath
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Inside reports whether path stays below root once symlinks are followed.
+// Parts of path that don't exist yet are compared as written.
+func Inside(root, path string) bool {
+	root, err := resolve(root)
+	if err != nil {
+		return false
+	}
+	path, err = resolve(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// Check returns why path, set in a config file of the repository at root,
+// must not be used, or an empty string. Relative paths are resolved against
+// root.
+func Check(root, path string) string {
+	if strings.HasPrefix(path, "~") || filepath.IsAbs(path) {
+		return "must be a relative path inside the repository"
+	}
+	if !Inside(root, filepath.Join(root, path)) {
+		return "must not point outside of the repository"
+	}
+	return ""
+}
+
+// resolve makes path absolute and follows the symlinks of its existing part
+func resolve(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err == nil {
+		return resolved, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	resolvedParent, err := resolve(parent)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(resolvedParent, filepath.Base(path)), nil
+}
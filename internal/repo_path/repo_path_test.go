@@ -0,0 +1,41 @@
+package repopath
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "out")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(".", filepath.Join(root, "self")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "file", path: "prompts/commit.tmpl"},
+		{name: "root", path: "."},
+		{name: "parent inside", path: "docs/../commit.tmpl"},
+		{name: "symlink inside", path: "self/commit.tmpl"},
+		{name: "absolute", path: "/etc/passwd", want: "must be a relative path inside the repository"},
+		{name: "home", path: "~/.ssh/id_rsa", want: "must be a relative path inside the repository"},
+		{name: "parent", path: "../secrets", want: "must not point outside of the repository"},
+		{name: "symlink outside", path: "out/secrets", want: "must not point outside of the repository"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Check(root, tt.path); got != tt.want {
+				t.Errorf("Check(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Package sarif writes SARIF 2.1.0 logs, the format code scanning tools
+// such as GitHub code scanning read findings from.
+package sarif
+
+// Version and Schema identify the SARIF format written
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// levels of a result
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNote    = "note"
+)
+
+// Log is the top-level SARIF document
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+// Run is one invocation of a tool and its results
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+// Driver describes the tool and the rules its results refer to
+type Driver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri,omitempty"`
+	Rules          []Rule `json:"rules,omitempty"`
+}
+
+type Rule struct {
+	ID               string   `json:"id"`
+	ShortDescription *Message `json:"shortDescription,omitempty"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Result is one finding
+type Result struct {
+	RuleID    string     `json:"ruleId"`
+	Level     string     `json:"level"`
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+// ArtifactLocation is a file, URI is relative to the repository root
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type Region struct {
+	StartLine int `json:"startLine"`
+}
+
+// NewLog returns a log of a single run of the tool
+func NewLog(driver Driver, results []Result) *Log {
+	if results == nil {
+		results = []Result{}
+	}
+	return &Log{
+		Schema:  Schema,
+		Version: Version,
+		Runs:    []Run{{Tool: Tool{Driver: driver}, Results: results}},
+	}
+}
+
+// FileLocation locates a result at line of path, line 0 meaning the whole file
+func FileLocation(path string, line int) Location {
+	location := Location{PhysicalLocation: PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: path}}}
+	if line > 0 {
+		location.PhysicalLocation.Region = &Region{StartLine: line}
+	}
+	return location
+}
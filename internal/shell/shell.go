@@ -0,0 +1,18 @@
+// Package shell writes command lines a POSIX shell reads back as written.
+package shell
+
+import (
+	"regexp"
+	"strings"
+)
+
+// safeWord matches words the shell neither splits nor expands
+var safeWord = regexp.MustCompile(`^[A-Za-z0-9_./:=@+,-]+$`)
+
+// Quote quotes word when the shell would split or expand it
+func Quote(word string) string {
+	if safeWord.MatchString(word) {
+		return word
+	}
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
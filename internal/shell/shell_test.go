@@ -0,0 +1,38 @@
+package shell
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		name string
+		word string
+		want string
+	}{
+		{name: "safe", word: "--since=2024-01-01", want: "--since=2024-01-01"},
+		{name: "path", word: "/usr/local/bin/git-genius", want: "/usr/local/bin/git-genius"},
+		{name: "space", word: "fix login", want: "'fix login'"},
+		{name: "expansion", word: "$HOME/*.go", want: "'$HOME/*.go'"},
+		{name: "single quote", word: "it's", want: `'it'\''s'`},
+		{name: "empty", word: "", want: "''"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Quote(tt.word)
+			if got != tt.want {
+				t.Errorf("Quote(%q) = %s, want %s", tt.word, got, tt.want)
+			}
+			// the shell reads the word back unchanged
+			out, err := exec.Command("sh", "-c", "printf %s "+got).Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tt.word {
+				t.Errorf("sh read %s as %q, want %q", got, out, tt.word)
+			}
+		})
+	}
+}
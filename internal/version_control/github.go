@@ -3,8 +3,8 @@ package versioncontrol
 import (
 	"context"
 	"fmt"
-	"os/exec"
-	"strings"
+
+	"git-genius/internal/patch"
 
 	"github.com/google/go-github/v68/github"
 	"golang.org/x/oauth2"
@@ -16,8 +16,9 @@ type GitHubManager struct {
 	repo   string
 }
 
-// NewGitHubPRCreator initializes a new GitHub PR creator
-func NewGitHubManager(ctx context.Context, token string) (*GitHubManager, error) {
+// NewGitHubPRCreator initializes a new GitHub PR creator, baseURL is only
+// needed for GitHub Enterprise
+func NewGitHubManager(ctx context.Context, token, baseURL string) (*GitHubManager, error) {
 	owner, repo, err := getOwnerAndRepo()
 	if err != nil {
 		return nil, fmt.Errorf("failed to determine owner and repo: %w", err)
@@ -26,8 +27,16 @@ func NewGitHubManager(ctx context.Context, token string) (*GitHubManager, error)
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 	tc := oauth2.NewClient(ctx, ts)
 
+	client := github.NewClient(tc)
+	if baseURL != "" {
+		client, err = client.WithEnterpriseURLs(baseURL, baseURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GitHub base URL: %w", err)
+		}
+	}
+
 	return &GitHubManager{
-		client: github.NewClient(tc),
+		client: client,
 		owner:  owner,
 		repo:   repo,
 	}, nil
@@ -51,30 +60,194 @@ func (g *GitHubManager) CreatePR(ctx context.Context, title, body, head, base st
 }
 
 func getOwnerAndRepo() (string, string, error) {
-	cmd := exec.Command("git", "config", "--get", "remote.origin.url")
-	out, err := cmd.Output()
+	remote, err := GetRemote()
+	if err != nil {
+		return "", "", err
+	}
+
+	return remote.Owner, remote.Repo, nil
+}
+
+// Ping checks that the token can access the repository
+func (g *GitHubManager) Ping(ctx context.Context) error {
+	_, _, err := g.client.Repositories.Get(ctx, g.owner, g.repo)
+	if err != nil {
+		return fmt.Errorf("failed to access %s/%s: %w", g.owner, g.repo, err)
+	}
+	return nil
+}
+
+// PullRequestsForCommit lists the pull requests that contain the commit
+func (g *GitHubManager) PullRequestsForCommit(ctx context.Context, sha string) ([]PullRequest, error) {
+	prs, _, err := g.client.PullRequests.ListPullRequestsWithCommit(ctx, g.owner, g.repo, sha, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests of %s: %w", sha, err)
+	}
+
+	var found []PullRequest
+	for _, pr := range prs {
+		found = append(found, *githubPullRequest(pr))
+	}
+	return found, nil
+}
+
+// CheckRelease accepts every release, GitHub supports drafts and pre-releases
+func (g *GitHubManager) CheckRelease(release Release) error {
+	return nil
+}
+
+// CreateRelease publishes a release for an existing tag
+func (g *GitHubManager) CreateRelease(ctx context.Context, release Release) (string, error) {
+	created, _, err := g.client.Repositories.CreateRelease(ctx, g.owner, g.repo, &github.RepositoryRelease{
+		TagName:    github.Ptr(release.Tag),
+		Name:       github.Ptr(release.Name),
+		Body:       github.Ptr(release.Notes),
+		Draft:      github.Ptr(release.Draft),
+		Prerelease: github.Ptr(release.Prerelease),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create release: %w", err)
+	}
+	return created.GetHTMLURL(), nil
+}
+
+// PullRequestForBranch returns the open pull request of a branch. The branch
+// is looked up in the repository it is pushed to, which is a fork when the
+// pull request comes from one.
+func (g *GitHubManager) PullRequestForBranch(ctx context.Context, branch string) (*PullRequest, error) {
+	owner := g.owner
+	if remote, err := PushRemote(branch); err == nil {
+		owner = remote.Owner
+	}
+	prs, _, err := g.client.PullRequests.List(ctx, g.owner, g.repo, &github.PullRequestListOptions{
+		State: "open",
+		Head:  owner + ":" + branch,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests of %s: %w", branch, err)
+	}
+	if len(prs) == 0 {
+		return nil, fmt.Errorf("there is no open pull request for %s:%s", owner, branch)
+	}
+	return githubPullRequest(prs[0]), nil
+}
+
+// GetPullRequest returns a pull request by number
+func (g *GitHubManager) GetPullRequest(ctx context.Context, number int) (*PullRequest, error) {
+	pr, _, err := g.client.PullRequests.Get(ctx, g.owner, g.repo, number)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to get remote URL: %w", err)
+		return nil, fmt.Errorf("failed to get pull request #%d: %w", number, err)
+	}
+	return githubPullRequest(pr), nil
+}
+
+func githubPullRequest(pr *github.PullRequest) *PullRequest {
+	var labels []string
+	for _, label := range pr.Labels {
+		labels = append(labels, label.GetName())
+	}
+	return &PullRequest{
+		Number:  pr.GetNumber(),
+		Title:   pr.GetTitle(),
+		Labels:  labels,
+		Base:    pr.GetBase().GetRef(),
+		HeadSHA: pr.GetHead().GetSHA(),
+	}
+}
+
+// CreateReview posts the review with its comments at their positions in the
+// pull request's diff
+func (g *GitHubManager) CreateReview(ctx context.Context, number int, review Review) (*ReviewResult, error) {
+	pr, _, err := g.client.PullRequests.Get(ctx, g.owner, g.repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pull request #%d: %w", number, err)
 	}
 
-	url := strings.TrimSpace(string(out))
+	files := map[string]*patch.File{}
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := g.client.PullRequests.ListFiles(ctx, g.owner, g.repo, number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the files of pull request #%d: %w", number, err)
+		}
+		for _, f := range page {
+			file, err := diffFile(f.GetPreviousFilename(), f.GetFilename(), f.GetPatch())
+			if err != nil {
+				return nil, err
+			}
+			files[f.GetFilename()] = file
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
 
-	// Handle SSH and HTTPS URLs
-	if strings.HasPrefix(url, "git@") {
-		// SSH URL format: git@github.com:owner/repo.git
-		parts := strings.SplitN(strings.TrimPrefix(url, "git@github.com:"), "/", 2)
-		if len(parts) < 2 {
-			return "", "", fmt.Errorf("invalid SSH URL format: %s", url)
+	bodies, err := g.reviewBodies(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	inline, body, result := planReview(review, files, postedKeys(bodies))
+	if result.Posted == 0 {
+		return result, nil
+	}
+
+	request := &github.PullRequestReviewRequest{
+		CommitID: github.Ptr(pr.GetHead().GetSHA()),
+		Body:     github.Ptr(body),
+		Event:    github.Ptr("COMMENT"),
+	}
+	for _, comment := range inline {
+		request.Comments = append(request.Comments, &github.DraftReviewComment{
+			Path:     github.Ptr(comment.Path),
+			Position: github.Ptr(comment.Location.Position),
+			Body:     github.Ptr(comment.Body),
+		})
+	}
+
+	created, _, err := g.client.PullRequests.CreateReview(ctx, g.owner, g.repo, number, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create review on pull request #%d: %w", number, err)
+	}
+	result.URL = created.GetHTMLURL()
+	return result, nil
+}
+
+// reviewBodies returns the bodies of the reviews and review comments of a
+// pull request
+func (g *GitHubManager) reviewBodies(ctx context.Context, number int) ([]string, error) {
+	var bodies []string
+
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		reviews, resp, err := g.client.PullRequests.ListReviews(ctx, g.owner, g.repo, number, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the reviews of pull request #%d: %w", number, err)
+		}
+		for _, review := range reviews {
+			bodies = append(bodies, review.GetBody())
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	commentOpts := &github.PullRequestListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := g.client.PullRequests.ListComments(ctx, g.owner, g.repo, number, commentOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the review comments of pull request #%d: %w", number, err)
+		}
+		for _, comment := range comments {
+			bodies = append(bodies, comment.GetBody())
 		}
-		return parts[0], strings.TrimSuffix(parts[1], ".git"), nil
-	} else if strings.HasPrefix(url, "https://") {
-		// HTTPS URL format: https://github.com/owner/repo.git
-		parts := strings.Split(strings.TrimPrefix(url, "https://github.com/"), "/")
-		if len(parts) < 2 {
-			return "", "", fmt.Errorf("invalid HTTPS URL format: %s", url)
+		if resp.NextPage == 0 {
+			break
 		}
-		return parts[0], strings.TrimSuffix(parts[1], ".git"), nil
+		commentOpts.Page = resp.NextPage
 	}
 
-	return "", "", fmt.Errorf("unsupported remote URL format: %s", url)
+	return bodies, nil
 }
@@ -0,0 +1,301 @@
+package versioncontrol
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"git-genius/internal/patch"
+)
+
+type GitLabManager struct {
+	client  *http.Client
+	baseURL string
+	token   string
+	// project is the URL-encoded "group/repo" path used as the project ID
+	project string
+}
+
+// NewGitLabManager initializes a GitLab client for the origin remote. baseURL
+// is the API URL, https://<remote host>/api/v4 by default.
+func NewGitLabManager(ctx context.Context, token, baseURL string) (*GitLabManager, error) {
+	remote, err := GetRemote()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine owner and repo: %w", err)
+	}
+
+	if baseURL == "" {
+		baseURL = "https://" + remote.Host + "/api/v4"
+	}
+
+	return &GitLabManager{
+		client:  &http.Client{},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		project: url.PathEscape(remote.Owner + "/" + remote.Repo),
+	}, nil
+}
+
+// CreatePR opens a merge request on GitLab
+func (g *GitLabManager) CreatePR(ctx context.Context, title, body, head, base string) (string, error) {
+	var mr struct {
+		WebURL string `json:"web_url"`
+	}
+	err := g.request(ctx, http.MethodPost, "/merge_requests", map[string]string{
+		"title":         title,
+		"description":   body,
+		"source_branch": head,
+		"target_branch": base,
+	}, &mr)
+	if err != nil {
+		return "", fmt.Errorf("failed to create merge request: %w", err)
+	}
+	return mr.WebURL, nil
+}
+
+// Ping checks that the token can access the project
+func (g *GitLabManager) Ping(ctx context.Context) error {
+	if err := g.request(ctx, http.MethodGet, "", nil, nil); err != nil {
+		return fmt.Errorf("failed to access %s: %w", g.projectPath(), err)
+	}
+	return nil
+}
+
+// PullRequestsForCommit lists the merge requests that contain the commit
+func (g *GitLabManager) PullRequestsForCommit(ctx context.Context, sha string) ([]PullRequest, error) {
+	var mrs []gitlabMergeRequest
+	if err := g.request(ctx, http.MethodGet, "/repository/commits/"+sha+"/merge_requests", nil, &mrs); err != nil {
+		return nil, fmt.Errorf("failed to list merge requests of %s: %w", sha, err)
+	}
+
+	var found []PullRequest
+	for _, mr := range mrs {
+		found = append(found, mr.pullRequest())
+	}
+	return found, nil
+}
+
+// CheckRelease rejects drafts and pre-releases, GitLab releases have neither
+func (g *GitLabManager) CheckRelease(release Release) error {
+	switch {
+	case release.Draft:
+		return fmt.Errorf("gitlab does not support draft releases")
+	case release.Prerelease:
+		return fmt.Errorf("gitlab does not support pre-releases")
+	}
+	return nil
+}
+
+// CreateRelease publishes a release for an existing tag
+func (g *GitLabManager) CreateRelease(ctx context.Context, release Release) (string, error) {
+	if err := g.CheckRelease(release); err != nil {
+		return "", err
+	}
+	var created struct {
+		Links struct {
+			Self string `json:"self"`
+		} `json:"_links"`
+	}
+	err := g.request(ctx, http.MethodPost, "/releases", map[string]string{
+		"tag_name":    release.Tag,
+		"name":        release.Name,
+		"description": release.Notes,
+	}, &created)
+	if err != nil {
+		return "", fmt.Errorf("failed to create release: %w", err)
+	}
+	return created.Links.Self, nil
+}
+
+// gitlabMergeRequest is the part of a merge request git-genius reads
+type gitlabMergeRequest struct {
+	IID          int      `json:"iid"`
+	Title        string   `json:"title"`
+	Labels       []string `json:"labels"`
+	TargetBranch string   `json:"target_branch"`
+	SHA          string   `json:"sha"`
+	WebURL       string   `json:"web_url"`
+	DiffRefs     struct {
+		BaseSHA  string `json:"base_sha"`
+		HeadSHA  string `json:"head_sha"`
+		StartSHA string `json:"start_sha"`
+	} `json:"diff_refs"`
+}
+
+func (mr gitlabMergeRequest) pullRequest() PullRequest {
+	return PullRequest{Number: mr.IID, Title: mr.Title, Labels: mr.Labels, Base: mr.TargetBranch, HeadSHA: mr.SHA}
+}
+
+// PullRequestForBranch returns the open merge request of a branch
+func (g *GitLabManager) PullRequestForBranch(ctx context.Context, branch string) (*PullRequest, error) {
+	var mrs []gitlabMergeRequest
+	path := "/merge_requests?state=opened&source_branch=" + url.QueryEscape(branch)
+	if err := g.request(ctx, http.MethodGet, path, nil, &mrs); err != nil {
+		return nil, fmt.Errorf("failed to list merge requests of %s: %w", branch, err)
+	}
+	if len(mrs) == 0 {
+		return nil, fmt.Errorf("there is no open merge request for %s", branch)
+	}
+	pr := mrs[0].pullRequest()
+	return &pr, nil
+}
+
+// GetPullRequest returns a merge request by its IID
+func (g *GitLabManager) GetPullRequest(ctx context.Context, number int) (*PullRequest, error) {
+	mr, err := g.mergeRequest(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	pr := mr.pullRequest()
+	return &pr, nil
+}
+
+func (g *GitLabManager) mergeRequest(ctx context.Context, number int) (*gitlabMergeRequest, error) {
+	mr := &gitlabMergeRequest{}
+	if err := g.request(ctx, http.MethodGet, fmt.Sprintf("/merge_requests/%d", number), nil, mr); err != nil {
+		return nil, fmt.Errorf("failed to get merge request !%d: %w", number, err)
+	}
+	return mr, nil
+}
+
+// gitlabPageSize is the page size of paginated GitLab requests
+const gitlabPageSize = 100
+
+// CreateReview starts a discussion on the merge request's diff for each
+// inline comment and adds the summary as a note
+func (g *GitLabManager) CreateReview(ctx context.Context, number int, review Review) (*ReviewResult, error) {
+	mr, err := g.mergeRequest(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]*patch.File{}
+	for page := 1; ; page++ {
+		var diffs []struct {
+			OldPath string `json:"old_path"`
+			NewPath string `json:"new_path"`
+			Diff    string `json:"diff"`
+		}
+		path := fmt.Sprintf("/merge_requests/%d/diffs?per_page=%d&page=%d", number, gitlabPageSize, page)
+		if err := g.request(ctx, http.MethodGet, path, nil, &diffs); err != nil {
+			return nil, fmt.Errorf("failed to list the diffs of merge request !%d: %w", number, err)
+		}
+		for _, diff := range diffs {
+			file, err := diffFile(diff.OldPath, diff.NewPath, diff.Diff)
+			if err != nil {
+				return nil, err
+			}
+			files[diff.NewPath] = file
+		}
+		if len(diffs) < gitlabPageSize {
+			break
+		}
+	}
+
+	var bodies []string
+	for page := 1; ; page++ {
+		var discussions []struct {
+			Notes []struct {
+				Body string `json:"body"`
+			} `json:"notes"`
+		}
+		path := fmt.Sprintf("/merge_requests/%d/discussions?per_page=%d&page=%d", number, gitlabPageSize, page)
+		if err := g.request(ctx, http.MethodGet, path, nil, &discussions); err != nil {
+			return nil, fmt.Errorf("failed to list the discussions of merge request !%d: %w", number, err)
+		}
+		for _, discussion := range discussions {
+			for _, note := range discussion.Notes {
+				bodies = append(bodies, note.Body)
+			}
+		}
+		if len(discussions) < gitlabPageSize {
+			break
+		}
+	}
+
+	inline, body, result := planReview(review, files, postedKeys(bodies))
+	if result.Posted == 0 {
+		return result, nil
+	}
+
+	for _, comment := range inline {
+		position := map[string]interface{}{
+			"position_type": "text",
+			"base_sha":      mr.DiffRefs.BaseSHA,
+			"start_sha":     mr.DiffRefs.StartSHA,
+			"head_sha":      mr.DiffRefs.HeadSHA,
+			"old_path":      comment.File.OldPath,
+			"new_path":      comment.File.NewPath,
+			"new_line":      comment.Line,
+		}
+		// unchanged lines are located on both sides of the diff
+		if comment.Location.OldLine > 0 {
+			position["old_line"] = comment.Location.OldLine
+		}
+		err := g.request(ctx, http.MethodPost, fmt.Sprintf("/merge_requests/%d/discussions", number), map[string]interface{}{
+			"body":     comment.Body,
+			"position": position,
+		}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to comment on %s:%d of merge request !%d: %w", comment.Path, comment.Line, number, err)
+		}
+	}
+
+	if body != "" {
+		err := g.request(ctx, http.MethodPost, fmt.Sprintf("/merge_requests/%d/notes", number), map[string]string{"body": body}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to comment on merge request !%d: %w", number, err)
+		}
+	}
+
+	result.URL = mr.WebURL
+	return result, nil
+}
+
+// request calls the project API at path and decodes the response into result
+func (g *GitLabManager) request(ctx context.Context, method, path string, body, result interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+"/projects/"+g.project+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("invalid GitLab URL: %w", err)
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to contact GitLab API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("gitlab api responded with status: %d, message: %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes)))
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode GitLab response: %w", err)
+	}
+	return nil
+}
+
+func (g *GitLabManager) projectPath() string {
+	path, _ := url.PathUnescape(g.project)
+	return path
+}
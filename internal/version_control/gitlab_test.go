@@ -0,0 +1,24 @@
+package versioncontrol
+
+import "testing"
+
+func TestGitLabCheckRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		release Release
+		wantErr bool
+	}{
+		{name: "release", release: Release{Tag: "v1.0.0", Name: "v1.0.0"}},
+		{name: "draft", release: Release{Tag: "v1.0.0", Draft: true}, wantErr: true},
+		{name: "pre-release", release: Release{Tag: "v1.0.0-rc.1", Prerelease: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&GitLabManager{}).CheckRelease(tt.release)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckRelease() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
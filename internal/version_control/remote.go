@@ -0,0 +1,85 @@
+package versioncontrol
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Remote describes the repository a git remote points to
+type Remote struct {
+	Host  string
+	Owner string
+	Repo  string
+}
+
+// GetRemote parses the URL of the origin remote
+func GetRemote() (*Remote, error) {
+	cmd := exec.Command("git", "config", "--get", "remote.origin.url")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remote URL: %w", err)
+	}
+
+	return ParseRemoteURL(strings.TrimSpace(string(out)))
+}
+
+// PushRemote parses the URL of the remote branch is pushed to, chosen like
+// git push does from branch.<name>.pushRemote, remote.pushDefault and
+// branch.<name>.remote
+func PushRemote(branch string) (*Remote, error) {
+	name := "origin"
+	for _, key := range []string{"branch." + branch + ".pushRemote", "remote.pushDefault", "branch." + branch + ".remote"} {
+		if out, err := exec.Command("git", "config", "--get", key).Output(); err == nil {
+			name = strings.TrimSpace(string(out))
+			break
+		}
+	}
+
+	for _, key := range []string{"remote." + name + ".pushurl", "remote." + name + ".url"} {
+		if out, err := exec.Command("git", "config", "--get", key).Output(); err == nil {
+			return ParseRemoteURL(strings.TrimSpace(string(out)))
+		}
+	}
+	return nil, fmt.Errorf("failed to get the URL of remote %s", name)
+}
+
+// ParseRemoteURL parses SSH (git@host:owner/repo.git, ssh://git@host/owner/repo.git)
+// and HTTPS (https://host/owner/repo.git) remote URLs. Nested groups are kept
+// in the owner, e.g. group/subgroup.
+func ParseRemoteURL(url string) (*Remote, error) {
+	var host, path string
+
+	switch {
+	case strings.HasPrefix(url, "ssh://"), strings.HasPrefix(url, "https://"), strings.HasPrefix(url, "http://"):
+		rest := url[strings.Index(url, "://")+3:]
+		// drop credentials and port
+		if at := strings.LastIndex(rest, "@"); at >= 0 {
+			rest = rest[at+1:]
+		}
+		var ok bool
+		host, path, ok = strings.Cut(rest, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid remote URL format: %s", url)
+		}
+		host, _, _ = strings.Cut(host, ":")
+	case strings.Contains(url, "@") && strings.Contains(url, ":"):
+		// scp-like syntax: git@host:owner/repo.git
+		rest := url[strings.Index(url, "@")+1:]
+		host, path, _ = strings.Cut(rest, ":")
+	default:
+		return nil, fmt.Errorf("unsupported remote URL format: %s", url)
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	slash := strings.LastIndex(path, "/")
+	if host == "" || slash <= 0 || slash == len(path)-1 {
+		return nil, fmt.Errorf("invalid remote URL format: %s", url)
+	}
+
+	return &Remote{
+		Host:  host,
+		Owner: path[:slash],
+		Repo:  path[slash+1:],
+	}, nil
+}
@@ -0,0 +1,133 @@
+package versioncontrol
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"git-genius/internal/patch"
+)
+
+// ReviewComment is a comment on a line of a pull request's changes
+type ReviewComment struct {
+	Path string
+	// Line is in the new version of Path
+	Line int
+	Body string
+	// Key identifies the comment across runs, comments whose key is already
+	// on the pull request are not posted again
+	Key string
+}
+
+// Review is a summary of the changes with inline comments
+type Review struct {
+	Body     string
+	Comments []ReviewComment
+}
+
+// ReviewResult tells what CreateReview posted
+type ReviewResult struct {
+	// URL is empty when there was nothing new to post
+	URL    string `json:"url,omitempty"`
+	Posted int    `json:"posted"`
+	// Duplicates were posted by an earlier run and skipped
+	Duplicates int `json:"duplicates"`
+	// OutsideDiff counts the posted comments on lines the pull request's
+	// diff doesn't show, they are listed in the review body instead
+	OutsideDiff int `json:"outside_diff"`
+}
+
+// Reviewer posts reviews on pull requests
+type Reviewer interface {
+	// PullRequestForBranch returns the open pull request of a branch
+	PullRequestForBranch(ctx context.Context, branch string) (*PullRequest, error)
+	GetPullRequest(ctx context.Context, number int) (*PullRequest, error)
+	// CreateReview posts the review on the pull request's latest commit
+	CreateReview(ctx context.Context, number int, review Review) (*ReviewResult, error)
+}
+
+// markerPattern finds the hidden markers git-genius leaves in its comments
+var markerPattern = regexp.MustCompile(`<!-- git-genius:(\S+) -->`)
+
+// marker hides the key of a comment in its Markdown body
+func marker(key string) string {
+	return "<!-- git-genius:" + key + " -->"
+}
+
+// postedKeys collects the keys of the markers in the bodies of existing comments
+func postedKeys(bodies []string) map[string]bool {
+	keys := map[string]bool{}
+	for _, body := range bodies {
+		for _, m := range markerPattern.FindAllStringSubmatch(body, -1) {
+			keys[m[1]] = true
+		}
+	}
+	return keys
+}
+
+// inlineComment is a review comment located in the pull request's diff
+type inlineComment struct {
+	ReviewComment
+	File     *patch.File
+	Location patch.Location
+}
+
+// planReview drops the comments posted before, locates the others in the
+// diff files, keyed by path, and moves those on lines the diff doesn't show
+// to the review body. The body is empty when there is nothing new to post.
+func planReview(review Review, files map[string]*patch.File, posted map[string]bool) ([]inlineComment, string, *ReviewResult) {
+	result := &ReviewResult{}
+	var inline []inlineComment
+	var outside []string
+	for _, comment := range review.Comments {
+		if comment.Key != "" && posted[comment.Key] {
+			result.Duplicates++
+			continue
+		}
+		hidden := ""
+		if comment.Key != "" {
+			hidden = marker(comment.Key)
+		}
+
+		if file, ok := files[comment.Path]; ok && comment.Line > 0 {
+			if location, ok := file.Locate(comment.Line); ok {
+				comment.Body = strings.TrimSpace(comment.Body + "\n\n" + hidden)
+				inline = append(inline, inlineComment{ReviewComment: comment, File: file, Location: location})
+				continue
+			}
+		}
+
+		where := comment.Path
+		if comment.Line > 0 {
+			where = fmt.Sprintf("%s:%d", comment.Path, comment.Line)
+		}
+		item := strings.ReplaceAll(strings.TrimSpace(comment.Body), "\n\n", "\n")
+		outside = append(outside, strings.TrimSpace(fmt.Sprintf("- `%s`: %s %s", where, strings.ReplaceAll(item, "\n", "\n  "), hidden)))
+	}
+
+	result.Posted = len(inline) + len(outside)
+	result.OutsideDiff = len(outside)
+	if result.Posted == 0 {
+		return nil, "", result
+	}
+
+	body := strings.TrimSpace(review.Body)
+	if len(outside) > 0 {
+		body += "\n\nOn lines outside the diff:\n\n" + strings.Join(outside, "\n")
+	}
+	return inline, strings.TrimSpace(body), result
+}
+
+// diffFile builds the file of a pull request's diff from the hunks the API
+// returns for it
+func diffFile(oldPath, newPath, hunks string) (*patch.File, error) {
+	parsed, err := patch.ParseHunks(hunks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the diff of %s: %w", newPath, err)
+	}
+	if oldPath == "" {
+		oldPath = newPath
+	}
+	return &patch.File{OldPath: oldPath, NewPath: newPath, Hunks: parsed}, nil
+}
@@ -0,0 +1,120 @@
+package versioncontrol
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"git-genius/internal/patch"
+)
+
+func TestPostedKeys(t *testing.T) {
+	bodies := []string{
+		"**warning** (style): long line\n\n<!-- git-genius:finding-a1 -->",
+		"Summary\n\n- `main.go:3`: typo <!-- git-genius:finding-b2 -->\n- `go.mod`: old <!-- git-genius:finding-c3 -->",
+		"a comment by someone else",
+	}
+	want := map[string]bool{"finding-a1": true, "finding-b2": true, "finding-c3": true}
+	if got := postedKeys(bodies); !reflect.DeepEqual(got, want) {
+		t.Errorf("postedKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestPlanReview(t *testing.T) {
+	// main.go shows lines 1 to 4 of its new version, line 2 is added
+	file, err := diffFile("", "main.go", "@@ -1,3 +1,4 @@\n package main\n+import \"fmt\"\n \n func main() {}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]*patch.File{"main.go": file}
+
+	tests := []struct {
+		name   string
+		review Review
+		posted map[string]bool
+		// inline lists the path:line of the inline comments
+		inline     []string
+		wantBody   []string
+		wantResult ReviewResult
+	}{
+		{
+			name: "inline",
+			review: Review{Body: "Summary", Comments: []ReviewComment{
+				{Path: "main.go", Line: 2, Body: "unused import", Key: "finding-a1"},
+			}},
+			inline:     []string{"main.go:2"},
+			wantBody:   []string{"Summary"},
+			wantResult: ReviewResult{Posted: 1},
+		},
+		{
+			name: "outside the diff",
+			review: Review{Body: "Summary", Comments: []ReviewComment{
+				{Path: "main.go", Line: 40, Body: "too long", Key: "finding-a1"},
+				{Path: "go.mod", Body: "old Go version\n\nbump it", Key: "finding-b2"},
+			}},
+			wantBody: []string{
+				"On lines outside the diff:",
+				"- `main.go:40`: too long <!-- git-genius:finding-a1 -->",
+				"- `go.mod`: old Go version\n  bump it <!-- git-genius:finding-b2 -->",
+			},
+			wantResult: ReviewResult{Posted: 2, OutsideDiff: 2},
+		},
+		{
+			name: "posted before",
+			review: Review{Body: "Summary", Comments: []ReviewComment{
+				{Path: "main.go", Line: 2, Body: "unused import", Key: "finding-a1"},
+				{Path: "main.go", Line: 4, Body: "empty main", Key: "finding-b2"},
+			}},
+			posted:     map[string]bool{"finding-a1": true},
+			inline:     []string{"main.go:4"},
+			wantBody:   []string{"Summary"},
+			wantResult: ReviewResult{Posted: 1, Duplicates: 1},
+		},
+		{
+			name: "nothing new",
+			review: Review{Body: "Summary", Comments: []ReviewComment{
+				{Path: "main.go", Line: 2, Body: "unused import", Key: "finding-a1"},
+			}},
+			posted:     map[string]bool{"finding-a1": true},
+			wantResult: ReviewResult{Duplicates: 1},
+		},
+		{
+			name: "no key",
+			review: Review{Comments: []ReviewComment{
+				{Path: "main.go", Line: 2, Body: "unused import"},
+			}},
+			posted:     map[string]bool{"": true},
+			inline:     []string{"main.go:2"},
+			wantResult: ReviewResult{Posted: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inline, body, result := planReview(tt.review, files, tt.posted)
+
+			var located []string
+			for _, comment := range inline {
+				located = append(located, fmt.Sprintf("%s:%d", comment.Path, comment.Line))
+				if comment.Key != "" && !strings.HasSuffix(comment.Body, marker(comment.Key)) {
+					t.Errorf("comment %s = %q, want it to end with its marker", comment.Key, comment.Body)
+				}
+			}
+			if !reflect.DeepEqual(located, tt.inline) {
+				t.Errorf("inline comments = %v, want %v", located, tt.inline)
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(body, want) {
+					t.Errorf("body = %q, want it to contain %q", body, want)
+				}
+			}
+			if len(tt.wantBody) == 0 && len(tt.inline) == 0 && body != "" {
+				t.Errorf("body = %q, want it empty", body)
+			}
+			if *result != tt.wantResult {
+				t.Errorf("result = %+v, want %+v", *result, tt.wantResult)
+			}
+		})
+	}
+}
@@ -4,4 +4,40 @@ import "context"
 
 type PRCreator interface {
 	CreatePR(ctx context.Context, title, body, head, base string) (string, error)
+	// Ping checks that the credentials can access the repository
+	Ping(ctx context.Context) error
+}
+
+// PullRequest is a pull request found for a commit or a branch
+type PullRequest struct {
+	Number int
+	Title  string
+	Labels []string
+	// Base is the branch the pull request merges into
+	Base string
+	// HeadSHA is the commit the pull request's branch points at
+	HeadSHA string
+}
+
+// PullRequestFinder looks up the pull requests that contain a commit
+type PullRequestFinder interface {
+	PullRequestsForCommit(ctx context.Context, sha string) ([]PullRequest, error)
+}
+
+// Release is a release published for a tag
+type Release struct {
+	Tag        string
+	Name       string
+	Notes      string
+	Draft      bool
+	Prerelease bool
+}
+
+// ReleaseCreator publishes releases for tags pushed to the repository
+type ReleaseCreator interface {
+	// CheckRelease rejects options the provider can't publish, before the
+	// tag is pushed
+	CheckRelease(release Release) error
+	// CreateRelease returns the URL of the release
+	CreateRelease(ctx context.Context, release Release) (string, error)
 }
@@ -0,0 +1,260 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+
+	context_provider "git-genius/internal/context_provider"
+	llm "git-genius/internal/llm"
+	"git-genius/internal/prompt"
+	"git-genius/internal/shell"
+)
+
+// limits of the searches planned for a question
+const (
+	askMaxQueries   = 5
+	askDefaultLimit = 20
+	askMaxLimit     = 50
+	// askMaxEntries bounds the top-level entries listed for planning
+	askMaxEntries = 100
+)
+
+// GitQuery is a git log search planned by the LLM. git-genius builds the
+// command line from the fields itself, so that only git log runs.
+type GitQuery struct {
+	// Pickaxe finds commits changing the number of occurrences of a string (-S)
+	Pickaxe string `json:"pickaxe,omitempty"`
+	// Regex finds commits adding or removing matching lines (-G)
+	Regex string `json:"regex,omitempty"`
+	// Message finds commits whose message matches (--grep)
+	Message string   `json:"message,omitempty"`
+	Paths   []string `json:"paths,omitempty"`
+	Author  string   `json:"author,omitempty"`
+	Since   string   `json:"since,omitempty"`
+	Until   string   `json:"until,omitempty"`
+	Limit   int      `json:"limit,omitempty"`
+}
+
+// QueryResult is a search that was run and what it found
+type QueryResult struct {
+	Query GitQuery `json:"query"`
+	// Command is the git command line that ran
+	Command string   `json:"command"`
+	Commits []string `json:"commits"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// HistoryAnswer answers a question about the repository's history
+type HistoryAnswer struct {
+	Question string        `json:"question"`
+	Queries  []QueryResult `json:"queries"`
+	Answer   string        `json:"answer"`
+	// Citations are the hashes the answer cites that the searches found
+	Citations []string `json:"citations"`
+}
+
+// askPlanSchema is the JSON shape requested for search plans
+var askPlanSchema = &llm.Schema{
+	Type: llm.TypeObject,
+	Properties: map[string]*llm.Schema{
+		"queries": {
+			Type: llm.TypeArray,
+			Items: &llm.Schema{
+				Type: llm.TypeObject,
+				Properties: map[string]*llm.Schema{
+					"pickaxe": {Type: llm.TypeString},
+					"regex":   {Type: llm.TypeString},
+					"message": {Type: llm.TypeString},
+					"paths":   {Type: llm.TypeArray, Items: &llm.Schema{Type: llm.TypeString}},
+					"author":  {Type: llm.TypeString},
+					"since":   {Type: llm.TypeString},
+					"until":   {Type: llm.TypeString},
+					"limit":   {Type: llm.TypeInteger},
+				},
+			},
+		},
+	},
+	Required: []string{"queries"},
+}
+
+// AskHistory answers a question about the repository's history. The LLM
+// plans git log searches, they run locally and the LLM answers from their
+// output only, citing the commits it relies on.
+func (g *GitGeniusSDK) AskHistory(ctx context.Context, question string) (*HistoryAnswer, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, fmt.Errorf("question cannot be empty")
+	}
+
+	rendered, err := g.prompts.Render(prompt.AskPlan, prompt.Data{
+		Question: question,
+		Files:    topLevelEntries(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var plan struct {
+		Queries []GitQuery `json:"queries"`
+	}
+	if err := g.generateJSON(ctx, rendered, 1024, askPlanSchema, &plan); err != nil {
+		return nil, err
+	}
+
+	answer := &HistoryAnswer{Question: question, Queries: []QueryResult{}, Citations: []string{}}
+	var results []string
+	for _, query := range plan.Queries {
+		if len(answer.Queries) == askMaxQueries {
+			break
+		}
+		query = query.normalize()
+		if query.empty() {
+			continue
+		}
+
+		result := QueryResult{Query: query, Command: query.String(), Commits: []string{}}
+		commits, err := context_provider.LogSummary(ctx, query.Args()...)
+		if err != nil {
+			result.Error = err.Error()
+		} else if commits != nil {
+			result.Commits = commits
+		}
+		answer.Queries = append(answer.Queries, result)
+		results = append(results, result.view())
+	}
+	if len(answer.Queries) == 0 {
+		return nil, fmt.Errorf("llm planned no git searches for the question")
+	}
+
+	rendered, err = g.prompts.Render(prompt.AskAnswer, prompt.Data{
+		Question: question,
+		Results:  results,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := g.generate(ctx, rendered, 1024)
+	if err != nil {
+		return nil, err
+	}
+	answer.Answer = strings.TrimSpace(text)
+	answer.Citations = citations(answer.Answer, answer.Queries)
+
+	return answer, nil
+}
+
+// normalize trims the fields and bounds the limit
+func (q GitQuery) normalize() GitQuery {
+	q.Pickaxe = strings.TrimSpace(q.Pickaxe)
+	q.Regex = strings.TrimSpace(q.Regex)
+	q.Message = strings.TrimSpace(q.Message)
+	q.Author = strings.TrimSpace(q.Author)
+	q.Since = strings.TrimSpace(q.Since)
+	q.Until = strings.TrimSpace(q.Until)
+	q.Paths = nonEmpty(q.Paths)
+	if q.Limit <= 0 {
+		q.Limit = askDefaultLimit
+	}
+	q.Limit = min(q.Limit, askMaxLimit)
+	return q
+}
+
+// empty reports whether the query has no filter and would list everything
+func (q GitQuery) empty() bool {
+	return q.Pickaxe == "" && q.Regex == "" && q.Message == "" && len(q.Paths) == 0 &&
+		q.Author == "" && q.Since == "" && q.Until == ""
+}
+
+// Args returns the git log arguments of the query. Every value is attached
+// to its option, so no value can be read as an option of its own.
+func (q GitQuery) Args() []string {
+	args := []string{fmt.Sprintf("--max-count=%d", q.Limit)}
+	if q.Pickaxe != "" {
+		args = append(args, "-S"+q.Pickaxe)
+	}
+	if q.Regex != "" {
+		args = append(args, "-G"+q.Regex)
+	}
+	if q.Message != "" {
+		args = append(args, "--regexp-ignore-case", "--grep="+q.Message)
+	}
+	if q.Author != "" {
+		args = append(args, "--author="+q.Author)
+	}
+	if q.Since != "" {
+		args = append(args, "--since="+q.Since)
+	}
+	if q.Until != "" {
+		args = append(args, "--until="+q.Until)
+	}
+	args = append(args, "--")
+	return append(args, q.Paths...)
+}
+
+// String returns the command line of the query as it could be typed in a shell
+func (q GitQuery) String() string {
+	words := []string{"git", "log", shell.Quote(context_provider.LogSummaryFormat)}
+	for _, arg := range q.Args() {
+		words = append(words, shell.Quote(arg))
+	}
+	if len(q.Paths) == 0 {
+		// a trailing "--" only separates the paths
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// view renders the result for the answer prompt
+func (r QueryResult) view() string {
+	text := "$ " + r.Command
+	switch {
+	case r.Error != "":
+		text += "\nerror: " + r.Error
+	case len(r.Commits) == 0:
+		text += "\n(no commits)"
+	default:
+		text += "\n" + strings.Join(r.Commits, "\n")
+	}
+	return text
+}
+
+var citedHash = regexp.MustCompile(`\b[0-9a-f]{7,40}\b`)
+
+// citations returns the hashes cited in the answer that the searches found
+func citations(answer string, results []QueryResult) []string {
+	found := map[string]bool{}
+	for _, result := range results {
+		for _, commit := range result.Commits {
+			hash, _, _ := strings.Cut(commit, " ")
+			found[hash] = true
+		}
+	}
+
+	cited := []string{}
+	for _, hash := range citedHash.FindAllString(answer, -1) {
+		for short := range found {
+			if (strings.HasPrefix(hash, short) || strings.HasPrefix(short, hash)) && !slices.Contains(cited, short) {
+				cited = append(cited, short)
+			}
+		}
+	}
+	return cited
+}
+
+// topLevelEntries lists the files and directories at the root of HEAD
+func topLevelEntries() []string {
+	out, err := exec.Command("git", "ls-tree", "--name-only", "HEAD").Output()
+	if err != nil {
+		return nil
+	}
+	entries := nonEmpty(strings.Split(string(out), "\n"))
+	if len(entries) > askMaxEntries {
+		entries = entries[:askMaxEntries]
+	}
+	return entries
+}
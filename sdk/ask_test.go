@@ -0,0 +1,79 @@
+package sdk
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGitQueryArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    GitQuery
+		wantArgs []string
+		wantLine string
+	}{
+		{
+			name:     "pickaxe with default limit",
+			query:    GitQuery{Pickaxe: " retryCount "},
+			wantArgs: []string{"--max-count=20", "-SretryCount", "--"},
+			wantLine: "git log '--format=%h %as %an: %s' --max-count=20 -SretryCount",
+		},
+		{
+			name:     "every filter",
+			query:    GitQuery{Regex: "func (New|Open)", Message: "login", Author: "Sam", Since: "2 weeks ago", Until: "2024-06-01", Paths: []string{"cmd/", " "}, Limit: 5},
+			wantArgs: []string{"--max-count=5", "-Gfunc (New|Open)", "--regexp-ignore-case", "--grep=login", "--author=Sam", "--since=2 weeks ago", "--until=2024-06-01", "--", "cmd/"},
+			wantLine: "git log '--format=%h %as %an: %s' --max-count=5 '-Gfunc (New|Open)' --regexp-ignore-case --grep=login --author=Sam '--since=2 weeks ago' --until=2024-06-01 -- cmd/",
+		},
+		{
+			name:     "limit bounded",
+			query:    GitQuery{Message: "fix", Limit: 500},
+			wantArgs: []string{"--max-count=50", "--regexp-ignore-case", "--grep=fix", "--"},
+			wantLine: "git log '--format=%h %as %an: %s' --max-count=50 --regexp-ignore-case --grep=fix",
+		},
+		{
+			// values stay attached to their option, none becomes an option
+			name:     "option lookalikes",
+			query:    GitQuery{Pickaxe: "--output=/tmp/x", Author: "--exec=sh", Paths: []string{"--all"}},
+			wantArgs: []string{"--max-count=20", "-S--output=/tmp/x", "--author=--exec=sh", "--", "--all"},
+			wantLine: "git log '--format=%h %as %an: %s' --max-count=20 -S--output=/tmp/x --author=--exec=sh -- --all",
+		},
+		{
+			name:     "quotes in values",
+			query:    GitQuery{Message: "don't"},
+			wantArgs: []string{"--max-count=20", "--regexp-ignore-case", "--grep=don't", "--"},
+			wantLine: `git log '--format=%h %as %an: %s' --max-count=20 --regexp-ignore-case '--grep=don'\''t'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := tt.query.normalize()
+			if query.empty() {
+				t.Fatalf("query %+v is empty", query)
+			}
+			if got := query.Args(); !reflect.DeepEqual(got, tt.wantArgs) {
+				t.Errorf("Args() = %q, want %q", got, tt.wantArgs)
+			}
+			if got := query.String(); got != tt.wantLine {
+				t.Errorf("String() = %s, want %s", got, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestGitQueryEmpty(t *testing.T) {
+	if query := (GitQuery{Message: "  ", Paths: []string{""}, Limit: 3}).normalize(); !query.empty() {
+		t.Errorf("query %+v is not empty, want it empty", query)
+	}
+}
+
+func TestCitations(t *testing.T) {
+	results := []QueryResult{
+		{Commits: []string{"a1b2c3d 2024-05-01 Sam: fix: login", "e4f5a6b 2024-04-01 Sam: feat: retry"}},
+		{Commits: []string{"0123456 2024-03-01 Alex: docs: readme"}},
+	}
+	answer := "Retries came with e4f5a6b7 and the login fix in a1b2c3d. Commit deadbee isn't in the results."
+	if got, want := citations(answer, results), []string{"e4f5a6b", "a1b2c3d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("citations() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	branchname "git-genius/internal/branch_name"
+	commitstyle "git-genius/internal/commit_style"
+	context_provider "git-genius/internal/context_provider"
+	llm "git-genius/internal/llm"
+	"git-genius/internal/prompt"
+)
+
+// branchMaxDiff bounds the staged diff sent to name a branch, in bytes
+const branchMaxDiff = 20000
+
+// BranchProposal is a branch name for an issue or the staged changes
+type BranchProposal struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Issue string `json:"issue,omitempty"`
+	Slug  string `json:"slug"`
+}
+
+// branchSchema is the JSON shape requested for branch proposals
+func branchSchema(types []string) *llm.Schema {
+	return &llm.Schema{
+		Type: llm.TypeObject,
+		Properties: map[string]*llm.Schema{
+			"type": {Type: llm.TypeString, Enum: types},
+			"slug": {Type: llm.TypeString, Description: "Two to five lowercase words joined with dashes"},
+		},
+		Required: []string{"type", "slug"},
+	}
+}
+
+// ProposeBranch names a branch for the issue and the staged changes, either
+// may be missing, following the branch pattern. The name is sanitized but
+// may collide with an existing branch.
+func (g *GitGeniusSDK) ProposeBranch(ctx context.Context) (*BranchProposal, error) {
+	types := g.commitRules.Types
+	if len(types) == 0 {
+		types = commitstyle.DefaultTypes
+	}
+	data := prompt.Data{Types: types}
+
+	if g.issueID != "" {
+		issue, err := g.contextManager.FetchIssue(ctx, g.issueID)
+		if err != nil {
+			return nil, err
+		}
+		if issue != nil {
+			data.Issue = &prompt.Issue{ID: g.issueID, Title: issue.Title, Description: issue.Description}
+		}
+	}
+
+	diff, err := context_provider.ReviewDiff(ctx, "", "", 3)
+	if err != nil {
+		return nil, err
+	}
+	if len(diff) > branchMaxDiff {
+		diff = diff[:branchMaxDiff] + "\n[diff truncated]"
+	}
+	data.Diff = diff
+	if data.Issue == nil && strings.TrimSpace(diff) == "" {
+		return nil, fmt.Errorf("there is nothing to name the branch after, stage changes or link an issue")
+	}
+
+	rendered, err := g.prompts.Render(prompt.Branch, data)
+	if err != nil {
+		return nil, err
+	}
+	var generated struct {
+		Type string `json:"type"`
+		Slug string `json:"slug"`
+	}
+	if err := g.generateJSON(ctx, rendered, 256, branchSchema(types), &generated); err != nil {
+		return nil, err
+	}
+
+	proposal := &BranchProposal{
+		Type:  strings.ToLower(strings.TrimSpace(generated.Type)),
+		Issue: g.issueID,
+		Slug:  branchname.Slugify(generated.Slug),
+	}
+	if !slices.Contains(types, proposal.Type) {
+		proposal.Type = ""
+	}
+	if proposal.Slug == "" && data.Issue != nil {
+		proposal.Slug = branchname.Slugify(data.Issue.Title)
+	}
+	if proposal.Slug == "" {
+		return nil, fmt.Errorf("llm returned a branch without a slug")
+	}
+
+	proposal.Name = branchname.Format(g.branchPattern, branchname.Fields{
+		Type:  proposal.Type,
+		Issue: proposal.Issue,
+		Slug:  proposal.Slug,
+	}, g.branchMaxLength)
+	return proposal, nil
+}
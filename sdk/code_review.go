@@ -0,0 +1,199 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	context_provider "git-genius/internal/context_provider"
+	llm "git-genius/internal/llm"
+	"git-genius/internal/patch"
+	"git-genius/internal/prompt"
+)
+
+// reviewContextLines is how much unchanged code is sent around each hunk
+const reviewContextLines = 10
+
+// ReviewCategories lists the kinds of problems a code review reports
+var ReviewCategories = []string{"bug", "security", "performance", "error-handling", "maintainability", "testing", "documentation", "style"}
+
+// CodeFinding is a problem the code review found in the changes
+type CodeFinding struct {
+	File string `json:"file"`
+	// Line is in the new version of File, 0 when the finding is about the
+	// whole file
+	Line       int    `json:"line,omitempty"`
+	Severity   string `json:"severity"`
+	Category   string `json:"category"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// Location returns "file:line", or the file for findings without a line
+func (f CodeFinding) Location() string {
+	if f.Line == 0 {
+		return f.File
+	}
+	return fmt.Sprintf("%s:%d", f.File, f.Line)
+}
+
+// CodeReview is the LLM's review of a set of changes
+type CodeReview struct {
+	Summary string `json:"summary"`
+	// Findings are sorted by file and line
+	Findings []CodeFinding `json:"findings"`
+	// Files is the reviewed diff
+	Files []*patch.File `json:"-"`
+}
+
+// codeReviewSchema is the JSON shape requested for code reviews
+var codeReviewSchema = &llm.Schema{
+	Type: llm.TypeObject,
+	Properties: map[string]*llm.Schema{
+		"summary": {Type: llm.TypeString, Description: "The changes and their overall quality in one or two sentences"},
+		"findings": {
+			Type: llm.TypeArray,
+			Items: &llm.Schema{
+				Type: llm.TypeObject,
+				Properties: map[string]*llm.Schema{
+					"file":       {Type: llm.TypeString},
+					"line":       {Type: llm.TypeInteger, Description: "Numbered line of the new version"},
+					"severity":   {Type: llm.TypeString, Enum: []string{SeverityError, SeverityWarning, SeverityInfo}},
+					"category":   {Type: llm.TypeString, Enum: ReviewCategories},
+					"message":    {Type: llm.TypeString},
+					"suggestion": {Type: llm.TypeString},
+				},
+				Required: []string{"file", "line", "severity", "category", "message"},
+			},
+		},
+	},
+	Required: []string{"summary", "findings"},
+}
+
+// ReviewChanges reviews what head changes since it diverged from base, or
+// the staged changes when base is empty. Findings about files outside the
+// diff are dropped and lines outside its hunks are reported for the whole
+// file.
+func (g *GitGeniusSDK) ReviewChanges(ctx context.Context, base, head string) (*CodeReview, error) {
+	from := ""
+	if base != "" {
+		mergeBase, err := context_provider.MergeBase(ctx, base, head)
+		if err != nil {
+			return nil, err
+		}
+		from = mergeBase
+	}
+
+	diff, err := context_provider.ReviewDiff(ctx, from, head, reviewContextLines)
+	if err != nil {
+		return nil, err
+	}
+	files, err := patch.Parse(diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %v", err)
+	}
+	if len(files) == 0 {
+		if base == "" {
+			return nil, fmt.Errorf("there are no staged changes to review")
+		}
+		return nil, fmt.Errorf("there are no changes between %s and %s", base, head)
+	}
+
+	rendered, err := g.prompts.Render(prompt.CodeReview, prompt.Data{
+		Diff:  reviewView(files),
+		Rules: g.reviewRules,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var generated struct {
+		Summary  string        `json:"summary"`
+		Findings []CodeFinding `json:"findings"`
+	}
+	if err := g.generateJSON(ctx, rendered, 4096, codeReviewSchema, &generated); err != nil {
+		return nil, err
+	}
+
+	review := &CodeReview{Summary: strings.TrimSpace(generated.Summary), Findings: []CodeFinding{}, Files: files}
+	for _, finding := range generated.Findings {
+		file := reviewedFile(files, finding.File)
+		if file == nil || strings.TrimSpace(finding.Message) == "" {
+			continue
+		}
+		finding.File = file.Path()
+		if _, ok := file.Locate(finding.Line); !ok {
+			finding.Line = 0
+		}
+		switch finding.Severity = strings.ToLower(finding.Severity); finding.Severity {
+		case SeverityError, SeverityWarning, SeverityInfo:
+		default:
+			finding.Severity = SeverityWarning
+		}
+		finding.Category = strings.ToLower(strings.TrimSpace(finding.Category))
+		finding.Message = strings.TrimSpace(finding.Message)
+		finding.Suggestion = strings.TrimSpace(finding.Suggestion)
+		review.Findings = append(review.Findings, finding)
+	}
+	sort.SliceStable(review.Findings, func(i, j int) bool {
+		a, b := review.Findings[i], review.Findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+
+	return review, nil
+}
+
+// reviewView renders the files for the code review prompt, numbering the
+// context and added lines with their line in the new version
+func reviewView(files []*patch.File) string {
+	var b strings.Builder
+	for _, file := range files {
+		switch {
+		case file.Deleted:
+			fmt.Fprintf(&b, "File %s (deleted)\n\n", file.OldPath)
+			continue
+		case file.Binary:
+			fmt.Fprintf(&b, "File %s (binary, not shown)\n\n", file.Path())
+			continue
+		case file.Renamed:
+			fmt.Fprintf(&b, "File %s (renamed from %s)\n", file.NewPath, file.OldPath)
+		case file.New:
+			fmt.Fprintf(&b, "File %s (new)\n", file.NewPath)
+		default:
+			fmt.Fprintf(&b, "File %s\n", file.NewPath)
+		}
+
+		for _, hunk := range file.Hunks {
+			b.WriteString(hunk.Header + "\n")
+			line := hunk.NewStart
+			for _, text := range hunk.Lines {
+				switch {
+				case strings.HasPrefix(text, "\\"):
+				case strings.HasPrefix(text, "-"):
+					fmt.Fprintf(&b, "%6s %s\n", "", text)
+				default:
+					fmt.Fprintf(&b, "%6d %s\n", line, text)
+					line++
+				}
+			}
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// reviewedFile finds the file a finding is about, nil when it isn't part of
+// the diff or was deleted
+func reviewedFile(files []*patch.File, path string) *patch.File {
+	path = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(path), "b/"), "./")
+	for _, file := range files {
+		if !file.Deleted && !file.Binary && file.Path() == path {
+			return file
+		}
+	}
+	return nil
+}
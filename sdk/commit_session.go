@@ -0,0 +1,235 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	context_provider "git-genius/internal/context_provider"
+	llm "git-genius/internal/llm"
+	"git-genius/internal/prompt"
+)
+
+// shorterFeedback is the revision asked for by CommitSession.Shorten
+const shorterFeedback = "Make the message shorter. Tighten the header and drop any body details that are not essential."
+
+// CommitSession keeps the conversation that produced a commit message so the
+// message can be regenerated or revised with feedback
+type CommitSession struct {
+	sdk      *GitGeniusSDK
+	rendered *prompt.Rendered
+	// history holds the turns before the current message, oldest first
+	history []llm.Message
+	// Message is the current commit message
+	Message string
+	// Candidates holds the alternatives generated at the start of the session,
+	// the first one is the initial Message
+	Candidates []string
+}
+
+// StartCommitSession collects the context and generates the first commit
+// message, or n distinct candidates to pick from with SetMessage
+func (g *GitGeniusSDK) StartCommitSession(ctx context.Context, n int) (*CommitSession, error) {
+	context, err := g.contextManager.CollectContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect context: %v", err)
+	}
+
+	data, err := commitPromptData(context, g.issueID, g.commitRules)
+	if err != nil {
+		return nil, err
+	}
+	withCommitExamples(&data, styleExamples(ctx, g.embedder, commitQuery(data), g.examples))
+
+	rendered, err := g.prompts.Render(prompt.Commit, data)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &CommitSession{sdk: g, rendered: rendered}
+	if n <= 1 {
+		if _, err := session.Regenerate(ctx); err != nil {
+			return nil, err
+		}
+		session.Candidates = []string{session.Message}
+		return session, nil
+	}
+
+	responses, err := g.generateN(ctx, rendered, 150, n)
+	if err != nil {
+		return nil, err
+	}
+
+	// conform every candidate, repairs may need another round trip each
+	conformed := make([]string, len(responses))
+	errs := make([]error, len(responses))
+	var wg sync.WaitGroup
+	for i, response := range responses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conformed[i], errs[i] = g.conformCommitMessage(ctx, response)
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	for _, message := range conformed {
+		if !slices.Contains(session.Candidates, message) {
+			session.Candidates = append(session.Candidates, message)
+		}
+	}
+	if len(session.Candidates) == 0 {
+		return nil, fmt.Errorf("llm returned no commit message")
+	}
+
+	session.history = []llm.Message{{Role: llm.RoleUser, Content: rendered.Prompt}}
+	session.Message = session.Candidates[0]
+	return session, nil
+}
+
+// GenerateCommitMessageFor writes a new message for an existing commit from
+// the commit's own diff
+func (g *GitGeniusSDK) GenerateCommitMessageFor(ctx context.Context, commit context_provider.Commit) (string, error) {
+	git, err := context_provider.CommitContext(ctx, commit)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(git.Diff) == "" {
+		return "", fmt.Errorf("commit %s has no changes to describe", commit.ShortHash())
+	}
+
+	rendered, err := g.prompts.Render(prompt.Commit, prompt.Data{
+		Diff:    git.Diff,
+		Files:   git.NewFiles,
+		Commits: git.PreviousMessage,
+		Message: commit.Message,
+		Rules:   g.commitRules.Instructions(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	message, err := g.generate(ctx, rendered, 150)
+	if err != nil {
+		return "", err
+	}
+	return g.conformCommitMessage(ctx, message)
+}
+
+// GenerateCommitMessages returns up to n distinct commit messages for the
+// staged changes
+func (g *GitGeniusSDK) GenerateCommitMessages(ctx context.Context, n int) ([]string, error) {
+	session, err := g.StartCommitSession(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	return session.Candidates, nil
+}
+
+// generateN asks for n responses to the same prompt, in a single request when
+// the backend supports candidates and with parallel requests otherwise
+func (g *GitGeniusSDK) generateN(ctx context.Context, rendered *prompt.Rendered, maxTokens, n int) ([]string, error) {
+	var responses []string
+	if generator, ok := g.llm.(llm.CandidateGenerator); ok {
+		var opts []llm.Option
+		if rendered.System != "" {
+			opts = append(opts, llm.WithSystemInstruction(rendered.System))
+		}
+
+		var err error
+		responses, err = generator.GenerateCandidates(ctx, rendered.Prompt, maxTokens, n, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate response: %v", err)
+		}
+	}
+
+	// top up whatever the backend didn't return
+	missing := n - len(responses)
+	if missing <= 0 {
+		return responses[:n], nil
+	}
+
+	extra := make([]string, missing)
+	errs := make([]error, missing)
+	var wg sync.WaitGroup
+	for i := range extra {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			extra[i], errs[i] = g.generate(ctx, rendered, maxTokens)
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return append(responses, extra...), nil
+}
+
+// Regenerate discards the conversation and generates a new message from the
+// original prompt
+func (s *CommitSession) Regenerate(ctx context.Context) (string, error) {
+	message, err := s.sdk.generate(ctx, s.rendered, 150)
+	if err != nil {
+		return "", err
+	}
+
+	message, err = s.sdk.conformCommitMessage(ctx, message)
+	if err != nil {
+		return "", err
+	}
+
+	s.history = []llm.Message{{Role: llm.RoleUser, Content: s.rendered.Prompt}}
+	s.Message = message
+	return message, nil
+}
+
+// Revise asks for a new version of the current message following feedback,
+// e.g. "mention the migration"
+func (s *CommitSession) Revise(ctx context.Context, feedback string) (string, error) {
+	feedback = strings.TrimSpace(feedback)
+	if feedback == "" {
+		return "", fmt.Errorf("feedback cannot be empty")
+	}
+
+	rendered, err := s.sdk.prompts.Render(prompt.CommitRevise, prompt.Data{
+		Feedback: feedback,
+		Rules:    s.sdk.commitRules.Instructions(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	history := append(s.history, llm.Message{Role: llm.RoleModel, Content: s.Message})
+	message, err := s.sdk.generate(ctx, rendered, 150, llm.WithHistory(history))
+	if err != nil {
+		return "", err
+	}
+
+	message, err = s.sdk.conformCommitMessage(ctx, message)
+	if err != nil {
+		return "", err
+	}
+
+	s.history = append(history, llm.Message{Role: llm.RoleUser, Content: rendered.Prompt})
+	s.Message = message
+	return message, nil
+}
+
+// Shorten revises the current message to be more concise
+func (s *CommitSession) Shorten(ctx context.Context) (string, error) {
+	return s.Revise(ctx, shorterFeedback)
+}
+
+// SetMessage replaces the current message, e.g. after the user edited it, so
+// that later revisions start from the edited text
+func (s *CommitSession) SetMessage(message string) {
+	s.Message = message
+}
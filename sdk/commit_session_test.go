@@ -0,0 +1,60 @@
+package sdk
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	llm "git-genius/internal/llm"
+	"git-genius/internal/prompt"
+)
+
+// fakeCandidateLLM returns candidates in one request, like backends with a
+// candidate count do
+type fakeCandidateLLM struct {
+	*fakeLLM
+	candidates []string
+}
+
+func (f *fakeCandidateLLM) GenerateCandidates(ctx context.Context, prompt string, maxTokens, n int, opts ...llm.Option) ([]string, error) {
+	return f.candidates[:min(n, len(f.candidates))], nil
+}
+
+func TestGenerateN(t *testing.T) {
+	tests := []struct {
+		name       string
+		responses  []string
+		candidates []string
+		n          int
+		want       []string
+		wantCalls  int
+	}{
+		{name: "parallel requests", responses: []string{"a", "b", "c"}, n: 3, want: []string{"a", "b", "c"}, wantCalls: 3},
+		{name: "all candidates", candidates: []string{"a", "b", "c", "d"}, n: 3, want: []string{"a", "b", "c"}},
+		{name: "topped up", responses: []string{"c"}, candidates: []string{"a", "b"}, n: 3, want: []string{"a", "b", "c"}, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeLLM{responses: tt.responses}
+			g := &GitGeniusSDK{llm: fake}
+			if tt.candidates != nil {
+				g.llm = &fakeCandidateLLM{fakeLLM: fake, candidates: tt.candidates}
+			}
+
+			got, err := g.generateN(context.Background(), &prompt.Rendered{Prompt: "write a commit message"}, 100, tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// parallel requests finish in any order
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("generateN() = %q, want %q", got, tt.want)
+			}
+			if len(fake.prompts) != tt.wantCalls {
+				t.Errorf("generateN() made %d requests, want %d", len(fake.prompts), tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package sdk
+
+import (
+	"context"
+	"strings"
+
+	commitindex "git-genius/internal/commit_index"
+	"git-genius/internal/prompt"
+)
+
+// maxExampleQuery bounds the text commits are matched against, in bytes
+const maxExampleQuery = 4000
+
+// SimilarCommits returns the k indexed commits most similar to text, most
+// similar first, none when the repository has no commit index yet
+func (g *GitGeniusSDK) SimilarCommits(ctx context.Context, text string, k int) ([]commitindex.Match, error) {
+	return similarCommits(ctx, g.embedder, text, k)
+}
+
+func similarCommits(ctx context.Context, embedder commitindex.Embedder, text string, k int) ([]commitindex.Match, error) {
+	path, err := commitindex.DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	idx, err := commitindex.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(idx.Documents) == 0 {
+		return nil, nil
+	}
+	return idx.Search(ctx, embedder, text, k)
+}
+
+// styleExamples returns the messages of the k commits most similar to text.
+// Examples are optional, without an index or when the search fails there
+// are none.
+func styleExamples(ctx context.Context, embedder commitindex.Embedder, text string, k int) []string {
+	if len(text) > maxExampleQuery {
+		text = text[:maxExampleQuery]
+	}
+	matches, err := similarCommits(ctx, embedder, text, k)
+	if err != nil {
+		return nil
+	}
+	var examples []string
+	for _, match := range matches {
+		examples = append(examples, match.Message)
+	}
+	return examples
+}
+
+// withCommitExamples replaces the latest subjects of a commit prompt with
+// the similar commits when there are any
+func withCommitExamples(data *prompt.Data, examples []string) {
+	if len(examples) > 0 {
+		data.Examples = examples
+		data.Commits = nil
+	}
+}
+
+// commitQuery is what staged changes are matched against: the new files and
+// the diff
+func commitQuery(data prompt.Data) string {
+	return strings.Join(data.Files, "\n") + "\n" + data.Diff
+}
+
+// pullRequestQuery is what a pull request is matched against: its issue and
+// commits
+func pullRequestQuery(data prompt.Data) string {
+	var query []string
+	if data.Issue != nil {
+		query = append(query, data.Issue.Title)
+	}
+	return strings.Join(append(query, data.Commits...), "\n")
+}
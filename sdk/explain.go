@@ -0,0 +1,224 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	context_provider "git-genius/internal/context_provider"
+	llm "git-genius/internal/llm"
+	"git-genius/internal/patch"
+	"git-genius/internal/prompt"
+)
+
+// limits of the context gathered for an explanation
+const (
+	// explainMaxDiff bounds the diff sent, in bytes
+	explainMaxDiff = 60000
+	// explainPathCommits is how many commits of a path are explained
+	explainPathCommits = 15
+	// explainPathDiffs is how many of a path's latest commits come with their diff
+	explainPathDiffs = 3
+	// explainHistoryFiles and explainHistoryCommits bound the history of the
+	// touched files
+	explainHistoryFiles   = 10
+	explainHistoryCommits = 5
+	// explainIssueLookups bounds the issue references looked up
+	explainIssueLookups = 3
+)
+
+// ExplainSession keeps the context gathered for an explanation, so that
+// follow-up questions are answered from the same commits, diff and issue
+type ExplainSession struct {
+	sdk *GitGeniusSDK
+	// history holds the conversation so far, oldest first
+	history []llm.Message
+	// Target is the revision, range or path being explained
+	Target  string
+	Commits []context_provider.Commit
+	// Issue is the issue the commits reference, nil when none was found
+	Issue *prompt.Issue
+	// Explanation is the summary of what changed and why
+	Explanation string
+}
+
+// Explain summarizes what changed in a revision, a "<from>..<to>" range or
+// the history of a path, and why, from the commits, their diff, the linked
+// issue and the earlier history of the touched files
+func (g *GitGeniusSDK) Explain(ctx context.Context, target string) (*ExplainSession, error) {
+	session := &ExplainSession{sdk: g, Target: target}
+
+	var diff string
+	var err error
+	isPath := false
+	switch {
+	case strings.Contains(target, ".."):
+		if session.Commits, err = context_provider.Log(ctx, "--no-merges", target); err != nil {
+			return nil, err
+		}
+		diff, err = context_provider.RangeDiff(ctx, target)
+	case isRevision(target):
+		if session.Commits, err = context_provider.Log(ctx, "-1", target); err != nil {
+			return nil, err
+		}
+		diff, err = context_provider.Show(ctx, target)
+	default:
+		// a path, its latest commits explain how it came to be
+		isPath = true
+		if session.Commits, err = context_provider.Log(ctx, fmt.Sprintf("--max-count=%d", explainPathCommits), "--", target); err != nil {
+			return nil, err
+		}
+		diff, err = pathDiffs(ctx, session.Commits, target)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(session.Commits) == 0 {
+		return nil, fmt.Errorf("%s is not a revision, a range with commits or a path with history", target)
+	}
+
+	var commits []string
+	for _, commit := range session.Commits {
+		commits = append(commits, fmt.Sprintf("%s %s %s\n%s", commit.ShortHash(), commit.Date, commit.Author, commit.Message))
+	}
+
+	session.Issue = g.linkedIssue(ctx, session.Commits)
+
+	// the commits of a path already are its history
+	var history []string
+	if !isPath {
+		if history, err = touchedFileHistory(ctx, diff, session.Commits[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(diff) > explainMaxDiff {
+		diff = diff[:explainMaxDiff] + "\n[diff truncated]"
+	}
+
+	rendered, err := g.prompts.Render(prompt.Explain, prompt.Data{
+		Target:  target,
+		Commits: commits,
+		Issue:   session.Issue,
+		History: history,
+		Diff:    diff,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	explanation, err := g.generate(ctx, rendered, 1024)
+	if err != nil {
+		return nil, err
+	}
+	session.Explanation = strings.TrimSpace(explanation)
+	session.history = []llm.Message{
+		{Role: llm.RoleUser, Content: rendered.Prompt},
+		{Role: llm.RoleModel, Content: session.Explanation},
+	}
+	return session, nil
+}
+
+// Ask answers a follow-up question about the explained changes
+func (s *ExplainSession) Ask(ctx context.Context, question string) (string, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return "", fmt.Errorf("question cannot be empty")
+	}
+
+	rendered, err := s.sdk.prompts.Render(prompt.ExplainAsk, prompt.Data{Question: question})
+	if err != nil {
+		return "", err
+	}
+
+	answer, err := s.sdk.generate(ctx, rendered, 1024, llm.WithHistory(s.history))
+	if err != nil {
+		return "", err
+	}
+	answer = strings.TrimSpace(answer)
+
+	s.history = append(s.history,
+		llm.Message{Role: llm.RoleUser, Content: rendered.Prompt},
+		llm.Message{Role: llm.RoleModel, Content: answer},
+	)
+	return answer, nil
+}
+
+// isRevision reports whether target names a commit
+func isRevision(target string) bool {
+	return exec.Command("git", "rev-parse", "--verify", "--quiet", target+"^{commit}").Run() == nil
+}
+
+// pathDiffs returns the changes of the latest commits to path
+func pathDiffs(ctx context.Context, commits []context_provider.Commit, path string) (string, error) {
+	var b strings.Builder
+	for i := len(commits) - 1; i >= 0 && i >= len(commits)-explainPathDiffs; i-- {
+		diff, err := context_provider.Show(ctx, commits[i].Hash, path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "Changes of %s:\n%s\n", commits[i].ShortHash(), diff)
+	}
+	return b.String(), nil
+}
+
+// touchedFileHistory lists the commits before first that touched the files
+// of the diff
+func touchedFileHistory(ctx context.Context, diff string, first context_provider.Commit) ([]string, error) {
+	files, err := patch.Parse(diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse diff: %v", err)
+	}
+	if len(first.Parents) == 0 {
+		return nil, nil
+	}
+
+	var history []string
+	for i, file := range files {
+		if i >= explainHistoryFiles {
+			break
+		}
+		if file.New {
+			continue
+		}
+		commits, err := context_provider.FileHistory(ctx, file.OldPath, first.Parents[0], explainHistoryCommits)
+		if err != nil {
+			return nil, err
+		}
+		for _, commit := range commits {
+			history = append(history, file.OldPath+": "+commit)
+		}
+	}
+	return history, nil
+}
+
+// linkedIssue looks up the first issue the commits reference in the
+// configured issue tracker, with commit.issue_pattern or Linear's identifiers
+func (g *GitGeniusSDK) linkedIssue(ctx context.Context, commits []context_provider.Commit) *prompt.Issue {
+	pattern := g.issuePattern
+	if pattern == nil {
+		pattern = context_provider.LinearIssuePattern
+	}
+
+	seen := map[string]bool{}
+	for _, commit := range commits {
+		for _, id := range pattern.FindAllString(commit.Message, -1) {
+			if seen[id] || len(seen) == explainIssueLookups {
+				continue
+			}
+			seen[id] = true
+
+			// references that are not issues, e.g. UTF-8, are not found
+			issue, err := g.contextManager.FetchIssue(ctx, id)
+			if err != nil {
+				continue
+			}
+			if issue == nil {
+				return nil
+			}
+			return &prompt.Issue{ID: id, Title: issue.Title, Description: issue.Description}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package sdk
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"git-genius/internal/prompt"
+)
+
+func TestExplain(t *testing.T) {
+	dir := testRepo(t)
+	commitFile(t, dir, "a.txt", "one\n", "feat: add a")
+	commitFile(t, dir, "a.txt", "two\n", "fix: correct a")
+	commitFile(t, dir, "b.txt", "b\n", "feat: add b")
+
+	tests := []struct {
+		name        string
+		target      string
+		wantCommits []string
+		// wantPrompt are parts of the prompt sent, skipPrompt parts it must not have
+		wantPrompt []string
+		skipPrompt []string
+		wantErr    bool
+	}{
+		{
+			name:        "revision",
+			target:      "HEAD~1",
+			wantCommits: []string{"fix: correct a"},
+			wantPrompt:  []string{"-one\n+two", "a.txt: ", "feat: add a"},
+			skipPrompt:  []string{"feat: add b"},
+		},
+		{
+			name:        "range",
+			target:      "HEAD~2..HEAD",
+			wantCommits: []string{"fix: correct a", "feat: add b"},
+			wantPrompt:  []string{"-one\n+two", "+b"},
+		},
+		{
+			name:        "path",
+			target:      "a.txt",
+			wantCommits: []string{"feat: add a", "fix: correct a"},
+			wantPrompt:  []string{"+one", "-one\n+two"},
+			skipPrompt:  []string{"feat: add b"},
+		},
+		{name: "unknown", target: "missing.txt", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeLLM{responses: []string{" It fixes a. "}}
+			g := &GitGeniusSDK{llm: fake, prompts: &prompt.Loader{}}
+
+			session, err := g.Explain(context.Background(), tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Explain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if session.Explanation != "It fixes a." {
+				t.Errorf("Explain() explanation = %q", session.Explanation)
+			}
+			var subjects []string
+			for _, commit := range session.Commits {
+				subjects = append(subjects, commit.Subject)
+			}
+			if strings.Join(subjects, "\n") != strings.Join(tt.wantCommits, "\n") {
+				t.Errorf("Explain() commits = %q, want %q", subjects, tt.wantCommits)
+			}
+			for _, want := range tt.wantPrompt {
+				if !strings.Contains(fake.prompts[0], want) {
+					t.Errorf("prompt doesn't include %q:\n%s", want, fake.prompts[0])
+				}
+			}
+			for _, skip := range tt.skipPrompt {
+				if strings.Contains(fake.prompts[0], skip) {
+					t.Errorf("prompt includes %q:\n%s", skip, fake.prompts[0])
+				}
+			}
+		})
+	}
+}
+
+func TestExplainAsk(t *testing.T) {
+	dir := testRepo(t)
+	commitFile(t, dir, "a.txt", "one\n", "feat: add a")
+
+	fake := &fakeLLM{responses: []string{"It adds a.", " Because b needs it. "}}
+	g := &GitGeniusSDK{llm: fake, prompts: &prompt.Loader{}}
+	session, err := g.Explain(context.Background(), "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := session.Ask(context.Background(), "  "); err == nil {
+		t.Errorf("Ask() with an empty question succeeded")
+	}
+	answer, err := session.Ask(context.Background(), "why?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "Because b needs it." {
+		t.Errorf("Ask() = %q", answer)
+	}
+	if len(session.history) != 4 || session.history[1].Content != "It adds a." || session.history[3].Content != answer {
+		t.Errorf("Ask() history = %+v, want the explanation and the answer", session.history)
+	}
+}
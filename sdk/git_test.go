@@ -0,0 +1,48 @@
+package sdk
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// testRepo creates an empty repository the git commands of the test run in,
+// isolated from the user's and the system's git config
+func testRepo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_COUNT", "0")
+	t.Setenv("GIT_AUTHOR_NAME", "Sam")
+	t.Setenv("GIT_AUTHOR_EMAIL", "sam@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Sam")
+	t.Setenv("GIT_COMMITTER_EMAIL", "sam@example.com")
+	git(t, dir, "init", "--quiet")
+	t.Setenv("GIT_DIR", filepath.Join(dir, ".git"))
+	t.Setenv("GIT_WORK_TREE", dir)
+	return dir
+}
+
+// git runs a git command in dir and fails the test when it fails
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return string(out)
+}
+
+// commitFile writes content to name and commits it with message
+func commitFile(t *testing.T, dir, name, content, message string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "add", name)
+	git(t, dir, "commit", "--quiet", "-m", message)
+}
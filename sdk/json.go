@@ -0,0 +1,68 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	llm "git-genius/internal/llm"
+	"git-genius/internal/prompt"
+)
+
+// generateJSON asks for a response matching schema and decodes it into out.
+// Backends without a JSON mode may wrap the object in prose or code fences, so
+// the response is parsed leniently and a broken one is sent back once for repair.
+func (g *GitGeniusSDK) generateJSON(ctx context.Context, rendered *prompt.Rendered, maxTokens int, schema *llm.Schema, out interface{}) error {
+	response, err := g.generate(ctx, rendered, maxTokens, llm.WithJSONSchema(schema))
+	if err != nil {
+		return err
+	}
+
+	parseErr := parseJSON(response, out)
+	if parseErr == nil {
+		return nil
+	}
+
+	repairPrompt, err := g.prompts.Render(prompt.JSONRepair, prompt.Data{
+		Response: response,
+		Error:    parseErr.Error(),
+	})
+	if err != nil {
+		return err
+	}
+
+	repaired, err := g.generate(ctx, repairPrompt, maxTokens, llm.WithJSONSchema(schema))
+	if err != nil {
+		return err
+	}
+
+	if err := parseJSON(repaired, out); err != nil {
+		return fmt.Errorf("failed to parse llm response as JSON: %v", err)
+	}
+	return nil
+}
+
+var trailingComma = regexp.MustCompile(`,(\s*[}\]])`)
+
+// parseJSON decodes the first JSON object found in response
+func parseJSON(response string, out interface{}) error {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return fmt.Errorf("no JSON object found")
+	}
+	object := response[start : end+1]
+
+	err := json.Unmarshal([]byte(object), out)
+	if err == nil {
+		return nil
+	}
+
+	// models often leave a trailing comma after the last element
+	if json.Unmarshal([]byte(trailingComma.ReplaceAllString(object, "$1")), out) == nil {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	llm "git-genius/internal/llm"
+	"git-genius/internal/prompt"
+)
+
+// fakeLLM returns its responses in order and records the prompts it was sent
+type fakeLLM struct {
+	mu        sync.Mutex
+	responses []string
+	prompts   []string
+}
+
+func (f *fakeLLM) GenerateResponse(ctx context.Context, prompt string, maxTokens int, opts ...llm.Option) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prompts = append(f.prompts, prompt)
+	if len(f.responses) == 0 {
+		return "", fmt.Errorf("no response left")
+	}
+	response := f.responses[0]
+	f.responses = f.responses[1:]
+	return response, nil
+}
+
+func (f *fakeLLM) Ping(ctx context.Context) error {
+	return nil
+}
+
+func TestParseJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+		wantErr  bool
+	}{
+		{name: "plain", response: `{"title": "Add login"}`, want: "Add login"},
+		{name: "code fence", response: "```json\n{\"title\": \"Add login\"}\n```", want: "Add login"},
+		{name: "prose around", response: "Here is the PR:\n{\"title\": \"Add login\"}\nLet me know!", want: "Add login"},
+		{name: "trailing comma", response: `{"title": "Add login", "tags": ["auth",],}`, want: "Add login"},
+		{name: "no object", response: "I can't help with that.", wantErr: true},
+		{name: "broken object", response: `{"title": "Add login"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got PullRequestContent
+			err := parseJSON(tt.response, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Title != tt.want {
+				t.Errorf("parseJSON() title = %q, want %q", got.Title, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []string
+		want      string
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "valid", responses: []string{`{"title": "Add login"}`}, want: "Add login", wantCalls: 1},
+		{name: "repaired", responses: []string{`title: Add login`, `{"title": "Add login"}`}, want: "Add login", wantCalls: 2},
+		{name: "still broken", responses: []string{`title: Add login`, `{"title": }`}, wantCalls: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeLLM{responses: tt.responses}
+			g := &GitGeniusSDK{llm: fake, prompts: &prompt.Loader{}}
+
+			var got PullRequestContent
+			err := g.generateJSON(context.Background(), &prompt.Rendered{Prompt: "describe the PR"}, 100, nil, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("generateJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Title != tt.want {
+				t.Errorf("generateJSON() title = %q, want %q", got.Title, tt.want)
+			}
+			if len(fake.prompts) != tt.wantCalls {
+				t.Fatalf("generateJSON() made %d calls, want %d", len(fake.prompts), tt.wantCalls)
+			}
+			// the repair round trip sends the broken response back
+			if tt.wantCalls > 1 && !strings.Contains(fake.prompts[1], tt.responses[0]) {
+				t.Errorf("repair prompt %q doesn't include the broken response", fake.prompts[1])
+			}
+		})
+	}
+}
@@ -1,7 +1,94 @@
 package sdk
 
+import "strings"
+
 type PullRequestContent struct {
-	Title string
-	Body  string
-	Tags  []string
+	Title string `json:"title"`
+	// Body is the rendered markdown description
+	Body            string               `json:"body"`
+	Sections        []PullRequestSection `json:"sections"`
+	Tags            []string             `json:"tags"`
+	BreakingChanges []string             `json:"breaking_changes"`
+	TestingNotes    []string             `json:"testing_notes"`
+}
+
+// PullRequestSection is a headed part of the PR description
+type PullRequestSection struct {
+	Heading string `json:"heading"`
+	Content string `json:"content"`
+}
+
+// renderBody builds the markdown description from the sections and notes
+func (p *PullRequestContent) renderBody() string {
+	var b strings.Builder
+	write := func(heading, content string) {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		if heading != "" {
+			b.WriteString("## " + heading + "\n\n")
+		}
+		b.WriteString(strings.TrimSpace(content))
+	}
+
+	for _, section := range p.Sections {
+		if strings.TrimSpace(section.Content) != "" {
+			write(section.Heading, section.Content)
+		}
+	}
+	if len(p.BreakingChanges) > 0 {
+		write("Breaking changes", bulletList(p.BreakingChanges))
+	}
+	if len(p.TestingNotes) > 0 {
+		write("Testing", bulletList(p.TestingNotes))
+	}
+
+	return b.String()
+}
+
+func bulletList(items []string) string {
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = "- " + strings.TrimSpace(item)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ReleaseNotes are the user-facing notes of a release
+type ReleaseNotes struct {
+	Version      string   `json:"version"`
+	Summary      string   `json:"summary"`
+	Highlights   []string `json:"highlights"`
+	UpgradeNotes []string `json:"upgrade_notes"`
+	// Contributors are the names of the authors and co-authors
+	Contributors []string `json:"contributors"`
+}
+
+// Markdown renders the notes for a release page
+func (n *ReleaseNotes) Markdown() string {
+	var b strings.Builder
+	write := func(heading, content string) {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		if heading != "" {
+			b.WriteString("## " + heading + "\n\n")
+		}
+		b.WriteString(strings.TrimSpace(content))
+	}
+
+	if n.Summary != "" {
+		write("", n.Summary)
+	}
+	if len(n.Highlights) > 0 {
+		write("Highlights", bulletList(n.Highlights))
+	}
+	if len(n.UpgradeNotes) > 0 {
+		write("Upgrade notes", bulletList(n.UpgradeNotes))
+	}
+	if len(n.Contributors) > 0 {
+		write("Contributors", bulletList(n.Contributors))
+	}
+
+	return b.String() + "\n"
 }
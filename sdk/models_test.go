@@ -0,0 +1,64 @@
+package sdk
+
+import "testing"
+
+func TestPullRequestBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		content PullRequestContent
+		want    string
+	}{
+		{name: "empty", content: PullRequestContent{}, want: ""},
+		{
+			name: "sections and notes",
+			content: PullRequestContent{
+				Sections: []PullRequestSection{
+					{Heading: "Summary", Content: "Adds login.\n"},
+					{Heading: "Screenshots", Content: "  "},
+					{Content: "Closes #12"},
+				},
+				BreakingChanges: []string{"drops the /v1 API"},
+				TestingNotes:    []string{" log in ", "log out"},
+			},
+			want: "## Summary\n\nAdds login.\n\nCloses #12\n\n## Breaking changes\n\n- drops the /v1 API" +
+				"\n\n## Testing\n\n- log in\n- log out",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.content.renderBody(); got != tt.want {
+				t.Errorf("renderBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseNotesMarkdown(t *testing.T) {
+	tests := []struct {
+		name  string
+		notes ReleaseNotes
+		want  string
+	}{
+		{name: "summary only", notes: ReleaseNotes{Summary: "Bug fixes."}, want: "Bug fixes.\n"},
+		{
+			name: "all parts",
+			notes: ReleaseNotes{
+				Summary:      "Drops the v1 API.",
+				Highlights:   []string{"Faster startup"},
+				UpgradeNotes: []string{"api: the /v1 endpoints are gone"},
+				Contributors: []string{"Ada Lovelace", "Grace Hopper"},
+			},
+			want: "Drops the v1 API.\n\n## Highlights\n\n- Faster startup\n\n## Upgrade notes\n\n- api: the /v1 endpoints are gone" +
+				"\n\n## Contributors\n\n- Ada Lovelace\n- Grace Hopper\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.notes.Markdown(); got != tt.want {
+				t.Errorf("Markdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"git-genius/config"
+	commitstyle "git-genius/internal/commit_style"
+	context_provider "git-genius/internal/context_provider"
+	llm "git-genius/internal/llm"
+	"git-genius/internal/prompt"
+)
+
+// RenderPrompt collects the context and renders the prompt called name
+// exactly as it would be sent, without calling the LLM
+func RenderPrompt(ctx context.Context, cfg *config.Config, name string) (*prompt.Rendered, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config is not defined")
+	}
+
+	rules := newCommitRules(ctx, cfg)
+
+	context, err := context_provider.NewContextManager(cfg).CollectContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect context: %v", err)
+	}
+
+	var data prompt.Data
+	switch name {
+	case prompt.Commit:
+		if data, err = commitPromptData(context, cfg.IssueID, rules); err == nil {
+			withCommitExamples(&data, styleExamples(ctx, cfg.NewEmbedder(), commitQuery(data), cfg.IndexExamples()))
+		}
+	case prompt.PullRequest:
+		if data, err = pullRequestPromptData(context, cfg.IssueID); err == nil {
+			data.Examples = styleExamples(ctx, cfg.NewEmbedder(), pullRequestQuery(data), cfg.IndexExamples())
+		}
+	default:
+		return nil, fmt.Errorf("prompt %q is only rendered while repairing a response, expected %s or %s",
+			name, prompt.Commit, prompt.PullRequest)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.NewPromptLoader().Render(name, data)
+}
+
+// newCommitRules builds the commit message rules, the repo's commitlint
+// config fills the gaps. A broken commitlint config only warns, it shouldn't
+// stop every command.
+func newCommitRules(ctx context.Context, cfg *config.Config) commitstyle.Rules {
+	rules := commitstyle.Rules{
+		Style:              commitstyle.Style(cfg.Commit.Style),
+		CustomInstructions: cfg.Commit.Instructions,
+		Types:              cfg.Commit.Types,
+		Scopes:             cfg.Commit.Scopes,
+		MaxSubjectLength:   cfg.Commit.MaxSubjectLength,
+		BodyWrap:           cfg.Commit.BodyWrap,
+	}
+	if root, err := context_provider.RepoRoot(ctx); err == nil {
+		commitlint, err := commitstyle.LoadCommitlint(root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "git-genius: warning: ignoring the commitlint config, using commit.style: %v\n", err)
+			return rules
+		}
+		rules = commitlint.Apply(rules)
+	}
+	return rules
+}
+
+func commitPromptData(context *context_provider.Context, issueID string, rules commitstyle.Rules) (prompt.Data, error) {
+	// if there is no git context then we can't generate a commit message
+	if context.Git == nil || context.Git.IsEmpty() {
+		return prompt.Data{}, fmt.Errorf("no git context found")
+	}
+
+	return prompt.Data{
+		Diff:    context.Git.Diff,
+		Files:   context.Git.NewFiles,
+		Commits: context.Git.PreviousMessage,
+		Issue:   issueData(context, issueID),
+		Rules:   rules.Instructions(),
+	}, nil
+}
+
+func pullRequestPromptData(context *context_provider.Context, issueID string) (prompt.Data, error) {
+	// if there is no PR template context then we can't generate a PR description
+	if context.PRTemplate == nil || context.PRTemplate.IsEmpty() {
+		return prompt.Data{}, fmt.Errorf("no PR template context found")
+	}
+
+	// all the commits made in the branch
+	if context.Git == nil || context.Git.IsEmpty() {
+		return prompt.Data{}, fmt.Errorf("no git context found")
+	}
+
+	return prompt.Data{
+		Commits:  context.Git.PreviousMessage,
+		Issue:    issueData(context, issueID),
+		Template: context.PRTemplate.Template,
+	}, nil
+}
+
+func issueData(context *context_provider.Context, issueID string) *prompt.Issue {
+	if context.Linear == nil || context.Linear.IsEmpty() {
+		return nil
+	}
+	return &prompt.Issue{
+		ID:          issueID,
+		Title:       context.Linear.Title,
+		Description: context.Linear.Description,
+	}
+}
+
+// generate sends a rendered prompt along with its system instruction
+func (g *GitGeniusSDK) generate(ctx context.Context, rendered *prompt.Rendered, maxTokens int, opts ...llm.Option) (string, error) {
+	if rendered.System != "" {
+		opts = append(opts, llm.WithSystemInstruction(rendered.System))
+	}
+
+	response, err := g.llm.GenerateResponse(ctx, rendered.Prompt, maxTokens, opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate response: %v", err)
+	}
+	return response, nil
+}
@@ -0,0 +1,87 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"git-genius/internal/changelog"
+	context_provider "git-genius/internal/context_provider"
+	llm "git-genius/internal/llm"
+	"git-genius/internal/prompt"
+)
+
+// releaseNotesSchema is the JSON shape requested for release notes
+var releaseNotesSchema = &llm.Schema{
+	Type: llm.TypeObject,
+	Properties: map[string]*llm.Schema{
+		"summary":    {Type: llm.TypeString, Description: "What the release brings to users, one or two sentences"},
+		"highlights": {Type: llm.TypeArray, Items: &llm.Schema{Type: llm.TypeString}},
+	},
+	Required: []string{"summary", "highlights"},
+}
+
+// GenerateReleaseNotes writes the notes of version for the commits after
+// from up to to, an empty from meaning the whole history. The upgrade notes
+// are the breaking change notes of the commits and the contributors their
+// authors and co-authors.
+func (g *GitGeniusSDK) GenerateReleaseNotes(ctx context.Context, from, to, version string) (*ReleaseNotes, error) {
+	logRange := to
+	if from != "" {
+		logRange = from + ".." + to
+	}
+	commits, err := context_provider.Log(ctx, "--no-merges", logRange)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("there are no commits in %s", logRange)
+	}
+
+	notes := &ReleaseNotes{Version: version, UpgradeNotes: []string{}, Contributors: []string{}}
+	var changes []string
+	for _, commit := range commits {
+		entry := changelog.NewEntry(commit.Hash, commit.Message, changelog.Options{IssuePattern: g.issuePattern})
+		for _, note := range entry.Breaking {
+			notes.UpgradeNotes = append(notes.UpgradeNotes, scopedNote(entry.Scope, note))
+		}
+
+		section := entry.Section
+		if section == "" {
+			section = "Internal"
+		}
+		changes = append(changes, fmt.Sprintf("[%s] %s", section, scopedNote(entry.Scope, entry.Subject)))
+	}
+
+	for _, identity := range coAuthors(commits, "") {
+		name, _, _ := strings.Cut(identity, " <")
+		notes.Contributors = append(notes.Contributors, strings.TrimSpace(name))
+	}
+
+	rendered, err := g.prompts.Render(prompt.ReleaseNotes, prompt.Data{
+		Version: version,
+		Commits: changes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var generated struct {
+		Summary    string   `json:"summary"`
+		Highlights []string `json:"highlights"`
+	}
+	if err := g.generateJSON(ctx, rendered, 1024, releaseNotesSchema, &generated); err != nil {
+		return nil, err
+	}
+	notes.Summary = strings.TrimSpace(generated.Summary)
+	notes.Highlights = nonEmpty(generated.Highlights)
+
+	return notes, nil
+}
+
+func scopedNote(scope, text string) string {
+	if scope == "" {
+		return text
+	}
+	return scope + ": " + text
+}
@@ -0,0 +1,55 @@
+package sdk
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"git-genius/internal/prompt"
+)
+
+func TestGenerateReleaseNotes(t *testing.T) {
+	dir := testRepo(t)
+	commitFile(t, dir, "a.txt", "a", "feat: add a")
+	git(t, dir, "tag", "v1.0.0")
+	commitFile(t, dir, "b.txt", "b", "feat(api)!: drop v1 endpoints\n\nBREAKING CHANGE: the /v1 endpoints are gone")
+	t.Setenv("GIT_AUTHOR_NAME", "Ada Lovelace")
+	t.Setenv("GIT_AUTHOR_EMAIL", "ada@example.com")
+	commitFile(t, dir, "c.txt", "c", "fix: handle empty input\n\nCo-authored-by: Grace Hopper <grace@example.com>")
+	commitFile(t, dir, "d.txt", "d", "chore: tidy")
+
+	fake := &fakeLLM{responses: []string{`{"summary": " Drops v1. ", "highlights": ["New API", " "]}`}}
+	g := &GitGeniusSDK{llm: fake, prompts: &prompt.Loader{}}
+
+	notes, err := g.GenerateReleaseNotes(context.Background(), "v1.0.0", "HEAD", "v2.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if notes.Version != "v2.0.0" || notes.Summary != "Drops v1." {
+		t.Errorf("GenerateReleaseNotes() version, summary = %q, %q", notes.Version, notes.Summary)
+	}
+	if want := []string{"New API"}; !slices.Equal(notes.Highlights, want) {
+		t.Errorf("GenerateReleaseNotes() highlights = %q, want %q", notes.Highlights, want)
+	}
+	if want := []string{"api: the /v1 endpoints are gone"}; !slices.Equal(notes.UpgradeNotes, want) {
+		t.Errorf("GenerateReleaseNotes() upgrade notes = %q, want %q", notes.UpgradeNotes, want)
+	}
+	if want := []string{"Sam", "Ada Lovelace", "Grace Hopper"}; !slices.Equal(notes.Contributors, want) {
+		t.Errorf("GenerateReleaseNotes() contributors = %q, want %q", notes.Contributors, want)
+	}
+
+	// only the commits of the release are sent, sorted into sections
+	if len(fake.prompts) != 1 {
+		t.Fatalf("GenerateReleaseNotes() made %d requests, want 1", len(fake.prompts))
+	}
+	for _, change := range []string{"[Added] api: drop v1 endpoints", "[Fixed] handle empty input", "[Internal] tidy"} {
+		if !strings.Contains(fake.prompts[0], change) {
+			t.Errorf("prompt doesn't include %q:\n%s", change, fake.prompts[0])
+		}
+	}
+	if strings.Contains(fake.prompts[0], "add a") {
+		t.Errorf("prompt includes a commit of the previous release:\n%s", fake.prompts[0])
+	}
+}
@@ -0,0 +1,133 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	commitstyle "git-genius/internal/commit_style"
+	context_provider "git-genius/internal/context_provider"
+	llm "git-genius/internal/llm"
+	"git-genius/internal/prompt"
+)
+
+// severities of a finding
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Finding is a problem found in a commit message
+type Finding struct {
+	Severity string `json:"severity"`
+	// Rule names the local rule that failed, "diff" for findings of the LLM review
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Rule, f.Message)
+}
+
+// CommitMessageReview is the LLM's assessment of a commit message
+type CommitMessageReview struct {
+	// Score rates the message from 1 to 10
+	Score    int       `json:"score"`
+	Findings []Finding `json:"findings"`
+}
+
+// LintCommitMessage checks a message against the commit style and the
+// configured issue reference pattern without calling the LLM
+func (g *GitGeniusSDK) LintCommitMessage(message string) []Finding {
+	var findings []Finding
+	for _, violation := range commitstyle.Validate(message, g.commitRules) {
+		findings = append(findings, Finding{
+			Severity: SeverityError,
+			Rule:     violation.Rule,
+			Message:  violation.Message,
+		})
+	}
+
+	if g.issuePattern != nil && !g.issuePattern.MatchString(message) {
+		findings = append(findings, Finding{
+			Severity: SeverityError,
+			Rule:     "issue-reference",
+			Message:  fmt.Sprintf("no issue reference matching %s", g.issuePattern),
+		})
+	}
+
+	return findings
+}
+
+// ReviewCommitMessage asks the LLM whether a message describes the staged
+// diff. An amended commit replaces HEAD, its message is compared with the
+// staged diff against the parent of HEAD.
+func (g *GitGeniusSDK) ReviewCommitMessage(ctx context.Context, message string, amend bool) (*CommitMessageReview, error) {
+	// only the diff matters here, skip the other context providers
+	provided, err := (&context_provider.GitContextProvider{Amend: amend}).FetchContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect context: %v", err)
+	}
+	git := provided.(*context_provider.GitContext)
+	if git.Diff == "" {
+		return nil, fmt.Errorf("no staged changes to compare the message with")
+	}
+
+	rendered, err := g.prompts.Render(prompt.CommitReview, prompt.Data{
+		Diff:    git.Diff,
+		Files:   git.NewFiles,
+		Message: message,
+		Rules:   g.commitRules.Instructions(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	review := &CommitMessageReview{}
+	if err := g.generateJSON(ctx, rendered, 1024, commitReviewSchema, review); err != nil {
+		return nil, err
+	}
+
+	for i := range review.Findings {
+		review.Findings[i].Rule = "diff"
+		if review.Findings[i].Severity != SeverityError {
+			review.Findings[i].Severity = SeverityWarning
+		}
+		review.Findings[i].Message = strings.TrimSpace(review.Findings[i].Message)
+	}
+	return review, nil
+}
+
+// commitReviewSchema is the JSON shape requested for commit message reviews
+var commitReviewSchema = &llm.Schema{
+	Type: llm.TypeObject,
+	Properties: map[string]*llm.Schema{
+		"score": {Type: llm.TypeInteger, Description: "Quality of the message from 1 to 10"},
+		"findings": {
+			Type: llm.TypeArray,
+			Items: &llm.Schema{
+				Type: llm.TypeObject,
+				Properties: map[string]*llm.Schema{
+					"severity": {Type: llm.TypeString, Enum: []string{SeverityError, SeverityWarning}},
+					"message":  {Type: llm.TypeString},
+				},
+				Required: []string{"severity", "message"},
+			},
+		},
+	},
+	Required: []string{"score", "findings"},
+}
+
+// compileIssuePattern compiles commit.issue_pattern, nil when unset
+func compileIssuePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid commit.issue_pattern: %v", err)
+	}
+	return re, nil
+}
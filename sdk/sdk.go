@@ -3,22 +3,49 @@ package sdk
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"git-genius/config"
+	commitindex "git-genius/internal/commit_index"
+	commitstyle "git-genius/internal/commit_style"
 	context_provider "git-genius/internal/context_provider"
 	llm "git-genius/internal/llm"
+	"git-genius/internal/prompt"
 	versioncontrol "git-genius/internal/version_control"
 )
 
 type GitGenius interface {
 	GenerateCommitMessage(ctx context.Context) (string, error)
+	GenerateCommitMessages(ctx context.Context, n int) ([]string, error)
+	GenerateCommitMessageFor(ctx context.Context, commit context_provider.Commit) (string, error)
+	StartCommitSession(ctx context.Context, n int) (*CommitSession, error)
+	GenerateSquashMessage(ctx context.Context, base string) (*SquashMessage, error)
+	ProposeSplit(ctx context.Context) (*SplitPlan, error)
 	GeneratePullRequestContent(ctx context.Context) (*PullRequestContent, error)
+	GenerateReleaseNotes(ctx context.Context, from, to, version string) (*ReleaseNotes, error)
+	ReviewChanges(ctx context.Context, base, head string) (*CodeReview, error)
+	Explain(ctx context.Context, target string) (*ExplainSession, error)
+	AskHistory(ctx context.Context, question string) (*HistoryAnswer, error)
+	SimilarCommits(ctx context.Context, text string, k int) ([]commitindex.Match, error)
+	ProposeBranch(ctx context.Context) (*BranchProposal, error)
+	LintCommitMessage(message string) []Finding
+	ReviewCommitMessage(ctx context.Context, message string, amend bool) (*CommitMessageReview, error)
 }
 
 type GitGeniusSDK struct {
-	llm            llm.LLM
-	prCreator      versioncontrol.PRCreator
-	contextManager *context_provider.ContextManager
+	llm             llm.LLM
+	prCreator       versioncontrol.PRCreator
+	contextManager  *context_provider.ContextManager
+	commitRules     commitstyle.Rules
+	prompts         *prompt.Loader
+	issueID         string
+	issuePattern    *regexp.Regexp
+	reviewRules     string
+	embedder        commitindex.Embedder
+	examples        int
+	branchPattern   string
+	branchMaxLength int
 }
 
 // NewGitGeniusSDK creates a new GeniusSDK instance
@@ -42,110 +69,149 @@ func NewGitGeniusSDK(ctx context.Context, cfg *config.Config) (*GitGeniusSDK, er
 	// create context manager
 	contextManager := context_provider.NewContextManager(cfg)
 
+	commitRules := newCommitRules(ctx, cfg)
+
+	issuePattern, err := compileIssuePattern(cfg.Commit.IssuePattern)
+	if err != nil {
+		return nil, err
+	}
+
 	return &GitGeniusSDK{
 		llm,
 		prCreator,
 		contextManager,
+		commitRules,
+		cfg.NewPromptLoader(),
+		cfg.IssueID,
+		issuePattern,
+		strings.TrimSpace(cfg.Review.Rules),
+		cfg.NewEmbedder(),
+		cfg.IndexExamples(),
+		cfg.BranchPattern(),
+		cfg.BranchMaxLength(),
 	}, nil
 }
 
 func (g *GitGeniusSDK) GenerateCommitMessage(ctx context.Context) (string, error) {
-	context, err := g.contextManager.CollectContext()
+	session, err := g.StartCommitSession(ctx, 1)
 	if err != nil {
-		return "", fmt.Errorf("failed to collect context: %v", err)
+		return "", err
 	}
+	return session.Message, nil
+}
 
-	var gitPrompt string
-	if context.Git != nil && !context.Git.IsEmpty() {
-		gitPrompt = fmt.Sprintf(`
-			Git Diff: %v,
-			Git New Files: %v,
-			Git Previous commit messages: %v
-		`, context.Git.Diff, context.Git.NewFiles, context.Git.PreviousMessage)
-	}
+// conformCommitMessage validates a generated message against the commit
+// rules. A non-conforming message is sent back once with the violations and
+// whatever is still wrong afterwards is repaired locally.
+func (g *GitGeniusSDK) conformCommitMessage(ctx context.Context, message string) (string, error) {
+	message = commitstyle.Clean(message)
 
-	// if there is no git context then we can't generate a commit message
-	if gitPrompt == "" {
-		return "", fmt.Errorf("no git context found")
+	violations := commitstyle.Validate(message, g.commitRules)
+	if len(violations) == 0 {
+		return message, nil
 	}
 
-	var linearPrompt string
-	if context.Linear != nil && !context.Linear.IsEmpty() {
-		linearPrompt = fmt.Sprintf(`
-			Linear ticket description: %v,
-			Linear ticket title: %v
-		`, context.Linear.Description, context.Linear.Title)
+	var problems []string
+	for _, violation := range violations {
+		problems = append(problems, violation.String())
+	}
+	rendered, err := g.prompts.Render(prompt.CommitRepair, prompt.Data{
+		Rules:    g.commitRules.Instructions(),
+		Message:  message,
+		Problems: problems,
+	})
+	if err != nil {
+		return "", err
 	}
 
-	prompt := fmt.Sprintf("Generate a concise git commit message using the following: %v %v", linearPrompt, gitPrompt)
-
-	// Generate commit message
-	commitMessage, err := g.llm.GenerateResponse(ctx, prompt, 150)
+	repaired, err := g.generate(ctx, rendered, 150)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate response: %v", err)
+		return "", err
+	}
+
+	repaired = commitstyle.Clean(repaired)
+	if len(commitstyle.Validate(repaired, g.commitRules)) == 0 {
+		return repaired, nil
 	}
 
-	return commitMessage, nil
+	return commitstyle.Fix(repaired, g.commitRules), nil
 }
 
 func (g *GitGeniusSDK) GeneratePullRequestContent(ctx context.Context) (*PullRequestContent, error) {
 
-	context, err := g.contextManager.CollectContext()
+	context, err := g.contextManager.CollectContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to collect context: %v", err)
 	}
 
-	var prTemplatePrompt string
-	if context.PRTemplate != nil && !context.PRTemplate.IsEmpty() {
-		prTemplatePrompt = fmt.Sprintf(`Write the PR according to the template: %v
-			`, context.PRTemplate.Template)
+	data, err := pullRequestPromptData(context, g.issueID)
+	if err != nil {
+		return nil, err
 	}
+	data.Examples = styleExamples(ctx, g.embedder, pullRequestQuery(data), g.examples)
 
-	// if there is no PR template context then we can't generate a PR description
-	if prTemplatePrompt == "" {
-		return nil, fmt.Errorf("no PR template context found")
+	rendered, err := g.prompts.Render(prompt.PullRequest, data)
+	if err != nil {
+		return nil, err
 	}
 
-	var linearPrompt string
-	if context.Linear != nil && !context.Linear.IsEmpty() {
-		linearPrompt = fmt.Sprintf(`
-				Linear ticket description: %v,
-				Linear ticket title: %v
-			`, context.Linear.Description, context.Linear.Title)
+	var generated struct {
+		Title           string               `json:"title"`
+		Sections        []PullRequestSection `json:"sections"`
+		Labels          []string             `json:"labels"`
+		BreakingChanges []string             `json:"breaking_changes"`
+		TestingNotes    []string             `json:"testing_notes"`
 	}
-
-	// all the commits made in the branch
-	var gitPrompt string
-	if context.Git != nil && !context.Git.IsEmpty() {
-		gitPrompt = fmt.Sprintf(`
-				Git commit messages: %v
-			`, context.Git.PreviousMessage)
+	if err := g.generateJSON(ctx, rendered, 2048, pullRequestSchema, &generated); err != nil {
+		return nil, err
 	}
 
-	// if there is no git context then we can't generate a commit message
-	if gitPrompt == "" {
-		return nil, fmt.Errorf("no git context found")
+	if strings.TrimSpace(generated.Title) == "" {
+		return nil, fmt.Errorf("llm returned a pull request without a title")
 	}
 
-	titlePrompt := fmt.Sprintf(`Generate a one line PR title
-		using the following: %v %v`, linearPrompt, gitPrompt)
-
-	title, err := g.llm.GenerateResponse(ctx, titlePrompt, 150)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate response: %v", err)
+	content := &PullRequestContent{
+		Title:           strings.TrimSpace(generated.Title),
+		Sections:        generated.Sections,
+		Tags:            nonEmpty(generated.Labels),
+		BreakingChanges: nonEmpty(generated.BreakingChanges),
+		TestingNotes:    nonEmpty(generated.TestingNotes),
 	}
+	content.Body = content.renderBody()
 
-	bodyPrompt := fmt.Sprintf(`Generate a PR description
-		using the following: %v %v %v`, linearPrompt, gitPrompt, prTemplatePrompt)
+	return content, nil
+}
 
-	body, err := g.llm.GenerateResponse(ctx, bodyPrompt, 150)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate response: %v", err)
-	}
+// pullRequestSchema is the JSON shape requested for pull request content
+var pullRequestSchema = &llm.Schema{
+	Type: llm.TypeObject,
+	Properties: map[string]*llm.Schema{
+		"title": {Type: llm.TypeString, Description: "One line PR title"},
+		"sections": {
+			Type: llm.TypeArray,
+			Items: &llm.Schema{
+				Type: llm.TypeObject,
+				Properties: map[string]*llm.Schema{
+					"heading": {Type: llm.TypeString},
+					"content": {Type: llm.TypeString, Description: "Markdown content of the section"},
+				},
+				Required: []string{"heading", "content"},
+			},
+		},
+		"labels":           {Type: llm.TypeArray, Items: &llm.Schema{Type: llm.TypeString}},
+		"breaking_changes": {Type: llm.TypeArray, Items: &llm.Schema{Type: llm.TypeString}},
+		"testing_notes":    {Type: llm.TypeArray, Items: &llm.Schema{Type: llm.TypeString}},
+	},
+	Required: []string{"title", "sections", "labels", "breaking_changes", "testing_notes"},
+}
 
-	return &PullRequestContent{
-		Title: title,
-		Body:  body,
-		Tags:  []string{},
-	}, nil
+// nonEmpty drops blank entries from a generated list
+func nonEmpty(items []string) []string {
+	out := []string{}
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
 }
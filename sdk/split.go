@@ -0,0 +1,216 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	context_provider "git-genius/internal/context_provider"
+	llm "git-genius/internal/llm"
+	"git-genius/internal/patch"
+	"git-genius/internal/prompt"
+)
+
+// SplitHunk is a numbered part of the staged diff that is committed as a
+// whole: one hunk, or every hunk of a file that can't be split
+type SplitHunk struct {
+	ID    int
+	File  *patch.File
+	Hunks []*patch.Hunk
+}
+
+// SplitCommit is one of the commits the staged changes are split into
+type SplitCommit struct {
+	Message string
+	// Hunks are in diff order
+	Hunks []*SplitHunk
+}
+
+// SplitPlan groups the staged hunks into commits, applied in order
+type SplitPlan struct {
+	Hunks   []*SplitHunk
+	Commits []*SplitCommit
+}
+
+// splitProposal is the JSON shape of the LLM's grouping
+type splitProposal struct {
+	Commits []struct {
+		Message string `json:"message"`
+		Hunks   []int  `json:"hunks"`
+	} `json:"commits"`
+}
+
+// splitSchema is the JSON shape requested for split proposals
+var splitSchema = &llm.Schema{
+	Type: llm.TypeObject,
+	Properties: map[string]*llm.Schema{
+		"commits": {
+			Type: llm.TypeArray,
+			Items: &llm.Schema{
+				Type: llm.TypeObject,
+				Properties: map[string]*llm.Schema{
+					"message": {Type: llm.TypeString, Description: "Commit message"},
+					"hunks":   {Type: llm.TypeArray, Items: &llm.Schema{Type: llm.TypeInteger}},
+				},
+				Required: []string{"message", "hunks"},
+			},
+		},
+	},
+	Required: []string{"commits"},
+}
+
+// ProposeSplit asks how to split the staged changes into atomic commits.
+// Every hunk ends up in exactly one commit: repeated hunks stay in the first
+// commit that claims them and forgotten ones are added to the last commit.
+func (g *GitGeniusSDK) ProposeSplit(ctx context.Context) (*SplitPlan, error) {
+	diff, err := context_provider.StagedPatch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files, err := patch.Parse(diff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse staged diff: %v", err)
+	}
+
+	plan := &SplitPlan{Hunks: splitHunks(files)}
+	if len(plan.Hunks) == 0 {
+		return nil, fmt.Errorf("there are no staged changes to split")
+	}
+
+	var view strings.Builder
+	for _, hunk := range plan.Hunks {
+		view.WriteString(hunk.view())
+	}
+
+	rendered, err := g.prompts.Render(prompt.Split, prompt.Data{
+		Diff:  view.String(),
+		Rules: g.commitRules.Instructions(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var proposal splitProposal
+	if err := g.generateJSON(ctx, rendered, 4096, splitSchema, &proposal); err != nil {
+		return nil, err
+	}
+
+	claimed := map[int]bool{}
+	for _, proposed := range proposal.Commits {
+		commit := &SplitCommit{}
+		for _, id := range proposed.Hunks {
+			if id < 1 || id > len(plan.Hunks) || claimed[id] {
+				continue
+			}
+			claimed[id] = true
+			commit.Hunks = append(commit.Hunks, plan.Hunks[id-1])
+		}
+		if len(commit.Hunks) == 0 {
+			continue
+		}
+
+		commit.Message, err = g.conformCommitMessage(ctx, proposed.Message)
+		if err != nil {
+			return nil, err
+		}
+		plan.Commits = append(plan.Commits, commit)
+	}
+	if len(plan.Commits) == 0 {
+		return nil, fmt.Errorf("llm proposed no commits")
+	}
+
+	last := plan.Commits[len(plan.Commits)-1]
+	for _, hunk := range plan.Hunks {
+		if !claimed[hunk.ID] {
+			last.Hunks = append(last.Hunks, hunk)
+		}
+	}
+	for _, commit := range plan.Commits {
+		sort.Slice(commit.Hunks, func(i, j int) bool { return commit.Hunks[i].ID < commit.Hunks[j].ID })
+	}
+
+	return plan, nil
+}
+
+// splitHunks numbers the hunks of the files, keeping the hunks of files that
+// can't be split together
+func splitHunks(files []*patch.File) []*SplitHunk {
+	var hunks []*SplitHunk
+	for _, file := range files {
+		if !file.Splittable() {
+			hunks = append(hunks, &SplitHunk{ID: len(hunks) + 1, File: file, Hunks: file.Hunks})
+			continue
+		}
+		for _, hunk := range file.Hunks {
+			hunks = append(hunks, &SplitHunk{ID: len(hunks) + 1, File: file, Hunks: []*patch.Hunk{hunk}})
+		}
+	}
+	return hunks
+}
+
+// Patch returns the commit's hunks as a patch for git apply, one section per file
+func (c *SplitCommit) Patch() string {
+	var b strings.Builder
+	for i := 0; i < len(c.Hunks); {
+		// hunks are in diff order, so the hunks of a file are adjacent
+		file := c.Hunks[i].File
+		var hunks []*patch.Hunk
+		for ; i < len(c.Hunks) && c.Hunks[i].File == file; i++ {
+			hunks = append(hunks, c.Hunks[i].Hunks...)
+		}
+		b.WriteString(file.Patch(hunks))
+	}
+	return b.String()
+}
+
+// String describes the hunk in one line, e.g. "3 main.go @@ -10,6 +10,8 @@ (+2 -0)"
+func (h *SplitHunk) String() string {
+	added, removed := 0, 0
+	for _, hunk := range h.Hunks {
+		a, r := hunk.Stats()
+		added += a
+		removed += r
+	}
+
+	location := changeKind(h.File)
+	if h.File.Splittable() {
+		location = h.Hunks[0].Header
+		if end := strings.LastIndex(location, "@@"); end > 0 {
+			location = location[:end+2]
+		}
+	}
+	return fmt.Sprintf("%d %s %s (+%d -%d)", h.ID, h.File.Path(), location, added, removed)
+}
+
+// view renders the hunk for the split prompt
+func (h *SplitHunk) view() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== hunk %d: %s (%s)\n", h.ID, h.File.Path(), changeKind(h.File))
+	if h.File.Binary {
+		b.WriteString("binary content not shown\n")
+	}
+	for _, hunk := range h.Hunks {
+		b.WriteString(hunk.String())
+	}
+	return b.String()
+}
+
+func changeKind(file *patch.File) string {
+	switch {
+	case file.New:
+		return "new file"
+	case file.Deleted:
+		return "deleted file"
+	case file.Renamed:
+		return "renamed from " + file.OldPath
+	case file.Binary:
+		return "binary file"
+	case len(file.Hunks) == 0:
+		return "mode change"
+	case !file.Splittable():
+		return "modified, mode change"
+	default:
+		return "modified"
+	}
+}
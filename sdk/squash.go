@@ -0,0 +1,155 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	commitstyle "git-genius/internal/commit_style"
+	context_provider "git-genius/internal/context_provider"
+	"git-genius/internal/prompt"
+)
+
+// coAuthorToken is the trailer GitHub and GitLab use to credit co-authors
+const coAuthorToken = "Co-authored-by"
+
+// SquashMessage is the message for squashing a branch into a single commit
+type SquashMessage struct {
+	Message string
+	// MergeBase is where the branch forked from the base
+	MergeBase string
+	// Commits are the branch's commits, oldest first
+	Commits []context_provider.Commit
+	// CoAuthors are the "Name <email>" identities credited in the message
+	CoAuthors []string
+}
+
+// GenerateSquashMessage writes one message for the commits of the current
+// branch since it forked from base, from their cumulative diff. The authors
+// of the commits and their co-authors are credited with Co-authored-by
+// trailers.
+func (g *GitGeniusSDK) GenerateSquashMessage(ctx context.Context, base string) (*SquashMessage, error) {
+	mergeBase, err := context_provider.MergeBase(ctx, base, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := context_provider.Log(ctx, "--no-merges", mergeBase+"..HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("there are no commits on the branch since %s", base)
+	}
+
+	diff, err := context_provider.Diff(ctx, mergeBase, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(diff) == "" {
+		return nil, fmt.Errorf("the branch has no changes against %s", base)
+	}
+
+	messages := make([]string, len(commits))
+	for i, commit := range commits {
+		// indent bodies so each commit stays one list item
+		messages[i] = strings.ReplaceAll(commit.Message, "\n", "\n  ")
+	}
+
+	rendered, err := g.prompts.Render(prompt.Squash, prompt.Data{
+		Diff:    diff,
+		Commits: messages,
+		Rules:   g.commitRules.Instructions(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := g.generate(ctx, rendered, 300)
+	if err != nil {
+		return nil, err
+	}
+	message, err = g.conformCommitMessage(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+
+	coAuthors := coAuthors(commits, authorEmail())
+	return &SquashMessage{
+		Message:   addCoAuthors(message, coAuthors),
+		MergeBase: mergeBase,
+		Commits:   commits,
+		CoAuthors: coAuthors,
+	}, nil
+}
+
+// coAuthors collects the authors and co-authors of the commits in order of
+// appearance, once per email and without the person committing the squash
+func coAuthors(commits []context_provider.Commit, self string) []string {
+	seen := map[string]bool{}
+	if self != "" {
+		seen[self] = true
+	}
+
+	var identities []string
+	add := func(identity string) {
+		email := identityEmail(identity)
+		if email == "" || seen[email] {
+			return
+		}
+		seen[email] = true
+		identities = append(identities, strings.TrimSpace(identity))
+	}
+
+	for _, commit := range commits {
+		add(commit.Author)
+		for _, footer := range commitstyle.Parse(commit.Message).Footers {
+			if strings.EqualFold(footer.Token, coAuthorToken) {
+				add(footer.Value)
+			}
+		}
+	}
+	return identities
+}
+
+// addCoAuthors appends a Co-authored-by trailer for every identity the
+// message doesn't credit yet
+func addCoAuthors(message string, identities []string) string {
+	if len(identities) == 0 {
+		return message
+	}
+
+	msg := commitstyle.Parse(message)
+	credited := map[string]bool{}
+	for _, footer := range msg.Footers {
+		if strings.EqualFold(footer.Token, coAuthorToken) {
+			credited[identityEmail(footer.Value)] = true
+		}
+	}
+	for _, identity := range identities {
+		if !credited[identityEmail(identity)] {
+			msg.Footers = append(msg.Footers, commitstyle.Footer{Token: coAuthorToken, Value: identity})
+		}
+	}
+	return msg.String()
+}
+
+// authorEmail returns the email git records as the author of new commits
+func authorEmail() string {
+	out, err := exec.Command("git", "var", "GIT_AUTHOR_IDENT").Output()
+	if err != nil {
+		return ""
+	}
+	return identityEmail(string(out))
+}
+
+// identityEmail returns the lowercased email of a "Name <email>" identity
+func identityEmail(identity string) string {
+	start := strings.Index(identity, "<")
+	end := strings.Index(identity, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(identity[start+1 : end]))
+}
@@ -0,0 +1,85 @@
+package sdk
+
+import (
+	"slices"
+	"testing"
+
+	context_provider "git-genius/internal/context_provider"
+)
+
+func TestCoAuthors(t *testing.T) {
+	commits := []context_provider.Commit{
+		{Author: "Ada Lovelace <ada@example.com>", Message: "feat: add parser"},
+		{Author: "Me <me@example.com>", Message: "fix: parser\n\nCo-authored-by: Grace Hopper <grace@example.com>"},
+		{Author: "Ada L. <ADA@example.com>", Message: "docs: parser\n\nco-authored-by: Me <me@example.com>"},
+		{Author: "Unknown", Message: "chore: tidy"},
+	}
+
+	tests := []struct {
+		name string
+		self string
+		want []string
+	}{
+		{name: "without self", self: "me@example.com", want: []string{"Ada Lovelace <ada@example.com>", "Grace Hopper <grace@example.com>"}},
+		{name: "no self", want: []string{"Ada Lovelace <ada@example.com>", "Me <me@example.com>", "Grace Hopper <grace@example.com>"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := coAuthors(commits, tt.self); !slices.Equal(got, tt.want) {
+				t.Errorf("coAuthors() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddCoAuthors(t *testing.T) {
+	tests := []struct {
+		name       string
+		message    string
+		identities []string
+		want       string
+	}{
+		{name: "none", message: "feat: add parser", want: "feat: add parser"},
+		{
+			name:       "appended",
+			message:    "feat: add parser\n\nParses the config.",
+			identities: []string{"Ada Lovelace <ada@example.com>"},
+			want:       "feat: add parser\n\nParses the config.\n\nCo-authored-by: Ada Lovelace <ada@example.com>",
+		},
+		{
+			name:       "already credited",
+			message:    "feat: add parser\n\nRefs: #12\nCo-authored-by: Ada <ADA@example.com>",
+			identities: []string{"Ada Lovelace <ada@example.com>", "Grace Hopper <grace@example.com>"},
+			want:       "feat: add parser\n\nRefs: #12\nCo-authored-by: Ada <ADA@example.com>\nCo-authored-by: Grace Hopper <grace@example.com>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addCoAuthors(tt.message, tt.identities); got != tt.want {
+				t.Errorf("addCoAuthors() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdentityEmail(t *testing.T) {
+	tests := []struct {
+		identity string
+		want     string
+	}{
+		{identity: "Ada Lovelace <Ada@Example.com>", want: "ada@example.com"},
+		{identity: "Ada Lovelace <ada@example.com> 1700000000 +0100", want: "ada@example.com"},
+		{identity: "Ada Lovelace", want: ""},
+		{identity: "Ada >broken<", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.identity, func(t *testing.T) {
+			if got := identityEmail(tt.identity); got != tt.want {
+				t.Errorf("identityEmail(%q) = %q, want %q", tt.identity, got, tt.want)
+			}
+		})
+	}
+}